@@ -1,64 +1,250 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"gobackup/internal/backup"
+	"gobackup/internal/config"
+	"gobackup/internal/history"
 	"gobackup/internal/logger"
+	"gobackup/internal/webhook"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+// Códigos de salida del modo headless
+const (
+	exitFailed    = 1
+	exitPartial   = 2
+	exitCancelled = 130
+)
+
+var (
+	cliSources     []string
+	cliDest        string
+	cliSince       time.Duration
+	cliConcurrency int
+	cliFormat      string
+)
+
+// exitError termina el proceso con un código distinto de 1 (ver Execute)
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
 var cliCmd = &cobra.Command{
-	Use:   "cli",
-	Short: "Run backup from command line",
-	Long:  `Run a backup operation using the configured source and backup directories.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		logger.Infof("Starting CLI backup operation")
-
-		// MODO COMPATIBILIDAD: Usar sistema antiguo si está configurado
-		if Cfg.SourceDir != "" && Cfg.BackupDir != "" {
-			fmt.Printf("Source directory: %s\n", Cfg.SourceDir)
-			fmt.Printf("Backup directory: %s\n", Cfg.BackupDir)
-			fmt.Printf("Modified minutes: %d\n", Cfg.ModifiedMinutes)
-			fmt.Printf("Max concurrency: %d\n", Cfg.MaxConcurrency)
-
-			// Ejecutar backup en modo legacy
-			if err := backup.RunBackup(); err != nil {
-				fmt.Printf("Error: %v\n", err)
-				os.Exit(1)
+	Use:     "cli",
+	Aliases: []string{"backup"},
+	Short:   "Run a backup from the command line",
+	Long: `Run a full backup job without the web server: scan the sources, copy the files,
+compress (and optionally encrypt) them, record the run in the history with its log
+and report, and apply the profile's retention.
+
+The job uses the profile selected with --profile; the flags below override it:
+
+  gobackup cli --profile daily
+  gobackup cli --source /data --source /etc --dest /mnt/backups --since 30m --format tar.zst
+
+With the legacy source_dir/backup_dir config and no profiles, the source is copied
+to backup_dir without compression as before.
+
+Exit codes: 0 success, 2 partial (some files failed), 1 failure, 130 cancelled.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		defer waitWebhooks()
+		p := backup.ActiveProfile()
+		flags := cmd.Flags()
+		if flags.Changed("format") {
+			format, err := backup.ParseFormat(cliFormat)
+			if err != nil {
+				return err
+			}
+			p.Format = format
+		}
+		if flags.Changed("dest") {
+			p.Dest = cliDest
+		}
+		if flags.Changed("since") {
+			p.Since = cliSince
+		}
+		if flags.Changed("concurrency") {
+			p.Concurrency = cliConcurrency
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		jobID := p.Name
+		if len(cliSources) > 0 {
+			p.Sources = nil
+			for _, src := range cliSources {
+				abs, err := filepath.Abs(src)
+				if err != nil {
+					return err
+				}
+				p.Sources = append(p.Sources, abs)
+			}
+			jobID = strings.Join(p.Sources, ",")
+		} else if _, explicit := Cfg.Profiles[p.Name]; !explicit && Cfg.SourceDir != "" && Cfg.BackupDir != "" {
+			// MODO COMPATIBILIDAD: Usar sistema antiguo si está configurado
+			return runLegacyBackup(ctx, p)
+		}
+		if len(p.Sources) == 0 {
+			return fmt.Errorf("--source is required (or set sources in profile %q)", p.Name)
+		}
+
+		for _, src := range p.Sources {
+			if info, err := os.Stat(src); err != nil || !info.IsDir() {
+				return fmt.Errorf("source %s is not a directory", src)
+			}
+			if config.Overlaps(src, p.Dest) {
+				return fmt.Errorf("dest %s is inside source %s: the backup would copy itself", p.Dest, src)
 			}
-			fmt.Println("Backup completed successfully!")
-			return
+		}
+		if err := os.MkdirAll(p.Dest, 0755); err != nil {
+			return fmt.Errorf("creating dest: %w", err)
 		}
 
-		// MODO NUEVO: Usar sistema de uploads/backups
-		fmt.Printf("Uploads directory: %s\n", Cfg.UploadsDir)
-		fmt.Printf("Backups directory: %s\n", Cfg.BackupsDir)
-		fmt.Printf("Temp directory: %s\n", Cfg.TempDir)
-		fmt.Printf("Modified minutes: %d\n", Cfg.ModifiedMinutes)
-		fmt.Printf("Max concurrency: %d\n", Cfg.MaxConcurrency)
+		opts := backup.JobOptions{
+			JobID:      jobID,
+			SessionID:  p.JobName(time.Now()),
+			BackupType: "cli",
+			Profile:    p,
+		}
+		logger.Info("Backup desde CLI", "profile", p.Name, "sources", p.Sources, "dest", p.Dest, "format", p.Format, "since", p.Since)
 
-		fmt.Println("\nNote: CLI mode with uploads requires files to be uploaded first.")
-		fmt.Println("Please use the web interface for drag-and-drop functionality.")
-		fmt.Println("Starting web server...")
+		backup.Status.TryStart()
+		done := make(chan struct{})
+		progressDone := startProgress(done)
+		snap, err := backup.RunJob(ctx, opts)
+		close(done)
+		<-progressDone
 
-		// Iniciar servidor web en modo CLI
-		startWebServer()
+		return cliResult(snap, err)
 	},
 }
 
 func init() {
+	cliCmd.Flags().StringSliceVar(&cliSources, "source", nil, "Directory to back up (repeatable; overrides the profile's sources)")
+	cliCmd.Flags().StringVar(&cliDest, "dest", "", "Directory for the archive (default: profile dest or backups_dir)")
+	cliCmd.Flags().DurationVar(&cliSince, "since", 0, "Only files modified in this window, e.g. 30m (0 = all)")
+	cliCmd.Flags().IntVar(&cliConcurrency, "concurrency", 0, "Parallel copies (default: profile or max_concurrency)")
+	cliCmd.Flags().StringVar(&cliFormat, "format", "", "Archive format: zip, tar.gz or tar.zst (default: profile format)")
 	rootCmd.AddCommand(cliCmd)
 }
 
-func startWebServer() {
-	// Iniciar servidor web
-	fmt.Printf("Starting web server on port %d...\n", Cfg.ServerPort)
-	fmt.Printf("Open http://localhost:%d in your browser\n", Cfg.ServerPort)
+// runLegacyBackup copia source_dir a backup_dir como hacía la versión anterior
+func runLegacyBackup(ctx context.Context, p backup.Profile) error {
+	fmt.Printf("Source directory: %s\n", p.Sources[0])
+	fmt.Printf("Backup directory: %s\n", p.Dest)
+	fmt.Printf("Modified since: %s\n", p.Since)
+	fmt.Printf("Max concurrency: %d\n", p.Concurrency)
+
+	snap, err := backup.RunBackup(ctx, p)
+	if snap.Status == "" {
+		// Falló antes de empezar, no hay nada registrado
+		return err
+	}
+	return cliResult(snap, err)
+}
+
+// webhookWait es cuánto se espera al salir a que terminen los avisos a los webhooks
+const webhookWait = 30 * time.Second
+
+// waitWebhooks espera los envíos en curso (el del final del trabajo) antes de salir
+func waitWebhooks() {
+	if n := webhook.Wait(webhookWait); n > 0 {
+		logger.Warn("Webhooks sin terminar al salir", "pending", n)
+	}
+}
+
+// cliResult imprime el resumen del trabajo y elige el código de salida
+func cliResult(snap history.Snapshot, err error) error {
+	fmt.Printf("Status:   %s\n", snap.Status)
+	fmt.Printf("Files:    %d copied, %d skipped, %d failed\n", snap.FilesCount, snap.FilesSkipped, snap.FilesFailed)
+	fmt.Printf("Size:     %s read, %s written\n", humanSize(snap.BytesRead), humanSize(snap.BytesWritten))
+	fmt.Printf("Duration: %s\n", formatSeconds(snap.Duration))
+	if snap.Archive != "" {
+		fmt.Printf("Archive:  %s\n", snap.Archive)
+	}
+	if snap.ReportPath != "" {
+		fmt.Printf("Report:   %s\n", snap.ReportPath)
+	}
+
+	switch snap.Status {
+	case history.StatusSuccess:
+		return nil
+	case history.StatusPartial:
+		return &exitError{code: exitPartial, err: fmt.Errorf("%d file(s) failed", snap.FilesFailed)}
+	case history.StatusCancelled:
+		return &exitError{code: exitCancelled, err: errors.New("backup cancelled")}
+	}
+	if err == nil {
+		err = errors.New("backup failed")
+	}
+	return &exitError{code: exitFailed, err: err}
+}
+
+// startProgress muestra el avance del backup en curso hasta que se cierre done.
+// En una terminal dibuja una barra; si no, imprime una línea por fase o cada 10%.
+func startProgress(done <-chan struct{}) <-chan struct{} {
+	finished := make(chan struct{})
+	tty := isTerminal(os.Stdout)
+
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+
+		lastPhase, lastStep := "", -1
+		for {
+			select {
+			case <-done:
+				if tty && lastPhase != "" {
+					fmt.Println()
+				}
+				return
+			case <-ticker.C:
+			}
+
+			st := backup.Status.Get()
+			if st.Phase == "" {
+				continue
+			}
+			percent := 0
+			if st.TotalFiles > 0 {
+				percent = st.FilesCopied * 100 / st.TotalFiles
+			}
+
+			if tty {
+				fmt.Printf("\r%-8s %s %3d%% (%d/%d)", st.Phase, progressBar(percent, 30), percent, st.FilesCopied, st.TotalFiles)
+			} else if st.Phase != lastPhase || (st.Phase == "copy" && percent/10 != lastStep) {
+				fmt.Printf("%s: %d/%d files (%d%%)\n", st.Phase, st.FilesCopied, st.TotalFiles, percent)
+				lastStep = percent / 10
+			}
+			lastPhase = st.Phase
+		}
+	}()
+	return finished
+}
+
+func progressBar(percent, width int) string {
+	filled := percent * width / 100
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(".", width-filled) + "]"
+}
 
-	// Aquí deberías llamar a tu función para iniciar el servidor web
-	// Por ahora mostramos un mensaje
-	fmt.Println("Web server functionality would start here")
-	fmt.Println("Use 'gobackup web' for full web server")
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"gobackup/internal/config"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	configJSON     bool
+	configSkipDirs bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration and where each value comes from",
+	Long: `Print the merged configuration. Values are layered as
+defaults < config file < GOBACKUP_* environment variables < command line flags.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if configJSON {
+			return printJSON(map[string]interface{}{
+				"file":    cfgPath,
+				"config":  Cfg,
+				"origins": CfgOrigins,
+			})
+		}
+
+		file := cfgPath
+		if file == "" {
+			file = "(none)"
+		}
+		fmt.Printf("Config file: %s\n\n", file)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+		for _, f := range config.Fields() {
+			origin := CfgOrigins[f.Key]
+			if origin == config.OriginEnv {
+				origin += " (" + f.Env + ")"
+			}
+			fmt.Fprintf(w, "%s\t%v\t%s\n", f.Key, f.Value(Cfg), origin)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\nProfiles: %s (see 'gobackup profiles')\n", strings.Join(Cfg.ProfileNames(), ", "))
+		return nil
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check a configuration file and report every problem",
+	Long: `Load the configuration (file, GOBACKUP_* environment and flags) and report
+all problems at once: unknown or mistyped keys, out of range values, directories
+that cannot be written and backup directories inside their own source.
+Exits with status 1 if the configuration is invalid.`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: map[string]string{skipConfigLoad: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := cfgPath
+		if len(args) == 1 {
+			path = args[0]
+		}
+		_, _, err := config.Check(path, cmd.Flags(), !configSkipDirs)
+
+		var invalid *config.ValidationError
+		if configJSON {
+			result := map[string]interface{}{"file": path, "valid": err == nil, "problems": []config.Problem{}}
+			if errors.As(err, &invalid) {
+				result["problems"] = invalid.Problems
+			} else if err != nil {
+				result["problems"] = []config.Problem{{Message: err.Error()}}
+			}
+			if perr := printJSON(result); perr != nil {
+				return perr
+			}
+			if err != nil {
+				return &exitError{code: 1, err: errors.New("invalid configuration")}
+			}
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			path = "defaults"
+		}
+		fmt.Printf("%s: OK\n", path)
+		return nil
+	},
+}
+
+func init() {
+	configShowCmd.Flags().BoolVar(&configJSON, "json", false, "Print JSON")
+	configValidateCmd.Flags().BoolVar(&configJSON, "json", false, "Print the result as JSON")
+	configValidateCmd.Flags().BoolVar(&configSkipDirs, "skip-dirs", false, "Do not check directories, encryption key files or passphrase variables (e.g. in CI)")
+	configCmd.AddCommand(configShowCmd, configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
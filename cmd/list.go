@@ -1 +1,298 @@
 package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"gobackup/internal/backup"
+	"gobackup/internal/history"
+	"gobackup/internal/logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	listSort      string
+	listAsc       bool
+	listSince     string
+	listUntil     string
+	listStatus    string
+	listLimit     int
+	listJSON      bool
+	listPath      string
+	listRecursive bool
+	listProfile   string
+)
+
+// listRow es una fila del listado: un snapshot del historial o un archivo sin historial
+type listRow struct {
+	ID       uint64    `json:"id,omitempty"`
+	Date     time.Time `json:"date"`
+	Job      string    `json:"job"`
+	Profile  string    `json:"profile,omitempty"`
+	Session  string    `json:"session_id,omitempty"`
+	Size     int64     `json:"size"`
+	Files    int       `json:"files"`
+	Duration float64   `json:"duration_seconds"`
+	Status   string    `json:"status"`
+	Archive  string    `json:"archive,omitempty"`
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list [id]",
+	Short: "List backups or show the contents of one",
+	Long: `Without arguments, list the backups recorded in the history plus any archive
+found in backups_dir. With an ID (history ID or session ID), show the files
+inside that backup's archive.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			return listArchive(args[0])
+		}
+		// Con --profile solo los backups de ese perfil
+		if cmd.Flags().Changed("profile") {
+			listProfile = profileName
+		}
+		return listBackups()
+	},
+}
+
+func init() {
+	listCmd.Flags().StringVar(&listSort, "sort", "date", "Sort by: id, date, size, files, duration, status")
+	listCmd.Flags().BoolVar(&listAsc, "asc", false, "Sort ascending (default is descending)")
+	listCmd.Flags().StringVar(&listSince, "since", "", "Only backups after this time (RFC3339 or duration like 72h)")
+	listCmd.Flags().StringVar(&listUntil, "until", "", "Only backups before this time (RFC3339 or duration like 24h)")
+	listCmd.Flags().StringVar(&listStatus, "status", "", "Only these statuses (comma separated: success,partial,failed,cancelled)")
+	listCmd.Flags().IntVarP(&listLimit, "limit", "n", 0, "Show at most N backups (0 = all)")
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Print JSON instead of a table")
+	listCmd.Flags().StringVar(&listPath, "path", "", "With an ID: directory inside the archive")
+	listCmd.Flags().BoolVarP(&listRecursive, "recursive", "r", true, "With an ID: include subdirectories")
+	rootCmd.AddCommand(listCmd)
+}
+
+func listBackups() error {
+	since, err := logger.ParseSince(listSince)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	until, err := logger.ParseSince(listUntil)
+	if err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	snapshots, err := history.Snapshots(history.Query{})
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+
+	var rows []listRow
+	withHistory := make(map[string]bool)
+	for _, snap := range snapshots {
+		if listProfile != "" && snap.Profile != listProfile {
+			continue
+		}
+		row := listRow{
+			ID:       snap.ID,
+			Date:     snap.Timestamp,
+			Job:      snap.JobID,
+			Profile:  snap.Profile,
+			Session:  snap.SessionID,
+			Size:     snap.TotalSize,
+			Files:    snap.FilesCount,
+			Duration: snap.Duration,
+			Status:   snap.Status,
+		}
+		if archive, err := backup.SnapshotArchive(snap); err == nil {
+			row.Archive = filepath.Base(archive)
+			if sameDir(filepath.Dir(archive), backup.BackupsDir) {
+				withHistory[row.Archive] = true
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	// Archivos presentes en disco que no figuran en el historial
+	archives, err := backup.ListBackups()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("listing backups: %w", err)
+	}
+	for _, name := range archives {
+		if withHistory[name] || (listProfile != "" && !strings.HasPrefix(name, listProfile+"_")) {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(backup.BackupsDir, name))
+		if err != nil {
+			continue
+		}
+		session := backup.TrimArchiveExt(name)
+		rows = append(rows, listRow{
+			Date:    info.ModTime(),
+			Job:     session,
+			Session: session,
+			Size:    info.Size(),
+			Status:  "unknown",
+			Archive: name,
+		})
+	}
+
+	rows = filterRows(rows, since, until)
+	if err := sortRows(rows); err != nil {
+		return err
+	}
+	if listLimit > 0 && len(rows) > listLimit {
+		rows = rows[:listLimit]
+	}
+
+	if listJSON {
+		if rows == nil {
+			rows = []listRow{}
+		}
+		return printJSON(rows)
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No backups found")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDATE\tJOB\tSIZE\tFILES\tDURATION\tSTATUS\tARCHIVE")
+	for _, r := range rows {
+		id := "-"
+		if r.ID != 0 {
+			id = strconv.FormatUint(r.ID, 10)
+		}
+		archive := r.Archive
+		if archive == "" {
+			archive = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			id, r.Date.Local().Format("2006-01-02 15:04:05"), r.Job, humanSize(r.Size),
+			r.Files, formatSeconds(r.Duration), r.Status, archive)
+	}
+	return w.Flush()
+}
+
+func filterRows(rows []listRow, since, until time.Time) []listRow {
+	statuses := make(map[string]bool)
+	for _, s := range strings.Split(listStatus, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			statuses[strings.ToLower(s)] = true
+		}
+	}
+
+	var result []listRow
+	for _, r := range rows {
+		if !since.IsZero() && r.Date.Before(since) {
+			continue
+		}
+		if !until.IsZero() && r.Date.After(until) {
+			continue
+		}
+		if len(statuses) > 0 && !statuses[r.Status] {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+func sortRows(rows []listRow) error {
+	var less func(a, b listRow) bool
+	switch listSort {
+	case "id":
+		less = func(a, b listRow) bool { return a.ID < b.ID }
+	case "date":
+		less = func(a, b listRow) bool { return a.Date.Before(b.Date) }
+	case "size":
+		less = func(a, b listRow) bool { return a.Size < b.Size }
+	case "files":
+		less = func(a, b listRow) bool { return a.Files < b.Files }
+	case "duration":
+		less = func(a, b listRow) bool { return a.Duration < b.Duration }
+	case "status":
+		less = func(a, b listRow) bool { return a.Status < b.Status }
+	default:
+		return fmt.Errorf("invalid --sort %q (use id, date, size, files, duration or status)", listSort)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if listAsc {
+			return less(rows[i], rows[j])
+		}
+		return less(rows[j], rows[i])
+	})
+	return nil
+}
+
+// listArchive muestra el contenido del archivo de un backup
+func listArchive(id string) error {
+	zipPath, err := backup.ResolveArchive(id)
+	if err != nil {
+		return fmt.Errorf("backup %s: %w", id, err)
+	}
+	entries, err := backup.ListArchive(zipPath, listPath, listRecursive)
+	if err != nil {
+		return err
+	}
+
+	if listJSON {
+		if entries == nil {
+			entries = []backup.ArchiveEntry{}
+		}
+		return printJSON(entries)
+	}
+
+	var total int64
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SIZE\tCOMPRESSED\tMODIFIED\tPATH")
+	for _, e := range entries {
+		name := e.Path
+		if e.IsDir {
+			name += "/"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", humanSize(e.Size), humanSize(e.CompressedSize),
+			e.Modified.Local().Format("2006-01-02 15:04:05"), name)
+		total += e.Size
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d entries, %s in %s\n", len(entries), humanSize(total), filepath.Base(zipPath))
+	return nil
+}
+
+func sameDir(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// humanSize muestra un tamaño en la unidad más adecuada
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func formatSeconds(s float64) string {
+	return (time.Duration(s * float64(time.Second))).Round(time.Millisecond).String()
+}
@@ -1 +1,89 @@
 package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gobackup/internal/logger"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsFollow bool
+	logsLevel  string
+	logsJob    string
+	logsGrep   string
+	logsSince  string
+	logsLines  int
+	logsJSON   bool
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show persisted logs",
+	Long: `Show the persisted log files, including rotated and compressed ones.
+With --follow, keep printing new entries as they are written.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var f logger.Filter
+		if logsLevel != "" {
+			level, err := logger.ParseLevel(logsLevel)
+			if err != nil {
+				return err
+			}
+			f.MinLevel = level
+		}
+		if logsSince != "" {
+			since, err := logger.ParseSince(logsSince)
+			if err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			f.Since = since
+		}
+		f.JobID = logsJob
+		f.Text = logsGrep
+		f.Limit = logsLines
+
+		dir := Cfg.Log.Dir
+		entries, err := logger.ReadFiles(dir, f)
+		if err != nil {
+			return fmt.Errorf("reading logs: %w", err)
+		}
+		for _, e := range entries {
+			printLogEntry(e)
+		}
+
+		if !logsFollow {
+			return nil
+		}
+
+		// Seguir el archivo activo hasta Ctrl+C
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return logger.Follow(ctx, dir, f, printLogEntry)
+	},
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new entries")
+	logsCmd.Flags().StringVar(&logsLevel, "level", "", "Minimum level (debug, info, warn, error)")
+	logsCmd.Flags().StringVar(&logsJob, "job", "", "Only entries for this job or session ID")
+	logsCmd.Flags().StringVar(&logsGrep, "grep", "", "Only entries containing this text")
+	logsCmd.Flags().StringVar(&logsSince, "since", "", "Only entries after this time (RFC3339 or duration like 2h)")
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", 0, "Only the last N matching entries (0 = all)")
+	logsCmd.Flags().BoolVar(&logsJSON, "json", false, "Print one JSON object per entry")
+	rootCmd.AddCommand(logsCmd)
+}
+
+func printLogEntry(e logger.LogEntry) {
+	if logsJSON {
+		data, _ := json.Marshal(e)
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Println(e.String())
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"gobackup/internal/backup"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	profilesJSON bool
+	decryptOut   string
+)
+
+var profilesCmd = &cobra.Command{
+	Use:   "profiles [name]",
+	Short: "List backup profiles or show one",
+	Long: `List the profiles defined in the "profiles" section of the config. The
+"default" profile is built from the top-level settings unless the config defines it.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			p, ok := backup.GetProfile(args[0])
+			if !ok {
+				return fmt.Errorf("unknown profile %q", args[0])
+			}
+			return printJSON(p)
+		}
+
+		profiles := backup.ListProfiles()
+		if profilesJSON {
+			return printJSON(profiles)
+		}
+		active := backup.ActiveProfile().Name
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSOURCES\tDEST\tFORMAT\tENCRYPTED\tRETENTION")
+		for _, p := range profiles {
+			name := p.Name
+			if name == active {
+				name += " *"
+			}
+			sources := strings.Join(p.Sources, ",")
+			if sources == "" {
+				sources = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\n", name, sources, p.Dest, p.Format, p.Encrypted(), retentionText(p))
+		}
+		return w.Flush()
+	},
+}
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt <archive>",
+	Short: "Decrypt an archive created by an encrypted profile",
+	Long: `Decrypt a .enc archive with the passphrase of the selected profile
+(--profile). By default the result is written next to it without the .enc extension.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src := args[0]
+		if !backup.IsEncrypted(src) {
+			// También se acepta el ID del backup
+			path, err := backup.ResolveArchive(src)
+			if err != nil || !backup.IsEncrypted(path) {
+				return fmt.Errorf("%s is not an encrypted archive", src)
+			}
+			src = path
+		}
+		dst := decryptOut
+		if dst == "" {
+			dst = strings.TrimSuffix(src, backup.EncryptedExt)
+		}
+		if err := backup.ActiveProfile().DecryptArchive(src, dst); err != nil {
+			return err
+		}
+		fmt.Printf("Decrypted %s -> %s\n", filepath.Base(src), dst)
+		return nil
+	},
+}
+
+func retentionText(p backup.Profile) string {
+	var parts []string
+	if p.Retention.KeepLast > 0 {
+		parts = append(parts, fmt.Sprintf("last %d", p.Retention.KeepLast))
+	}
+	if p.Retention.MaxAgeDays > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", p.Retention.MaxAgeDays))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func init() {
+	profilesCmd.Flags().BoolVar(&profilesJSON, "json", false, "Print JSON instead of a table")
+	decryptCmd.Flags().StringVarP(&decryptOut, "output", "o", "", "Output file (default: archive name without .enc)")
+	rootCmd.AddCommand(profilesCmd, decryptCmd)
+}
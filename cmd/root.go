@@ -1,47 +1,103 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"gobackup/internal/audit"
+	"gobackup/internal/auth"
 	"gobackup/internal/backup"
+	"gobackup/internal/history"
+	"gobackup/internal/logger"
+	"gobackup/internal/webhook"
 	"os"
+	"strings"
+	"time"
 
 	"gobackup/internal/config"
 
 	"github.com/spf13/cobra"
 )
 
+// defaultCfgPath se usa si no se indica --config ni GOBACKUP_CONFIG; puede no existir
+const defaultCfgPath = "config/default.json"
+
+// skipConfigLoad es la anotación de los comandos que no necesitan la configuración cargada
+const skipConfigLoad = "skip-config-load"
+
 var (
-	cfgPath string         // Ruta al archivo config
-	Cfg     *config.Config // Config cargada accesible globalmente
+	cfgPath     string         // Ruta al archivo config
+	profileName string         // Perfil elegido con --profile
+	Cfg         *config.Config // Config cargada accesible globalmente
+	CfgOrigins  config.Origins // De dónde salió cada valor (ver config show)
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "gobackup",
 	Short: "Gobackup is a backup tool",
 	Long:  `Gobackup is a CLI tool to manage backups and optionally run a web server.`,
+	// Los errores se imprimen una sola vez en Execute, sin repetir la ayuda
+	SilenceUsage:  true,
+	SilenceErrors: true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// Cargar configuración antes de ejecutar cualquier subcomando
 		var err error
-		Cfg, err = config.LoadConfig(cfgPath)
+		cfgPath = configPath(cmd)
+		if cmd.Annotations[skipConfigLoad] != "" {
+			return nil // el comando carga la configuración por su cuenta
+		}
+		Cfg, CfgOrigins, err = config.Load(cfgPath, cmd.Flags())
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		// Inicializar rutas y parámetros de backup desde la configuración
-		// Mantener compatibilidad con sistema antiguo
-		backup.SourceDir = Cfg.SourceDir
-		backup.BackupDir = Cfg.BackupDir
+		// Inicializar logger con el nivel, formato y rotación configurados
+		level, err := logger.ParseLevel(Cfg.Log.Level)
+		if err != nil {
+			return fmt.Errorf("invalid log config: %w", err)
+		}
+		err = logger.Init(logger.Options{
+			Dir:            Cfg.Log.Dir,
+			MaxLines:       500,
+			Level:          level,
+			Format:         Cfg.Log.Format,
+			MaxSizeMB:      Cfg.Log.MaxSizeMB,
+			MaxAgeDays:     Cfg.Log.MaxAgeDays,
+			MaxBackups:     Cfg.Log.MaxBackups,
+			RotateInterval: time.Duration(Cfg.Log.RotateHours) * time.Hour,
+			Compress:       Cfg.Log.Compress,
+			Console:        os.Stderr, // stdout queda libre para la salida de los comandos (--json)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to init logger: %w", err)
+		}
 
-		// Inicializar nuevo sistema
+		// Inicializar los directorios del sistema de sesiones
 		backup.UploadsDir = Cfg.UploadsDir
 		backup.BackupsDir = Cfg.BackupsDir
-		fmt.Println("[DEBUG] BackupsDir:", backup.BackupsDir)
 		backup.TempDir = Cfg.TempDir
-		backup.ModifiedMinutes = Cfg.ModifiedMinutes
-		backup.MaxConcurrency = Cfg.MaxConcurrency
+		backup.UploadTTL = time.Duration(Cfg.UploadTTLHours) * time.Hour
+
+		// Usuarios del panel web (ver gobackup user)
+		auth.UsersFile = Cfg.Auth.UsersFile
+		auth.TokensFile = Cfg.Auth.TokensFile
+		auth.SessionTTL = time.Duration(Cfg.Auth.SessionHours) * time.Hour
+
+		// Registro de auditoría (ver gobackup audit)
+		audit.File = Cfg.AuditFile
+		webhook.LogFile = Cfg.WebhookLogFile
+
+		// Perfiles de backup y el elegido con --profile
+		if err := applyConfig(Cfg, CfgOrigins); err != nil {
+			return err
+		}
+
+		// Abrir la base de historial (migra backup_history.json si existe)
+		if err := history.Init(Cfg.BackupsDir); err != nil {
+			return fmt.Errorf("failed to open history: %w", err)
+		}
 
-		fmt.Printf("Config loaded: Uploads=%s, Backups=%s, Temp=%s\n",
-			Cfg.UploadsDir, Cfg.BackupsDir, Cfg.TempDir)
+		logger.Debug("Configuración cargada",
+			"config", cfgPath, "uploads_dir", Cfg.UploadsDir, "backups_dir", Cfg.BackupsDir, "temp_dir", Cfg.TempDir)
 
 		return nil
 	},
@@ -52,12 +108,70 @@ var rootCmd = &cobra.Command{
 
 func init() {
 	// Flag global para especificar archivo de configuración
-	rootCmd.PersistentFlags().StringVarP(&cfgPath, "config", "c", "config/default.json", "Path to configuration file")
+	rootCmd.PersistentFlags().StringVarP(&cfgPath, "config", "c", defaultCfgPath, "Path to configuration file (.json, .yaml or .toml; env GOBACKUP_CONFIG)")
+	rootCmd.PersistentFlags().StringVarP(&profileName, "profile", "p", config.DefaultProfile, "Backup profile to use (see 'gobackup profiles')")
+	// Un flag por cada valor de la configuración (--server-port, --log-level, ...)
+	config.BindFlags(rootCmd.PersistentFlags())
+}
+
+// applyConfig aplica lo que se puede cambiar sin reiniciar: perfiles (con su
+// concurrencia), webhooks, nivel y formato del log y límites de subida. El
+// servidor web lo vuelve a llamar en cada recarga de la configuración.
+func applyConfig(cfg *config.Config, origins config.Origins) error {
+	profiles, err := backup.LoadProfiles(cfg)
+	if err != nil {
+		return err
+	}
+	if _, ok := profiles[profileName]; !ok {
+		return fmt.Errorf("unknown profile %q (available: %s)", profileName, strings.Join(cfg.ProfileNames(), ", "))
+	}
+	level, err := logger.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return err
+	}
+
+	backup.SetProfiles(profiles, profileName)
+	webhook.Configure(cfg.Webhooks)
+	logger.SetLevel(level)
+	logger.SetFormat(cfg.Log.Format)
+	const mb = 1024 * 1024
+	backup.SetUploadLimits(backup.Limits{
+		MaxFileSize:     int64(cfg.Quotas.MaxFileMB) * mb,
+		MaxSessionSize:  int64(cfg.Quotas.MaxSessionMB) * mb,
+		MaxSessionFiles: cfg.Quotas.MaxSessionFiles,
+		UserQuota:       int64(cfg.Quotas.UserQuotaMB) * mb,
+		MinFreeSpace:    int64(cfg.Quotas.MinFreeMB) * mb,
+		MaxPending:      cfg.Quotas.MaxPending,
+	})
+	Cfg, CfgOrigins = cfg, origins
+	return nil
+}
+
+// configPath elige el archivo de configuración: --config, GOBACKUP_CONFIG o el
+// de por defecto si existe. Vacío significa solo defaults, entorno y flags.
+func configPath(cmd *cobra.Command) string {
+	if cmd.Flags().Changed("config") {
+		return cfgPath
+	}
+	if path := os.Getenv("GOBACKUP_CONFIG"); path != "" {
+		return path
+	}
+	if _, err := os.Stat(defaultCfgPath); err != nil {
+		return ""
+	}
+	return defaultCfgPath
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	logger.Close()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		// cli usa códigos distintos para backups parciales o cancelados
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
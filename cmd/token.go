@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"gobackup/internal/audit"
+	"gobackup/internal/auth"
+	"os"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	tokenUser        string
+	tokenScopes      string
+	tokenExpiresDays int
+	tokenJSON        bool
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens for scripts",
+	Long: `Manage API tokens (auth.tokens_file). Scripts send them as
+"Authorization: Bearer <token>" instead of logging in.
+Scopes: stats (read-only), backup (upload and run backups), download
+(download backups) and admin (everything). Every scope can read status and
+stats. A token never gets more than the role of its user allows.
+Only a SHA-256 hash of each token is stored.`,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a token and print it once",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scopes, err := auth.ParseScopes(tokenScopes)
+		if err != nil {
+			return err
+		}
+		owner, err := auth.GetUser(tokenUser)
+		if err != nil {
+			return fmt.Errorf("user %s: %w", tokenUser, err)
+		}
+		if !auth.ScopesCover(owner.Role.Scopes(), scopes) {
+			return fmt.Errorf("role %s of %s does not allow scopes %s", owner.Role, owner.Name, tokenScopes)
+		}
+		if tokenExpiresDays < 0 {
+			return fmt.Errorf("--expires-days cannot be negative")
+		}
+
+		token, secret, err := auth.CreateToken(args[0], owner.Name, scopes, time.Duration(tokenExpiresDays)*24*time.Hour)
+		if err != nil {
+			return err
+		}
+		if err := auth.RecordTokenEvent(auth.TokenEvent{TokenID: token.ID, Name: token.Name, Action: "created", Actor: "cli:" + currentOSUser()}); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: could not write the token audit trail: %v\n", err)
+		}
+		recordAudit(audit.ActionToken, "token:"+token.ID+" ("+token.Name+")", audit.Success, "created")
+
+		fmt.Fprintf(os.Stderr, "Token %s (%s) created for %s. Store it now, it is not shown again:\n", token.ID, token.Name, owner.Name)
+		fmt.Println(secret)
+		return nil
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List tokens",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tokens, err := auth.ListTokens()
+		if err != nil {
+			return err
+		}
+		if tokenJSON {
+			// Sin el hash
+			for i := range tokens {
+				tokens[i].Hash = ""
+			}
+			if tokens == nil {
+				tokens = []auth.Token{}
+			}
+			return printJSON(tokens)
+		}
+		if len(tokens) == 0 {
+			fmt.Println("No tokens (create one with 'gobackup token create <name> --user <user> --scopes stats')")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tUSER\tSCOPES\tSTATUS\tCREATED\tEXPIRES\tLAST USED")
+		for _, t := range tokens {
+			scopes := make([]string, len(t.Scopes))
+			for i, s := range t.Scopes {
+				scopes[i] = string(s)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Owner, strings.Join(scopes, ","), t.Status(),
+				formatTokenTime(t.CreatedAt, "-"), formatTokenTime(t.ExpiresAt, "never"), formatTokenTime(t.LastUsedAt, "never"))
+		}
+		return w.Flush()
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <id|name>...",
+	Short: "Revoke tokens",
+	Long:  `Revoke tokens by ID or name. Revoked tokens stay in the list for the audit trail.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failed := 0
+		for _, ref := range args {
+			token, err := auth.FindToken(ref)
+			if err == nil {
+				token, err = auth.RevokeToken(token.ID)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", ref, err)
+				failed++
+				continue
+			}
+			if err := auth.RecordTokenEvent(auth.TokenEvent{TokenID: token.ID, Name: token.Name, Action: "revoked", Actor: "cli:" + currentOSUser()}); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: could not write the token audit trail: %v\n", err)
+			}
+			recordAudit(audit.ActionToken, "token:"+token.ID+" ("+token.Name+")", audit.Success, "revoked")
+			fmt.Printf("Revoked %s (%s)\n", token.ID, token.Name)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d token(s) could not be revoked", failed)
+		}
+		return nil
+	},
+}
+
+var tokenAuditCmd = &cobra.Command{
+	Use:   "audit [id|name]",
+	Short: "Show the audit trail of tokens",
+	Long: `Show token creations, revocations, rejected uses and the requests made
+with each token that change something or download a backup.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := ""
+		if len(args) == 1 {
+			token, err := auth.FindToken(args[0])
+			if err != nil {
+				return fmt.Errorf("%s: %w", args[0], err)
+			}
+			id = token.ID
+		}
+		events, err := auth.TokenEvents(id)
+		if err != nil {
+			return err
+		}
+		if tokenJSON {
+			if events == nil {
+				events = []auth.TokenEvent{}
+			}
+			return printJSON(events)
+		}
+		if len(events) == 0 {
+			fmt.Println("No token events")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tTOKEN\tNAME\tACTION\tBY/IP\tDETAIL")
+		for _, e := range events {
+			who := e.Actor
+			if who == "" {
+				who = e.IP
+				if e.Remote != "" {
+					who += " via " + e.Remote
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.TokenID, e.Name, e.Action, who, e.Detail)
+		}
+		return w.Flush()
+	},
+}
+
+func formatTokenTime(t time.Time, zero string) string {
+	if t.IsZero() {
+		return zero
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// currentOSUser es el usuario del sistema que corre el comando, para el historial
+func currentOSUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
+
+func init() {
+	tokenCreateCmd.Flags().StringVar(&tokenUser, "user", "", "User that owns the token (its role caps the scopes)")
+	tokenCreateCmd.Flags().StringVar(&tokenScopes, "scopes", string(auth.ScopeStats), "Comma separated scopes: stats, backup, download, admin")
+	tokenCreateCmd.Flags().IntVar(&tokenExpiresDays, "expires-days", 0, "Days until the token expires (0 = never)")
+	tokenCreateCmd.MarkFlagRequired("user")
+	tokenListCmd.Flags().BoolVar(&tokenJSON, "json", false, "Print JSON instead of a table")
+	tokenAuditCmd.Flags().BoolVar(&tokenJSON, "json", false, "Print JSON instead of a table")
+
+	tokenCmd.AddCommand(tokenCreateCmd, tokenListCmd, tokenRevokeCmd, tokenAuditCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
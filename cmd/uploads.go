@@ -1 +1,220 @@
 package cmd
+
+import (
+	"context"
+	"fmt"
+	"gobackup/internal/audit"
+	"gobackup/internal/auth"
+	"gobackup/internal/backup"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	uploadsJSON       bool
+	uploadsWithBackup bool
+	uploadsTTL        time.Duration
+	uploadsDryRun     bool
+	uploadsOwner      string
+)
+
+var uploadsCmd = &cobra.Command{
+	Use:   "uploads",
+	Short: "Manage upload sessions",
+	Long:  `Inspect, import, delete and garbage-collect the upload sessions stored in uploads_dir.`,
+}
+
+var uploadsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List upload sessions",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessions, err := backup.ListSessions()
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if uploadsJSON {
+			if sessions == nil {
+				sessions = []backup.Session{}
+			}
+			return printJSON(sessions)
+		}
+		if len(sessions) == 0 {
+			fmt.Println("No upload sessions")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tOWNER\tFILES\tSIZE\tLAST MODIFIED\tAGE\tBACKED UP")
+		for _, s := range sessions {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", s.ID, sessionOwner(s), s.Files, humanSize(s.Size),
+				s.LastModified.Local().Format("2006-01-02 15:04:05"),
+				time.Since(s.LastModified).Round(time.Minute), sessionState(s))
+		}
+		return w.Flush()
+	},
+}
+
+var uploadsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a session and its files",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := backup.GetSession(args[0])
+		if err != nil {
+			return fmt.Errorf("session %s: %w", args[0], err)
+		}
+		files, err := backup.SessionFiles(s.ID)
+		if err != nil {
+			return err
+		}
+		if uploadsJSON {
+			if files == nil {
+				files = []backup.SessionFile{}
+			}
+			return printJSON(map[string]interface{}{"session": s, "files": files})
+		}
+
+		fmt.Printf("Session:       %s\n", s.ID)
+		fmt.Printf("Path:          %s\n", s.Path)
+		fmt.Printf("Owner:         %s\n", sessionOwner(s))
+		fmt.Printf("Files:         %d (%s)\n", s.Files, humanSize(s.Size))
+		fmt.Printf("Created:       %s\n", s.Created.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("Last modified: %s\n", s.LastModified.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("Backed up:     %s\n", sessionState(s))
+		if len(files) == 0 {
+			return nil
+		}
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SIZE\tMODIFIED\tPATH")
+		for _, f := range files {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", humanSize(f.Size), f.Modified.Local().Format("2006-01-02 15:04:05"), f.Path)
+		}
+		return w.Flush()
+	},
+}
+
+var uploadsImportCmd = &cobra.Command{
+	Use:   "import <dir>",
+	Short: "Import a local directory as a new upload session",
+	Long: `Import a local directory as a new upload session. Without --owner the
+session is only visible to admins in the web panel.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if uploadsOwner != "" {
+			if _, err := auth.GetUser(uploadsOwner); err != nil {
+				return fmt.Errorf("user %s: %w", uploadsOwner, err)
+			}
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		id, err := backup.ImportDir(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		if uploadsOwner != "" {
+			if err := backup.SetOwner(id, backup.Owner{User: uploadsOwner}); err != nil {
+				return err
+			}
+		}
+		recordAudit(audit.ActionUpload, id, audit.Success, "importado de "+args[0])
+		// Solo el ID en stdout para poder usarlo en scripts
+		fmt.Println(id)
+		return nil
+	},
+}
+
+var uploadsRmCmd = &cobra.Command{
+	Use:   "rm <id>...",
+	Short: "Delete upload sessions",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failed := 0
+		for _, id := range args {
+			if err := backup.RemoveSession(id, uploadsWithBackup); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", id, err)
+				failed++
+				continue
+			}
+			recordAudit(audit.ActionDelete, id, audit.Success, "")
+			fmt.Printf("Removed %s\n", id)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d session(s) could not be removed", failed)
+		}
+		return nil
+	},
+}
+
+var uploadsGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete expired or already backed up sessions",
+	Long: `Delete upload sessions with no activity for longer than the TTL
+(upload_ttl_hours, or --ttl) and sessions whose backup is newer than their files.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ttl := backup.UploadTTL
+		if cmd.Flags().Changed("ttl") {
+			ttl = uploadsTTL
+		}
+		removed, err := backup.GCSessions(ttl, uploadsDryRun)
+		if err != nil {
+			return err
+		}
+		if uploadsJSON {
+			if removed == nil {
+				removed = []backup.GCResult{}
+			}
+			return printJSON(removed)
+		}
+
+		verb := "Removed"
+		if uploadsDryRun {
+			verb = "Would remove"
+		}
+		var total int64
+		for _, r := range removed {
+			fmt.Printf("%s %s (%s, %d files, %s)\n", verb, r.Session.ID, r.Reason, r.Session.Files, humanSize(r.Session.Size))
+			total += r.Session.Size
+		}
+		fmt.Printf("%d session(s), %s\n", len(removed), humanSize(total))
+		return nil
+	},
+}
+
+func sessionOwner(s backup.Session) string {
+	if s.Owner == "" {
+		return "-"
+	}
+	return s.Owner
+}
+
+func sessionState(s backup.Session) string {
+	switch {
+	case s.InProgress:
+		return "in progress"
+	case !s.BackedUp:
+		return "no"
+	case s.BackupTime.Before(s.LastModified):
+		return "outdated"
+	}
+	return "yes"
+}
+
+func init() {
+	uploadsListCmd.Flags().BoolVar(&uploadsJSON, "json", false, "Print JSON instead of a table")
+	uploadsShowCmd.Flags().BoolVar(&uploadsJSON, "json", false, "Print JSON")
+	uploadsImportCmd.Flags().StringVar(&uploadsOwner, "owner", "", "User that owns the new session")
+	uploadsRmCmd.Flags().BoolVar(&uploadsWithBackup, "with-backup", false, "Also delete the session's archive, log and report")
+	uploadsGCCmd.Flags().DurationVar(&uploadsTTL, "ttl", 0, "Override upload_ttl_hours (e.g. 24h; 0 = only backed up sessions)")
+	uploadsGCCmd.Flags().BoolVar(&uploadsDryRun, "dry-run", false, "Only show what would be removed")
+	uploadsGCCmd.Flags().BoolVar(&uploadsJSON, "json", false, "Print JSON")
+
+	uploadsCmd.AddCommand(uploadsListCmd, uploadsShowCmd, uploadsImportCmd, uploadsRmCmd, uploadsGCCmd)
+	rootCmd.AddCommand(uploadsCmd)
+}
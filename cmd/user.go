@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"gobackup/internal/audit"
+	"gobackup/internal/auth"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	userRole          string // rol de user add
+	userNewRole       string // rol de user passwd --role
+	userPasswordStdin bool
+	userJSON          bool
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage web panel users",
+	Long: `Manage the users that can log in to the web panel (auth.users_file).
+Roles: viewer (read-only), operator (upload, run and download backups) and
+admin (also delete backups, see logs, metrics and server settings).
+Passwords are stored as bcrypt hashes.`,
+}
+
+var userAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Create a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		role, err := auth.ParseRole(userRole)
+		if err != nil {
+			return err
+		}
+		if _, err := auth.GetUser(args[0]); err == nil {
+			return fmt.Errorf("user %s already exists (use 'gobackup user passwd')", args[0])
+		}
+		password, err := readNewPassword()
+		if err != nil {
+			return err
+		}
+		if err := auth.AddUser(args[0], password, role); err != nil {
+			return err
+		}
+		recordAudit(audit.ActionUser, args[0], audit.Success, "creado con rol "+string(role))
+		fmt.Printf("User %s created with role %s\n", args[0], role)
+		return nil
+	},
+}
+
+var userPasswdCmd = &cobra.Command{
+	Use:   "passwd <name>",
+	Short: "Change a user's password (and role with --role)",
+	Long: `Change a user's password. Open sessions of the user are closed.
+With --role only the role is changed and no password is asked.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := auth.GetUser(args[0]); err != nil {
+			return fmt.Errorf("user %s: %w", args[0], err)
+		}
+		if cmd.Flags().Changed("role") {
+			role, err := auth.ParseRole(userNewRole)
+			if err != nil {
+				return err
+			}
+			if err := auth.SetRole(args[0], role); err != nil {
+				return err
+			}
+			recordAudit(audit.ActionUser, args[0], audit.Success, "rol cambiado a "+string(role))
+			fmt.Printf("User %s now has role %s\n", args[0], role)
+			return nil
+		}
+
+		password, err := readNewPassword()
+		if err != nil {
+			return err
+		}
+		if err := auth.SetPassword(args[0], password); err != nil {
+			return err
+		}
+		recordAudit(audit.ActionUser, args[0], audit.Success, "contraseña cambiada")
+		fmt.Printf("Password of %s changed\n", args[0])
+		return nil
+	},
+}
+
+var userRmCmd = &cobra.Command{
+	Use:   "rm <name>...",
+	Short: "Delete users",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failed := 0
+		for _, name := range args {
+			if err := auth.RemoveUser(name); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+				failed++
+				continue
+			}
+			recordAudit(audit.ActionUser, name, audit.Success, "borrado")
+			fmt.Printf("Removed %s\n", name)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d user(s) could not be removed", failed)
+		}
+		return nil
+	},
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List users",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		users, err := auth.ListUsers()
+		if err != nil {
+			return err
+		}
+		if userJSON {
+			// Sin el hash de la contraseña
+			type userRow struct {
+				Name      string    `json:"name"`
+				Role      auth.Role `json:"role"`
+				CreatedAt time.Time `json:"created_at"`
+				UpdatedAt time.Time `json:"updated_at"`
+			}
+			rows := make([]userRow, 0, len(users))
+			for _, u := range users {
+				rows = append(rows, userRow{u.Name, u.Role, u.CreatedAt, u.UpdatedAt})
+			}
+			return printJSON(rows)
+		}
+		if len(users) == 0 {
+			fmt.Println("No users (create one with 'gobackup user add <name> --role admin')")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tROLE\tCREATED\tUPDATED")
+		for _, u := range users {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", u.Name, u.Role,
+				u.CreatedAt.Local().Format("2006-01-02 15:04:05"), u.UpdatedAt.Local().Format("2006-01-02 15:04:05"))
+		}
+		return w.Flush()
+	},
+}
+
+// readNewPassword pide la contraseña dos veces sin mostrarla, o la lee de la
+// primera línea de stdin con --password-stdin o si stdin no es una terminal
+func readNewPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if userPasswordStdin || !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", errors.New("no password on stdin")
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	first, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprint(os.Stderr, "Repeat password: ")
+	second, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(first) != string(second) {
+		return "", errors.New("passwords do not match")
+	}
+	return string(first), nil
+}
+
+func init() {
+	userAddCmd.Flags().StringVar(&userRole, "role", string(auth.RoleViewer), "Role: viewer, operator or admin")
+	userAddCmd.Flags().BoolVar(&userPasswordStdin, "password-stdin", false, "Read the password from stdin")
+	userPasswdCmd.Flags().StringVar(&userNewRole, "role", "", "Change the role instead of the password")
+	userPasswdCmd.Flags().BoolVar(&userPasswordStdin, "password-stdin", false, "Read the password from stdin")
+	userListCmd.Flags().BoolVar(&userJSON, "json", false, "Print JSON instead of a table")
+
+	userCmd.AddCommand(userAddCmd, userPasswdCmd, userRmCmd, userListCmd)
+	rootCmd.AddCommand(userCmd)
+}
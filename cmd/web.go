@@ -1,20 +1,135 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
+	"gobackup/internal/audit"
+	"gobackup/internal/auth"
+	"gobackup/internal/backup"
+	"gobackup/internal/config"
+	"gobackup/internal/logger"
 	"gobackup/internal/web"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
+// configPollInterval es cada cuánto se revisa si cambió el archivo de configuración
+const configPollInterval = 2 * time.Second
+
 var webCmd = &cobra.Command{
 	Use:   "web",
 	Short: "Run gobackup web server",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Starting web server on http://localhost:8080 ...")
-		web.StartServer()
+	Long: `Run the web server. The configuration file is watched (and re-read on SIGHUP):
+valid changes to profiles, concurrency, logging and the upload GC are applied
+without restarting or interrupting running backups. Changes to the port or
+directories are reported and take effect on the next restart.
+
+With tls.enabled the server speaks HTTPS on server_port, using tls.cert_file and
+tls.key_file or a self-signed certificate generated there on first run.
+tls.redirect_port adds a plain HTTP listener that redirects to HTTPS.
+
+On SIGINT or SIGTERM the server stops accepting backups and uploads, waits up to
+shutdown_timeout_seconds for running backups and cancels the rest (their partial
+archives are removed). The exit status is 0 if nothing was interrupted and 130
+otherwise; a second signal exits immediately.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Inicio de sesión en el panel: sin usuarios nadie puede entrar
+		web.AuthEnabled = Cfg.Auth.Enabled
+		if Cfg.Auth.Enabled {
+			users, err := auth.ListUsers()
+			if err != nil {
+				return fmt.Errorf("failed to read users: %w", err)
+			}
+			if len(users) == 0 {
+				logger.Warn("No hay usuarios: cree uno con 'gobackup user add <nombre> --role admin'", "users_file", Cfg.Auth.UsersFile)
+			}
+		} else {
+			logger.Warn("Inicio de sesión desactivado (auth.enabled=false): cualquiera con acceso al puerto puede usar el panel")
+		}
+
+		// SIGINT/SIGTERM inician el apagado ordenado; una segunda señal lo corta
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+
+		// Limpieza periódica de sesiones de subida vencidas o ya respaldadas. Se
+		// compara con los valores con que corre el GC, no con la última recarga.
+		stopGC := startSessionGC(ctx, Cfg)
+		gcMinutes, gcTTL := Cfg.UploadGCMinutes, Cfg.UploadTTLHours
+
+		watcher := config.NewWatcher(cfgPath, cmd.Flags(), Cfg, func(cfg *config.Config, origins config.Origins) error {
+			if err := applyConfig(cfg, origins); err != nil {
+				return err
+			}
+			web.SetRateLimits(cfg.RateLimit)
+			if cfg.UploadGCMinutes != gcMinutes || cfg.UploadTTLHours != gcTTL {
+				stopGC()
+				stopGC = startSessionGC(ctx, cfg)
+				gcMinutes, gcTTL = cfg.UploadGCMinutes, cfg.UploadTTLHours
+			}
+			return nil
+		})
+		web.ConfigWatcher = watcher
+		go watcher.Run(ctx, configPollInterval, func(changed []string, err error) {
+			if err != nil {
+				logger.Error("Recarga de configuración rechazada, se mantiene la anterior", "error", err)
+				recordConfigAudit(audit.Failure, err.Error())
+				return
+			}
+			info := watcher.Info()
+			if len(info.PendingRestart) > 0 {
+				logger.Warn("Cambios de configuración que requieren reiniciar", "keys", strings.Join(info.PendingRestart, ","))
+			}
+			if len(changed) == 0 {
+				logger.Info("Configuración sin cambios aplicables")
+				return
+			}
+			logger.Info("Configuración recargada", "revision", info.Revision, "changed", strings.Join(changed, ","))
+			recordConfigAudit(audit.Success, strings.Join(changed, ","))
+		})
+
+		web.TLS = Cfg.TLS
+		web.TrustedProxies = Cfg.TrustedProxyList()
+		web.SetRateLimits(Cfg.RateLimit)
+		scheme := "http"
+		if Cfg.TLS.Enabled {
+			scheme = "https"
+		}
+		fmt.Printf("Starting web server on %s://localhost:%d ...\n", scheme, Cfg.ServerPort)
+		err := web.StartServer(ctx, Cfg.ServerPort, time.Duration(Cfg.ShutdownTimeoutSeconds)*time.Second)
+		if errors.Is(err, web.ErrInterrupted) {
+			return &exitError{code: exitCancelled, err: err}
+		}
+		return err
 	},
 }
 
+// startSessionGC lanza el GC de sesiones con los valores de cfg y devuelve cómo pararlo
+func startSessionGC(ctx context.Context, cfg *config.Config) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	backup.StartSessionGC(ctx, time.Duration(cfg.UploadGCMinutes)*time.Minute, time.Duration(cfg.UploadTTLHours)*time.Hour)
+	return cancel
+}
+
 func init() {
+	// Atajo de --server-port (lo aplica config.Load)
+	webCmd.Flags().Int("port", 0, "Port for the web server (overrides server_port)")
 	rootCmd.AddCommand(webCmd)
 }
+
+// recordConfigAudit anota una recarga de la configuración (el archivo lo cambió
+// alguien con acceso al equipo, no se sabe quién)
+func recordConfigAudit(outcome, detail string) {
+	err := audit.Record(audit.Event{Actor: "sistema", Action: audit.ActionConfig, Target: cfgPath, Outcome: outcome, Detail: detail})
+	if err != nil {
+		logger.Error("Error guardando el registro de auditoría", "action", audit.ActionConfig, "error", err)
+	}
+}
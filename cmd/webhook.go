@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"gobackup/internal/backup"
+	"gobackup/internal/config"
+	"gobackup/internal/webhook"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	webhookJSON  bool
+	webhookEvent string
+	webhookLines int
+)
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Inspect and test the configured webhooks",
+	Long: `Webhooks are defined in the "webhooks" section of the config. Each one
+receives a POST on job start, success, partial success, failure and cancellation
+(or only the events listed in "events"), signed with HMAC-SHA256 when it has a
+secret, and retried with backoff. Every delivery is written to webhook_log_file.`,
+}
+
+var webhookListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the configured webhooks",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names := webhook.Names()
+		if len(names) == 0 {
+			fmt.Println("No webhooks configured")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tURL\tFORMAT\tEVENTS\tSIGNED")
+		for _, name := range names {
+			h, _ := webhook.Get(name)
+			format, events := h.Format, strings.Join(h.Events, ",")
+			if format == "" {
+				format = "json"
+			}
+			if events == "" {
+				events = "all"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\n", name, webhook.Redact(h.URL), format, events, h.SecretEnv != "" || h.SecretFile != "")
+		}
+		return w.Flush()
+	},
+}
+
+var webhookTestCmd = &cobra.Command{
+	Use:   "test <name>",
+	Short: "Send a sample event to a webhook and show the result",
+	Long: `Send a sample event to a webhook, with the same format, signature and
+retries as a real one, and wait for the result. With --event job.failed (or any
+other job event) the message carries made-up stats to preview the template.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if webhookEvent != "" && webhookEvent != webhook.EventTest && !slices.Contains(config.WebhookEvents, webhookEvent) {
+			return fmt.Errorf("unknown event %q (use %s or %s)", webhookEvent, webhook.EventTest, strings.Join(config.WebhookEvents, ", "))
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		d, err := webhook.Send(ctx, args[0], webhook.TestPayload(webhookEvent, backup.ActiveProfile().Name))
+		if err != nil {
+			return err
+		}
+		if webhookJSON {
+			return printJSON(d)
+		}
+		if !d.OK {
+			return fmt.Errorf("delivery %s failed after %d attempt(s): %s", d.ID, d.Attempts, d.Error)
+		}
+		fmt.Printf("Delivered %s: HTTP %d in %d ms (%d attempt(s))\n", d.ID, d.StatusCode, d.DurationMS, d.Attempts)
+		return nil
+	},
+}
+
+var webhookLogCmd = &cobra.Command{
+	Use:   "log [name]",
+	Short: "Show the delivery log",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		list, err := webhook.Deliveries(name, webhookLines)
+		if err != nil {
+			return err
+		}
+		if webhookJSON {
+			if list == nil {
+				list = []webhook.Delivery{}
+			}
+			return printJSON(list)
+		}
+		if len(list) == 0 {
+			fmt.Println("No deliveries")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tWEBHOOK\tEVENT\tJOB\tRESULT\tATTEMPTS\tSTATUS\tERROR")
+		for _, d := range list {
+			result := "ok"
+			if !d.OK {
+				result = "failed"
+			}
+			status := "-"
+			if d.StatusCode != 0 {
+				status = fmt.Sprint(d.StatusCode)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n", d.Time.Local().Format("2006-01-02 15:04:05"),
+				d.Webhook, d.Event, d.JobID, result, d.Attempts, status, d.Error)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	webhookTestCmd.Flags().StringVar(&webhookEvent, "event", webhook.EventTest, "Event to simulate (test, job.started, job.success, job.partial, job.failed, job.cancelled)")
+	webhookLogCmd.Flags().IntVarP(&webhookLines, "lines", "n", 50, "Only the last N deliveries (0 = all)")
+	webhookCmd.PersistentFlags().BoolVar(&webhookJSON, "json", false, "Print JSON instead of a table")
+
+	webhookCmd.AddCommand(webhookListCmd, webhookTestCmd, webhookLogCmd)
+	rootCmd.AddCommand(webhookCmd)
+}
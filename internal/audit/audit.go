@@ -0,0 +1,270 @@
+// Package audit guarda quién hizo qué con los backups: subidas, descargas,
+// borrados, cambios de configuración, usuarios y tokens e inicios de sesión.
+// Es un archivo aparte del log operativo, una línea JSON por evento, que solo
+// se agrega. Cada evento lleva el hash del anterior, así que editar o borrar
+// una línea del medio rompe la cadena y Verify lo detecta.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File es el archivo de auditoría (lo inicializa cmd/root.go desde audit_file)
+var File = "config/audit.jsonl"
+
+// Acciones registradas
+const (
+	ActionLogin    = "login"
+	ActionLogout   = "logout"
+	ActionUpload   = "upload"
+	ActionBackup   = "backup"
+	ActionDownload = "download"
+	ActionDelete   = "delete"
+	ActionConfig   = "config"
+	ActionUser     = "user"
+	ActionToken    = "token"
+)
+
+// Resultados
+const (
+	Success = "success"
+	Failure = "failure"
+	Denied  = "denied" // sin permiso o credenciales inválidas
+)
+
+// Event es una entrada del registro de auditoría
+type Event struct {
+	Seq     int64     `json:"seq"`
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"` // usuario, token:<id> (<nombre>) o cli:<usuario del sistema>
+	IP      string    `json:"ip,omitempty"`
+	Remote  string    `json:"remote_ip,omitempty"` // la conexión, si IP salió del X-Forwarded-For de un proxy de confianza
+	Action  string    `json:"action"`
+	Target  string    `json:"target,omitempty"` // sesión, backup, archivo o usuario afectado
+	Outcome string    `json:"outcome"`
+	Detail  string    `json:"detail,omitempty"`
+	Prev    string    `json:"prev"` // hash del evento anterior (vacío en el primero)
+	Hash    string    `json:"hash"` // sha256 del evento con hash vacío
+}
+
+// sum calcula el hash del evento: el JSON con Hash vacío, que incluye Prev
+func (e Event) sum() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:]), nil
+}
+
+var mu sync.Mutex
+
+// Record agrega un evento al final de la cadena. Completa Seq, Time, Prev y Hash.
+func Record(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	if e.Outcome == "" {
+		e.Outcome = Success
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(File), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(File, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// El servidor y los comandos de la terminal pueden escribir a la vez
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer unlockFile(f)
+
+	last, err := lastEvent(f)
+	if err != nil {
+		return err
+	}
+	e.Seq, e.Prev = last.Seq+1, last.Hash
+	if e.Hash, err = e.sum(); err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// lastEvent lee la última línea completa del archivo (el final alcanza: los
+// eventos son chicos)
+func lastEvent(f *os.File) (Event, error) {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return Event{}, err
+	}
+	const tail = 64 << 10
+	start := info.Size() - tail
+	if start < 0 {
+		start = 0
+	}
+	buf := make([]byte, info.Size()-start)
+	if _, err := f.ReadAt(buf, start); err != nil && err != io.EOF {
+		return Event{}, err
+	}
+	lines := bytes.Split(bytes.TrimRight(buf, "\n"), []byte("\n"))
+	var e Event
+	if err := json.Unmarshal(lines[len(lines)-1], &e); err != nil {
+		return Event{}, fmt.Errorf("última línea de %s ilegible (¿archivo modificado?): %w", File, err)
+	}
+	return e, nil
+}
+
+// Filter elige eventos de Read. Los campos vacíos no filtran.
+type Filter struct {
+	Since   time.Time
+	Until   time.Time
+	Actor   string
+	Action  string
+	Target  string // coincide si lo contiene
+	Outcome string
+	Limit   int // los últimos Limit que cumplen (0 = todos)
+}
+
+func (f Filter) match(e Event) bool {
+	switch {
+	case !f.Since.IsZero() && e.Time.Before(f.Since),
+		!f.Until.IsZero() && e.Time.After(f.Until),
+		f.Actor != "" && e.Actor != f.Actor && !strings.HasPrefix(e.Actor, f.Actor+" "),
+		f.Action != "" && e.Action != f.Action,
+		f.Target != "" && !strings.Contains(e.Target, f.Target),
+		f.Outcome != "" && e.Outcome != f.Outcome:
+		return false
+	}
+	return true
+}
+
+// Read devuelve los eventos que cumplen el filtro, del más viejo al más nuevo
+func Read(f Filter) ([]Event, error) {
+	var events []Event
+	err := scan(func(e Event, _ []byte) error {
+		if f.match(e) {
+			events = append(events, e)
+			if f.Limit > 0 && len(events) > f.Limit {
+				events = events[1:]
+			}
+		}
+		return nil
+	})
+	return events, err
+}
+
+// Export escribe en w las líneas originales de los eventos que cumplen el
+// filtro, tal cual están en el archivo (así se pueden verificar afuera)
+func Export(w io.Writer, f Filter) error {
+	return scan(func(e Event, line []byte) error {
+		if !f.match(e) {
+			return nil
+		}
+		_, err := w.Write(append(line, '\n'))
+		return err
+	})
+}
+
+// scan recorre el archivo línea por línea
+func scan(fn func(e Event, line []byte) error) error {
+	f, err := os.Open(File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // Verify la marca; para consultar se ignora
+		}
+		if err := fn(e, scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Verification es el resultado de revisar la cadena
+type Verification struct {
+	Events int    `json:"events"`
+	OK     bool   `json:"ok"`
+	Line   int    `json:"line,omitempty"`  // primera línea con problemas
+	Error  string `json:"error,omitempty"` // qué está mal en esa línea
+	Last   string `json:"last,omitempty"`  // hash del último evento (para anotarlo afuera)
+}
+
+// Verify recalcula los hashes y revisa que cada evento apunte al anterior
+func Verify() (Verification, error) {
+	v := Verification{OK: true}
+	f, err := os.Open(File)
+	if os.IsNotExist(err) {
+		return v, nil
+	}
+	if err != nil {
+		return v, err
+	}
+	defer f.Close()
+
+	var prev Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		fail := func(format string, args ...interface{}) {
+			v.OK, v.Line, v.Error = false, line, fmt.Sprintf(format, args...)
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			fail("JSON inválido: %v", err)
+			break
+		}
+		sum, err := e.sum()
+		switch {
+		case err != nil:
+			fail("%v", err)
+		case sum != e.Hash:
+			fail("el hash no coincide con el contenido (evento %d modificado)", e.Seq)
+		case e.Prev != prev.Hash:
+			fail("no sigue al evento anterior (falta o sobra un evento antes del %d)", e.Seq)
+		case e.Seq != prev.Seq+1:
+			fail("secuencia %d después de %d", e.Seq, prev.Seq)
+		}
+		if !v.OK {
+			break
+		}
+		v.Events++
+		v.Last = e.Hash
+		prev = e
+	}
+	return v, scanner.Err()
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// SessionTTL es cuánto dura una sesión del panel sin volver a iniciar sesión
+// (se inicializa en cmd/root.go)
+var SessionTTL = 12 * time.Hour
+
+// Session es una sesión iniciada en el navegador. Vive solo en memoria del
+// servidor: reiniciarlo obliga a volver a entrar.
+type Session struct {
+	Token     string
+	CSRF      string // se exige en cada petición que modifica algo
+	User      string
+	ExpiresAt time.Time
+	hash      string // hash de la contraseña al iniciar sesión
+}
+
+var sessions = struct {
+	sync.Mutex
+	byToken map[string]Session
+}{byToken: make(map[string]Session)}
+
+// RandomToken devuelve n bytes aleatorios en hexadecimal
+func RandomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand no falla en los sistemas soportados
+	}
+	return hex.EncodeToString(b)
+}
+
+// NewSession abre una sesión para un usuario ya autenticado
+func NewSession(u User) Session {
+	s := Session{
+		Token:     RandomToken(32),
+		CSRF:      RandomToken(32),
+		User:      u.Name,
+		ExpiresAt: time.Now().Add(SessionTTL),
+		hash:      u.Hash,
+	}
+	sessions.Lock()
+	defer sessions.Unlock()
+	// Aprovechar para olvidar las sesiones vencidas
+	for token, old := range sessions.byToken {
+		if time.Now().After(old.ExpiresAt) {
+			delete(sessions.byToken, token)
+		}
+	}
+	sessions.byToken[s.Token] = s
+	return s
+}
+
+// LookupSession devuelve la sesión y el usuario actual. Falla si venció, si el
+// usuario se borró o si cambió su contraseña desde que inició sesión.
+func LookupSession(token string) (Session, User, bool) {
+	sessions.Lock()
+	s, ok := sessions.byToken[token]
+	if ok && time.Now().After(s.ExpiresAt) {
+		delete(sessions.byToken, token)
+		ok = false
+	}
+	sessions.Unlock()
+	if !ok {
+		return Session{}, User{}, false
+	}
+
+	u, err := GetUser(s.User)
+	if err != nil || u.Hash != s.hash {
+		EndSession(token)
+		return Session{}, User{}, false
+	}
+	return s, u, true
+}
+
+// EndSession cierra una sesión
+func EndSession(token string) {
+	sessions.Lock()
+	defer sessions.Unlock()
+	delete(sessions.byToken, token)
+}
+
+// CheckCSRF compara el token CSRF recibido con el de la sesión
+func (s Session) CheckCSRF(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.CSRF)) == 1
+}
@@ -0,0 +1,413 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scope es un permiso que se le da a un token de API. Todos permiten consultar
+// (estado, estadísticas, lista de backups); los demás agregan una acción.
+type Scope string
+
+const (
+	ScopeStats    Scope = "stats"    // solo lectura
+	ScopeBackup   Scope = "backup"   // subir archivos y lanzar backups
+	ScopeDownload Scope = "download" // descargar backups
+	ScopeAdmin    Scope = "admin"    // todo, incluido borrar y configurar
+)
+
+// Scopes lista los permisos existentes
+var Scopes = []Scope{ScopeStats, ScopeBackup, ScopeDownload, ScopeAdmin}
+
+// ParseScopes valida una lista de permisos separados por comas
+func ParseScopes(s string) ([]Scope, error) {
+	var scopes []Scope
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		scope := Scope(part)
+		if !scope.Valid() {
+			return nil, fmt.Errorf("permiso desconocido %q (use stats, backup, download o admin)", part)
+		}
+		if !hasScope(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("el token necesita al menos un permiso")
+	}
+	return scopes, nil
+}
+
+// Valid indica si el permiso existe
+func (s Scope) Valid() bool {
+	return hasScope(Scopes, s)
+}
+
+func hasScope(scopes []Scope, s Scope) bool {
+	for _, scope := range scopes {
+		if scope == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Scopes devuelve los permisos equivalentes al rol, para tratar igual a usuarios y tokens
+func (r Role) Scopes() []Scope {
+	switch r {
+	case RoleViewer:
+		return []Scope{ScopeStats}
+	case RoleOperator:
+		return []Scope{ScopeStats, ScopeBackup, ScopeDownload}
+	case RoleAdmin:
+		return []Scope{ScopeAdmin}
+	}
+	return nil
+}
+
+// ScopesAllow indica si una lista de permisos alcanza para need (admin alcanza para todo)
+func ScopesAllow(scopes []Scope, need Scope) bool {
+	return hasScope(scopes, ScopeAdmin) || hasScope(scopes, need)
+}
+
+// ScopesCover indica si have alcanza para dar todos los permisos de want
+func ScopesCover(have, want []Scope) bool {
+	for _, s := range want {
+		if !ScopesAllow(have, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// Token es un token de API para scripts. Se muestra una sola vez al crearlo;
+// solo se guarda su SHA-256 (el token ya es aleatorio, no hace falta bcrypt).
+type Token struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Scopes     []Scope   `json:"scopes"`
+	Hash       string    `json:"hash,omitempty"`
+	Owner      string    `json:"owner"` // usuario que lo creó
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at,omitzero"`
+	LastUsedAt time.Time `json:"last_used_at,omitzero"`
+	LastUsedIP string    `json:"last_used_ip,omitempty"`
+	RevokedAt  time.Time `json:"revoked_at,omitzero"`
+}
+
+// Active indica si el token todavía se puede usar
+func (t Token) Active() bool {
+	return t.RevokedAt.IsZero() && (t.ExpiresAt.IsZero() || time.Now().Before(t.ExpiresAt))
+}
+
+// Status describe el estado del token para listados
+func (t Token) Status() string {
+	switch {
+	case !t.RevokedAt.IsZero():
+		return "revoked"
+	case !t.ExpiresAt.IsZero() && !time.Now().Before(t.ExpiresAt):
+		return "expired"
+	}
+	return "active"
+}
+
+// TokenPrefix encabeza los tokens para reconocerlos (p. ej. en escáneres de secretos)
+const TokenPrefix = "gbk_"
+
+// lastUsedEvery limita cada cuánto se reescribe el archivo al usar un token
+const lastUsedEvery = time.Minute
+
+var (
+	// ErrTokenNotFound se devuelve si el token no existe
+	ErrTokenNotFound = errors.New("token no encontrado")
+	// ErrBadToken se devuelve si el token es inválido, venció o fue revocado
+	ErrBadToken = errors.New("token inválido, vencido o revocado")
+)
+
+// TokensFile es el archivo JSON con los tokens (se inicializa en cmd/root.go).
+// Como UsersFile, se relee en cada consulta.
+var TokensFile string
+
+var tokensMu sync.Mutex
+
+type tokensDoc struct {
+	Tokens []Token `json:"tokens"`
+}
+
+func readTokens() ([]Token, error) {
+	data, err := os.ReadFile(TokensFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc tokensDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", TokensFile, err)
+	}
+	return doc.Tokens, nil
+}
+
+// updateTokens lee, modifica y guarda los tokens
+func updateTokens(fn func(tokens []Token) ([]Token, error)) error {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+	tokens, err := readTokens()
+	if err != nil {
+		return err
+	}
+	tokens, err = fn(tokens)
+	if err != nil {
+		return err
+	}
+	return writeFile(TokensFile, tokensDoc{Tokens: tokens})
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateToken crea un token y devuelve el registro y el token en claro, que no
+// se vuelve a poder ver. ttl 0 es un token sin vencimiento.
+func CreateToken(name, owner string, scopes []Scope, ttl time.Duration) (Token, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 64 {
+		return Token{}, "", errors.New("el nombre del token debe tener entre 1 y 64 caracteres")
+	}
+	for _, s := range scopes {
+		if !s.Valid() {
+			return Token{}, "", fmt.Errorf("permiso desconocido %q", s)
+		}
+	}
+	if len(scopes) == 0 {
+		return Token{}, "", errors.New("el token necesita al menos un permiso")
+	}
+
+	t := Token{
+		ID:        RandomToken(6),
+		Name:      name,
+		Scopes:    scopes,
+		Owner:     owner,
+		CreatedAt: time.Now(),
+	}
+	if ttl > 0 {
+		t.ExpiresAt = t.CreatedAt.Add(ttl)
+	}
+	// El ID va dentro del token para encontrarlo sin recorrer todos los hashes
+	secret := TokenPrefix + t.ID + "_" + RandomToken(24)
+	t.Hash = hashToken(secret)
+
+	err := updateTokens(func(tokens []Token) ([]Token, error) {
+		return append(tokens, t), nil
+	})
+	if err != nil {
+		return Token{}, "", err
+	}
+	return t, secret, nil
+}
+
+// GetToken devuelve un token por ID
+func GetToken(id string) (Token, error) {
+	tokens, err := ListTokens()
+	if err != nil {
+		return Token{}, err
+	}
+	for _, t := range tokens {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return Token{}, ErrTokenNotFound
+}
+
+// FindToken busca un token por ID o, si no hay ninguno con ese ID, por nombre
+func FindToken(ref string) (Token, error) {
+	tokens, err := ListTokens()
+	if err != nil {
+		return Token{}, err
+	}
+	var byName []Token
+	for _, t := range tokens {
+		if t.ID == ref {
+			return t, nil
+		}
+		if t.Name == ref {
+			byName = append(byName, t)
+		}
+	}
+	switch len(byName) {
+	case 0:
+		return Token{}, ErrTokenNotFound
+	case 1:
+		return byName[0], nil
+	}
+	return Token{}, fmt.Errorf("hay %d tokens llamados %q, use el ID", len(byName), ref)
+}
+
+// RevokeToken revoca un token; se conserva en el archivo para el historial
+func RevokeToken(id string) (Token, error) {
+	var revoked Token
+	err := updateTokens(func(tokens []Token) ([]Token, error) {
+		for i, t := range tokens {
+			if t.ID != id {
+				continue
+			}
+			if t.RevokedAt.IsZero() {
+				tokens[i].RevokedAt = time.Now()
+			}
+			revoked = tokens[i]
+			return tokens, nil
+		}
+		return nil, ErrTokenNotFound
+	})
+	return revoked, err
+}
+
+// ListTokens devuelve los tokens del más nuevo al más viejo
+func ListTokens() ([]Token, error) {
+	tokensMu.Lock()
+	tokens, err := readTokens()
+	tokensMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.After(tokens[j].CreatedAt) })
+	return tokens, nil
+}
+
+// AuthenticateToken valida un token recibido en Authorization: Bearer y anota
+// cuándo y desde dónde se usó (como mucho una vez por minuto)
+func AuthenticateToken(secret, ip string) (Token, error) {
+	rest, ok := strings.CutPrefix(secret, TokenPrefix)
+	if !ok {
+		return Token{}, ErrBadToken
+	}
+	id, _, ok := strings.Cut(rest, "_")
+	if !ok {
+		return Token{}, ErrBadToken
+	}
+
+	tokens, err := ListTokens()
+	if err != nil {
+		return Token{}, err
+	}
+	var found *Token
+	for i := range tokens {
+		if tokens[i].ID == id {
+			found = &tokens[i]
+			break
+		}
+	}
+	if found == nil || subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(found.Hash)) != 1 {
+		return Token{}, ErrBadToken
+	}
+	t := *found
+	if !t.Active() {
+		return t, ErrBadToken
+	}
+
+	if time.Since(t.LastUsedAt) >= lastUsedEvery || t.LastUsedIP != ip {
+		now := time.Now()
+		err := updateTokens(func(tokens []Token) ([]Token, error) {
+			for i := range tokens {
+				if tokens[i].ID == t.ID {
+					tokens[i].LastUsedAt, tokens[i].LastUsedIP = now, ip
+				}
+			}
+			return tokens, nil
+		})
+		if err != nil {
+			return Token{}, err
+		}
+		t.LastUsedAt, t.LastUsedIP = now, ip
+	}
+	return t, nil
+}
+
+// TokenEvent es una entrada del historial de acciones con tokens
+type TokenEvent struct {
+	Time    time.Time `json:"time"`
+	TokenID string    `json:"token_id"`
+	Name    string    `json:"name,omitempty"`
+	Action  string    `json:"action"`          // created, revoked, denied, request
+	Actor   string    `json:"actor,omitempty"` // quién creó o revocó el token
+	IP      string    `json:"ip,omitempty"`
+	Remote  string    `json:"remote_ip,omitempty"` // la conexión, si IP salió del X-Forwarded-For de un proxy de confianza
+	Detail  string    `json:"detail,omitempty"`    // p. ej. "POST /api/backup/create 200"
+}
+
+// TokenAuditFile es el historial de acciones con tokens, una línea JSON por evento,
+// junto al archivo de tokens
+func TokenAuditFile() string {
+	return filepath.Join(filepath.Dir(TokensFile), "tokens_audit.jsonl")
+}
+
+var auditMu sync.Mutex
+
+// RecordTokenEvent agrega un evento al historial (solo se agrega, nunca se reescribe)
+func RecordTokenEvent(e TokenEvent) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	path := TokenAuditFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// TokenEvents devuelve el historial, filtrado por token si id no está vacío
+func TokenEvents(id string) ([]TokenEvent, error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	f, err := os.Open(TokenAuditFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []TokenEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e TokenEvent
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue // línea cortada por un corte de luz, se ignora
+		}
+		if id == "" || e.TokenID == id {
+			events = append(events, e)
+		}
+	}
+	return events, scanner.Err()
+}
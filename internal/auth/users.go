@@ -0,0 +1,296 @@
+// Package auth guarda los usuarios del panel web, sus sesiones y los tokens de API
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Role define qué puede hacer un usuario. Cada rol incluye a los anteriores:
+// viewer consulta, operator además sube archivos y lanza backups, admin además borra
+// y ve la configuración y los logs del servidor.
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+// Roles lista los roles de menor a mayor
+var Roles = []Role{RoleViewer, RoleOperator, RoleAdmin}
+
+func (r Role) level() int {
+	for i, role := range Roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// Valid indica si el rol existe
+func (r Role) Valid() bool {
+	return r.level() >= 0
+}
+
+// Allows indica si el rol alcanza para algo que requiere min
+func (r Role) Allows(min Role) bool {
+	return r.Valid() && r.level() >= min.level()
+}
+
+// ParseRole valida un rol escrito por el usuario
+func ParseRole(s string) (Role, error) {
+	r := Role(s)
+	if !r.Valid() {
+		return "", fmt.Errorf("rol desconocido %q (use viewer, operator o admin)", s)
+	}
+	return r, nil
+}
+
+// User es una cuenta del panel web. Solo se guarda el hash bcrypt de la contraseña.
+type User struct {
+	Name      string    `json:"name"`
+	Role      Role      `json:"role"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MinPasswordLength es el largo mínimo de una contraseña
+const MinPasswordLength = 8
+
+var (
+	// ErrUserNotFound se devuelve si el usuario no existe
+	ErrUserNotFound = errors.New("usuario no encontrado")
+	// ErrUserExists se devuelve al crear un usuario que ya existe
+	ErrUserExists = errors.New("el usuario ya existe")
+	// ErrBadCredentials se devuelve si el usuario o la contraseña no coinciden
+	ErrBadCredentials = errors.New("usuario o contraseña incorrectos")
+)
+
+// UsersFile es el archivo JSON con los usuarios (se inicializa en cmd/root.go).
+// Lo comparten el servidor y los comandos: se relee en cada consulta.
+var UsersFile string
+
+var (
+	usersMu  sync.Mutex
+	userName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@-]{0,63}$`)
+)
+
+type usersDoc struct {
+	Users []User `json:"users"`
+}
+
+func readUsers() (map[string]User, error) {
+	users := make(map[string]User)
+	data, err := os.ReadFile(UsersFile)
+	if os.IsNotExist(err) {
+		return users, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc usersDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", UsersFile, err)
+	}
+	for _, u := range doc.Users {
+		users[u.Name] = u
+	}
+	return users, nil
+}
+
+// writeUsers guarda los usuarios ordenados por nombre
+func writeUsers(users map[string]User) error {
+	doc := usersDoc{Users: make([]User, 0, len(users))}
+	for _, u := range users {
+		doc.Users = append(doc.Users, u)
+	}
+	sort.Slice(doc.Users, func(i, j int) bool { return doc.Users[i].Name < doc.Users[j].Name })
+	return writeFile(UsersFile, doc)
+}
+
+// writeFile guarda v como JSON con permisos 0600 (escribe y renombra para no
+// dejar el archivo a medias)
+func writeFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".auth-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// updateUsers lee, modifica y guarda los usuarios
+func updateUsers(fn func(users map[string]User) error) error {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+	users, err := readUsers()
+	if err != nil {
+		return err
+	}
+	if err := fn(users); err != nil {
+		return err
+	}
+	return writeUsers(users)
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("la contraseña debe tener al menos %d caracteres", MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// AddUser crea un usuario
+func AddUser(name, password string, role Role) error {
+	if !userName.MatchString(name) {
+		return fmt.Errorf("nombre de usuario inválido %q (letras, números, . _ @ -)", name)
+	}
+	if !role.Valid() {
+		return fmt.Errorf("rol desconocido %q", role)
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return updateUsers(func(users map[string]User) error {
+		if _, ok := users[name]; ok {
+			return ErrUserExists
+		}
+		now := time.Now()
+		users[name] = User{Name: name, Role: role, Hash: hash, CreatedAt: now, UpdatedAt: now}
+		return nil
+	})
+}
+
+// SetPassword cambia la contraseña; las sesiones abiertas del usuario dejan de valer
+func SetPassword(name, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return updateUsers(func(users map[string]User) error {
+		u, ok := users[name]
+		if !ok {
+			return ErrUserNotFound
+		}
+		u.Hash, u.UpdatedAt = hash, time.Now()
+		users[name] = u
+		return nil
+	})
+}
+
+// SetRole cambia el rol de un usuario
+func SetRole(name string, role Role) error {
+	if !role.Valid() {
+		return fmt.Errorf("rol desconocido %q", role)
+	}
+	return updateUsers(func(users map[string]User) error {
+		u, ok := users[name]
+		if !ok {
+			return ErrUserNotFound
+		}
+		u.Role, u.UpdatedAt = role, time.Now()
+		users[name] = u
+		return nil
+	})
+}
+
+// RemoveUser borra un usuario
+func RemoveUser(name string) error {
+	return updateUsers(func(users map[string]User) error {
+		if _, ok := users[name]; !ok {
+			return ErrUserNotFound
+		}
+		delete(users, name)
+		return nil
+	})
+}
+
+// GetUser devuelve un usuario por nombre
+func GetUser(name string) (User, error) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+	users, err := readUsers()
+	if err != nil {
+		return User{}, err
+	}
+	u, ok := users[name]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return u, nil
+}
+
+// ListUsers devuelve los usuarios ordenados por nombre
+func ListUsers() ([]User, error) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+	users, err := readUsers()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]User, 0, len(users))
+	for _, u := range users {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// dummyHash se compara cuando el usuario no existe, para que la respuesta tarde
+// lo mismo y no revele qué usuarios hay
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("gobackup-dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// Authenticate comprueba usuario y contraseña
+func Authenticate(name, password string) (User, error) {
+	u, err := GetUser(name)
+	if errors.Is(err, ErrUserNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return User{}, ErrBadCredentials
+	}
+	if err != nil {
+		return User{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.Hash), []byte(password)) != nil {
+		return User{}, ErrBadCredentials
+	}
+	return u, nil
+}
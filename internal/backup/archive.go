@@ -0,0 +1,210 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"gobackup/internal/logger"
+	"gobackup/internal/safepath"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Formatos de archivo soportados
+const (
+	FormatZip    = "zip"
+	FormatTarGz  = "tar.gz"
+	FormatTarZst = "tar.zst"
+)
+
+// Formats lista los formatos en orden de preferencia
+var Formats = []string{FormatZip, FormatTarGz, FormatTarZst}
+
+// ParseFormat valida un formato (acepta también "tgz" y "tzst")
+func ParseFormat(s string) (string, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, ".")) {
+	case "", "zip":
+		return FormatZip, nil
+	case "tar.gz", "tgz":
+		return FormatTarGz, nil
+	case "tar.zst", "tzst", "tar.zstd":
+		return FormatTarZst, nil
+	}
+	return "", fmt.Errorf("formato desconocido %q (use zip, tar.gz o tar.zst)", s)
+}
+
+// archiveFormat deduce el formato por la extensión del archivo (sin .enc)
+func archiveFormat(path string) (string, bool) {
+	path = strings.TrimSuffix(path, EncryptedExt)
+	for _, f := range Formats {
+		if strings.HasSuffix(path, "."+f) {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// TrimArchiveExt quita la extensión de formato ("x.tar.zst.enc" -> "x")
+func TrimArchiveExt(name string) string {
+	if f, ok := archiveFormat(name); ok {
+		return strings.TrimSuffix(strings.TrimSuffix(name, EncryptedExt), "."+f)
+	}
+	return name
+}
+
+// IsEncrypted indica si un archivo de backup está cifrado
+func IsEncrypted(path string) bool {
+	return strings.HasSuffix(path, EncryptedExt)
+}
+
+// CreateArchive comprime sourceDir en archivePath con el formato indicado
+func CreateArchive(ctx context.Context, format, sourceDir, archivePath string) error {
+	switch format {
+	case FormatZip:
+		return ZipDirectory(ctx, sourceDir, archivePath)
+	case FormatTarGz, FormatTarZst:
+		return TarDirectory(ctx, format, sourceDir, archivePath)
+	}
+	return fmt.Errorf("formato desconocido %q", format)
+}
+
+// TarDirectory comprime un directorio a tar.gz o tar.zst
+func TarDirectory(ctx context.Context, format, sourceDir, archivePath string) (err error) {
+	file, err := os.Create(archivePath)
+	if err != nil {
+		return fmt.Errorf("error creando archivo %s: %v", format, err)
+	}
+	defer func() {
+		file.Close()
+		if err != nil {
+			os.Remove(archivePath)
+		}
+	}()
+
+	var compressor io.WriteCloser
+	if format == FormatTarZst {
+		compressor, err = zstd.NewWriter(file)
+		if err != nil {
+			return err
+		}
+	} else {
+		compressor = gzip.NewWriter(file)
+	}
+	tw := tar.NewWriter(compressor)
+
+	err = filepath.Walk(sourceDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(sourceDir, filePath)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relPath)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		in, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		if _, err := io.Copy(tw, in); err != nil {
+			return err
+		}
+
+		logger.FromContext(ctx).Debug("Comprimido", "file", relPath, "bytes", info.Size())
+		return nil
+	})
+	if err != nil {
+		tw.Close()
+		compressor.Close()
+		return fmt.Errorf("error recorriendo directorio: %w", err)
+	}
+
+	// Cerrar en orden para detectar errores al escribir el final del archivo
+	if err = tw.Close(); err != nil {
+		return fmt.Errorf("error cerrando tar: %w", err)
+	}
+	if err = compressor.Close(); err != nil {
+		return fmt.Errorf("error cerrando compresor: %w", err)
+	}
+	return file.Close()
+}
+
+// readArchive devuelve la lista de archivos de un backup en cualquier formato soportado
+func readArchive(path string) ([]ArchiveEntry, error) {
+	format, ok := archiveFormat(path)
+	if !ok {
+		return nil, fmt.Errorf("formato de archivo no reconocido: %s", filepath.Base(path))
+	}
+	if IsEncrypted(path) {
+		return nil, ErrEncrypted
+	}
+	if format == FormatZip {
+		return readZip(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader
+	if format == FormatTarZst {
+		dec, err := zstd.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		r = dec
+	} else {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var entries []ArchiveEntry
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name, err := safepath.Rel(h.Name)
+		if err != nil {
+			continue // gobackup nunca crea estos nombres: el archivo fue manipulado
+		}
+		entries = append(entries, ArchiveEntry{
+			Path:     name,
+			IsDir:    h.Typeflag == tar.TypeDir,
+			Size:     h.Size,
+			Modified: h.ModTime,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,179 @@
+package backup
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"gobackup/internal/history"
+	"gobackup/internal/safepath"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoArchive indica que el backup no tiene un archivo comprimido (por ejemplo, modo legacy)
+var ErrNoArchive = errors.New("el backup no tiene archivo comprimido")
+
+// ArchiveEntry es un archivo o directorio dentro de un backup comprimido
+type ArchiveEntry struct {
+	Name           string    `json:"name"`
+	Path           string    `json:"path"`
+	IsDir          bool      `json:"is_dir"`
+	Size           int64     `json:"size"`
+	CompressedSize int64     `json:"compressed_size"`
+	Modified       time.Time `json:"modified"`
+}
+
+// ResolveArchive devuelve la ruta del archivo de un backup a partir del ID del
+// snapshot en el historial o del ID de la sesión
+func ResolveArchive(id string) (string, error) {
+	if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+		snap, err := history.GetSnapshot(n)
+		if err != nil {
+			return "", err
+		}
+		return SnapshotArchive(snap)
+	}
+
+	id = TrimArchiveExt(id)
+	if err := safepath.ID(id); err != nil {
+		return "", err
+	}
+	if path, ok := findArchive(BackupsDir, id); ok {
+		return path, nil
+	}
+	return "", history.ErrNotFound
+}
+
+// SnapshotArchive devuelve el archivo generado por una ejecución, si todavía existe
+func SnapshotArchive(snap history.Snapshot) (string, error) {
+	if snap.Archive != "" {
+		if _, err := os.Stat(snap.Archive); err != nil {
+			return "", ErrNoArchive
+		}
+		return snap.Archive, nil
+	}
+	// Registros anteriores a JobOptions: ZIP de la sesión en BackupsDir
+	if snap.SessionID == "" || !BackupExists(snap.SessionID) {
+		return "", ErrNoArchive
+	}
+	return GetBackupPath(snap.SessionID), nil
+}
+
+// findArchive busca dir/name.<formato> en cualquiera de los formatos soportados
+func findArchive(dir, name string) (string, bool) {
+	if safepath.ID(name) != nil {
+		return "", false
+	}
+	for _, f := range Formats {
+		for _, ext := range []string{"." + f, "." + f + EncryptedExt} {
+			path := filepath.Join(dir, name+ext)
+			if _, err := os.Stat(path); err == nil {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// readZip devuelve la lista de archivos de un ZIP
+func readZip(zipPath string) ([]ArchiveEntry, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	entries := make([]ArchiveEntry, 0, len(r.File))
+	for _, f := range r.File {
+		name, err := safepath.Rel(f.Name)
+		if err != nil {
+			continue // gobackup nunca crea estos nombres: el archivo fue manipulado
+		}
+		entries = append(entries, ArchiveEntry{
+			Path:           name,
+			IsDir:          f.FileInfo().IsDir(),
+			Size:           int64(f.UncompressedSize64),
+			CompressedSize: int64(f.CompressedSize64),
+			Modified:       f.Modified,
+		})
+	}
+	return entries, nil
+}
+
+// ListArchive lista el contenido de un backup (zip, tar.gz o tar.zst). Con recursive
+// lista todos los archivos; si no, solo las entradas directas de dir (los
+// subdirectorios se agregan como entradas).
+func ListArchive(archivePath, dir string, recursive bool) ([]ArchiveEntry, error) {
+	all, err := readArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := ""
+	if dir != "" {
+		if dir, err = safepath.Rel(dir); err != nil {
+			return nil, err
+		}
+		prefix = dir + "/"
+	}
+
+	var entries []ArchiveEntry
+	dirs := make(map[string]*ArchiveEntry)
+	found := dir == ""
+
+	for _, f := range all {
+		name := f.Path
+		if name == dir {
+			found = true
+			continue
+		}
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		found = true
+		rest := strings.TrimPrefix(name, prefix)
+
+		if recursive || !strings.Contains(rest, "/") {
+			if f.IsDir {
+				continue
+			}
+			f.Name = path.Base(name)
+			entries = append(entries, f)
+			continue
+		}
+
+		// Agrupar el contenido de los subdirectorios en una sola entrada
+		sub := rest[:strings.Index(rest, "/")]
+		d, ok := dirs[sub]
+		if !ok {
+			d = &ArchiveEntry{Name: sub, Path: prefix + sub, IsDir: true}
+			dirs[sub] = d
+		}
+		d.Size += f.Size
+		d.CompressedSize += f.CompressedSize
+		if f.Modified.After(d.Modified) {
+			d.Modified = f.Modified
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("el directorio %q no existe en el backup", dir)
+	}
+
+	for _, d := range dirs {
+		entries = append(entries, *d)
+	}
+	// Directorios primero, después por nombre
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
@@ -0,0 +1,403 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gobackup/internal/disk"
+	"gobackup/internal/logger"
+	"gobackup/internal/safepath"
+	"gobackup/internal/utils"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Subidas por partes: el cliente declara el archivo (ruta, tamaño y sha256),
+// manda fragmentos en orden indicando desde qué byte van y al final pide que se
+// verifique y se mueva a la sesión. Lo recibido queda en TempDir/chunked, así
+// que si se corta la conexión se consulta el offset y se sigue desde ahí.
+
+var (
+	ErrUploadOffset   = errors.New("el fragmento no empieza donde termina lo recibido")
+	ErrUploadTooLarge = errors.New("el fragmento pasa del tamaño declarado")
+	ErrUploadChunkSum = errors.New("el checksum del fragmento no coincide")
+	ErrUploadChecksum = errors.New("el sha256 del archivo no coincide con el declarado")
+	ErrUploadPending  = errors.New("todavía faltan bytes por recibir")
+)
+
+// ChunkedUpload es una subida por partes en curso
+type ChunkedUpload struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"session_id,omitempty"` // vacío = se crea una sesión al terminar
+	Path      string    `json:"path"`                 // ruta relativa dentro de la sesión
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256,omitempty"` // hex, se verifica al terminar si viene
+	Offset    int64     `json:"offset"`           // bytes recibidos (vale el tamaño del .part)
+	User      string    `json:"user,omitempty"`
+	Created   time.Time `json:"created"`
+	Updated   time.Time `json:"updated"`
+}
+
+// chunkLocks serializa las operaciones sobre cada subida (un fragmento a la vez)
+var chunkLocks sync.Map
+
+func lockChunked(id string) func() {
+	mu, _ := chunkLocks.LoadOrStore(id, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// finishing marca las subidas que FinishChunkedUpload está pasando a una sesión:
+// mientras tanto sus datos están en .moving. Un .moving sin marca es de un
+// proceso que murió a mitad y GetChunkedUpload lo devuelve a .part.
+var finishing sync.Map
+
+func chunkedDir() string {
+	return filepath.Join(TempDir, "chunked")
+}
+
+// chunkedPaths devuelve el estado y los datos de una subida, validando el ID
+func chunkedPaths(id string) (state, data string, err error) {
+	if err := safepath.ID(id); err != nil || !strings.HasPrefix(id, "upload_") {
+		return "", "", fmt.Errorf("ID de subida inválido %q: %w", id, safepath.ErrUnsafe)
+	}
+	dir := chunkedDir()
+	return filepath.Join(dir, id+".json"), filepath.Join(dir, id+".part"), nil
+}
+
+func movingPath(data string) string {
+	return strings.TrimSuffix(data, ".part") + ".moving"
+}
+
+// NewChunkedUpload registra una subida por partes. Valida la ruta y el sha256 y
+// rechaza de entrada lo que ya se sabe que no entra en los límites de subida.
+func NewChunkedUpload(sessionID, rel string, size int64, sum, user string) (ChunkedUpload, error) {
+	rel, err := safepath.Rel(rel)
+	if err != nil {
+		return ChunkedUpload{}, err
+	}
+	if sessionID != "" {
+		if _, err := SessionDir(sessionID); err != nil {
+			return ChunkedUpload{}, err
+		}
+	}
+	if size < 0 {
+		return ChunkedUpload{}, fmt.Errorf("tamaño inválido: %d", size)
+	}
+	sum = strings.ToLower(sum)
+	if b, err := hex.DecodeString(sum); sum != "" && (err != nil || len(b) != sha256.Size) {
+		return ChunkedUpload{}, fmt.Errorf("sha256 inválido: %q", sum)
+	}
+
+	// Con el lock del usuario para que varias a la vez no pasen juntas el tope
+	defer lockUser(user)()
+	if max := UploadLimits().MaxPending; max > 0 && len(pendingUploads(user)) >= max {
+		return ChunkedUpload{}, quotaError("quotas.max_pending_uploads", int64(max),
+			"ya hay %d subidas por partes sin terminar (termine o cancele alguna)", max)
+	}
+	if err := CheckUploadSize(user, size); err != nil {
+		return ChunkedUpload{}, err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ChunkedUpload{}, err
+	}
+	now := time.Now()
+	u := ChunkedUpload{
+		ID:        "upload_" + hex.EncodeToString(b),
+		SessionID: sessionID,
+		Path:      rel,
+		Size:      size,
+		SHA256:    sum,
+		User:      user,
+		Created:   now,
+		Updated:   now,
+	}
+	state, data, _ := chunkedPaths(u.ID)
+	if err := os.MkdirAll(chunkedDir(), 0755); err != nil {
+		return ChunkedUpload{}, err
+	}
+	if err := os.WriteFile(data, nil, 0644); err != nil {
+		return ChunkedUpload{}, err
+	}
+	if err := saveChunked(state, u); err != nil {
+		os.Remove(data)
+		return ChunkedUpload{}, err
+	}
+	logger.Info("Subida por partes iniciada", "upload_id", u.ID, "session_id", sessionID, "file", rel, "bytes", size, "user", user)
+	return u, nil
+}
+
+func saveChunked(state string, u ChunkedUpload) error {
+	data, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(state, data, 0644)
+}
+
+// GetChunkedUpload devuelve una subida con los bytes recibidos hasta ahora
+func GetChunkedUpload(id string) (ChunkedUpload, error) {
+	state, data, err := chunkedPaths(id)
+	if err != nil {
+		return ChunkedUpload{}, err
+	}
+	raw, err := os.ReadFile(state)
+	if err != nil {
+		return ChunkedUpload{}, err
+	}
+	var u ChunkedUpload
+	if err := json.Unmarshal(raw, &u); err != nil {
+		return ChunkedUpload{}, err
+	}
+	info, err := os.Stat(data)
+	if os.IsNotExist(err) {
+		if _, busy := finishing.Load(id); !busy && os.Rename(movingPath(data), data) == nil {
+			logger.Warn("Subida por partes recuperada de un cierre a mitad", "upload_id", id)
+			info, err = os.Stat(data)
+		}
+	}
+	if err != nil {
+		return ChunkedUpload{}, err
+	}
+	u.Offset = info.Size()
+	return u, nil
+}
+
+// ParseChunkChecksum interpreta el checksum de un fragmento con el formato de tus
+// ("sha256 <base64>"); vacío = sin verificar
+func ParseChunkChecksum(header string) ([]byte, error) {
+	if header == "" {
+		return nil, nil
+	}
+	algo, value, _ := strings.Cut(header, " ")
+	if algo != "sha256" {
+		return nil, fmt.Errorf("algoritmo de checksum no soportado: %q", algo)
+	}
+	sum, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("checksum inválido: %q", value)
+	}
+	return sum, nil
+}
+
+// AppendChunk agrega a la subida id lo que llega por r, que tiene que empezar en
+// offset. Si se corta a mitad se conserva lo recibido (el cliente sigue desde el
+// nuevo offset), salvo que venga chunkSum: entonces el fragmento entra entero
+// y verificado o no entra. Devuelve los bytes recibidos en total.
+func AppendChunk(id string, offset int64, r io.Reader, chunkSum []byte) (int64, error) {
+	defer lockChunked(id)()
+	u, err := GetChunkedUpload(id)
+	if err != nil {
+		return 0, err
+	}
+	if offset != u.Offset {
+		return u.Offset, ErrUploadOffset
+	}
+	if err := checkRemaining(u); err != nil {
+		return u.Offset, err
+	}
+	state, data, _ := chunkedPaths(id)
+	f, err := os.OpenFile(data, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return u.Offset, err
+	}
+
+	var h hash.Hash
+	src := io.LimitReader(r, u.Size-u.Offset+1)
+	if chunkSum != nil {
+		h = sha256.New()
+		src = io.TeeReader(src, h)
+	}
+	n, err := io.Copy(f, src)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	switch {
+	case u.Offset+n > u.Size:
+		err = ErrUploadTooLarge
+	case err == nil && h != nil && !bytes.Equal(h.Sum(nil), chunkSum):
+		err = ErrUploadChunkSum
+	}
+	if err != nil && (h != nil || errors.Is(err, ErrUploadTooLarge)) {
+		// Volver a donde estaba para que el fragmento se pueda reenviar
+		if terr := os.Truncate(data, u.Offset); terr != nil {
+			return u.Offset, terr
+		}
+		n = 0
+	}
+
+	u.Updated = time.Now()
+	if serr := saveChunked(state, u); err == nil {
+		err = serr
+	}
+	return offset + n, err
+}
+
+// checkRemaining revisa antes de cada fragmento que lo que falta recibir siga
+// entrando: en la cuota (que ya cuenta lo recibido de todas las subidas por
+// partes del usuario) y en el espacio libre de temp_dir, donde se va guardando
+func checkRemaining(u ChunkedUpload) error {
+	remaining := u.Size - u.Offset
+	allowed, limitErr, err := uploadAllowance(u.User)
+	if err != nil {
+		return err
+	}
+	if allowed >= 0 && remaining > allowed {
+		return limitErr
+	}
+	if min := UploadLimits().MinFreeSpace; min > 0 {
+		if err := disk.CheckFree(chunkedDir(), uint64(min+remaining)); err != nil {
+			return quotaError("quotas.min_free_mb", min, "no queda espacio en el servidor para lo que falta (se reservan %s libres)",
+				utils.FormatFileSize(min))
+		}
+	}
+	return nil
+}
+
+// FinishChunkedUpload verifica que la subida esté completa y su sha256 y la mueve
+// a la sesión sessionID (con los límites de AddToSession; owner es el dueño si la
+// sesión es nueva). Si el sha256 no coincide la subida se descarta, porque no hay
+// forma de saber qué parte está mal.
+func FinishChunkedUpload(id, sessionID string, owner Owner) (ChunkedUpload, error) {
+	defer lockChunked(id)()
+	u, err := GetChunkedUpload(id)
+	if err != nil {
+		return u, err
+	}
+	if u.Offset < u.Size {
+		return u, ErrUploadPending
+	}
+	_, data, _ := chunkedPaths(id)
+	if u.SHA256 != "" {
+		_, sum, err := hashFile(data)
+		if err != nil {
+			return u, err
+		}
+		if sum != u.SHA256 {
+			removeChunked(id)
+			logger.Warn("Subida por partes descartada: sha256 distinto", "upload_id", id, "file", u.Path, "user", u.User)
+			return u, ErrUploadChecksum
+		}
+	}
+	// Mientras se pasa a la sesión deja de contar como pendiente, si no la cuota
+	// lo contaría dos veces. La cuota se vuelve a mirar: pudo llenarse mientras
+	// llegaban las partes.
+	moving := movingPath(data)
+	finishing.Store(id, true)
+	defer finishing.Delete(id)
+	if err := os.Rename(data, moving); err != nil {
+		return u, err
+	}
+	err = CheckUploadSize(u.User, u.Size)
+	if err == nil {
+		err = AddToSession(sessionID, u.Path, moving, u.Size, owner)
+	}
+	if err != nil {
+		if rerr := os.Rename(moving, data); rerr != nil {
+			logger.Error("No se pudo devolver la subida por partes a pendiente", "upload_id", id, "error", rerr)
+			return u, fmt.Errorf("%w (y no se pudo restaurar lo recibido: %v)", err, rerr)
+		}
+		return u, err
+	}
+	u.SessionID = sessionID
+	removeChunked(id)
+	logger.Info("Subida por partes completada", "upload_id", id, "session_id", sessionID, "file", u.Path, "bytes", u.Size, "user", u.User)
+	return u, nil
+}
+
+func hashFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	return n, hex.EncodeToString(h.Sum(nil)), err
+}
+
+// RemoveChunkedUpload cancela una subida por partes y borra lo recibido
+func RemoveChunkedUpload(id string) error {
+	defer lockChunked(id)()
+	if _, err := GetChunkedUpload(id); err != nil {
+		return err
+	}
+	removeChunked(id)
+	return nil
+}
+
+func removeChunked(id string) {
+	state, data, err := chunkedPaths(id)
+	if err != nil {
+		return
+	}
+	os.Remove(data)
+	os.Remove(movingPath(data))
+	os.Remove(state)
+	chunkLocks.Delete(id)
+}
+
+// pendingUploads lista las subidas por partes sin terminar de user
+func pendingUploads(user string) []ChunkedUpload {
+	entries, err := os.ReadDir(chunkedDir())
+	if err != nil {
+		return nil
+	}
+	var list []ChunkedUpload
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		if u, err := GetChunkedUpload(id); err == nil && u.User == user {
+			list = append(list, u)
+		}
+	}
+	return list
+}
+
+// GCChunkedUploads borra las subidas por partes sin fragmentos nuevos durante más
+// de ttl, y los estados que se quedaron sin datos
+func GCChunkedUploads(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(chunkedDir())
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		u, err := GetChunkedUpload(id)
+		if os.IsNotExist(err) {
+			if _, busy := finishing.Load(id); busy {
+				continue
+			}
+			if info, ierr := entry.Info(); ierr == nil && time.Since(info.ModTime()) > ttl {
+				removeChunked(id)
+				logger.Info("GC: estado de subida por partes sin datos borrado", "upload_id", id)
+			}
+			continue
+		}
+		if err != nil || time.Since(u.Updated) <= ttl {
+			continue
+		}
+		removeChunked(id)
+		logger.Info("GC: subida por partes abandonada borrada", "upload_id", id, "file", u.Path, "bytes", u.Offset)
+	}
+}
@@ -1,6 +1,7 @@
 package backup
 
 import (
+	"context"
 	"fmt"
 	"gobackup/internal/logger"
 	"io"
@@ -9,16 +10,50 @@ import (
 	"sync"
 )
 
+// FileError describe un archivo que no pudo copiarse
+type FileError struct {
+	Path string
+	Err  error
+}
+
+// CopiedFile describe un archivo copiado y verificado
+type CopiedFile struct {
+	Path     string
+	Size     int64
+	Checksum string // SHA256 verificado en origen y destino
+}
+
+// CopyResult resume el resultado de una copia concurrente
+type CopyResult struct {
+	Copied    int
+	BytesRead int64
+	Files     []CopiedFile
+	Failed    []FileError
+}
+
 // CopyFilesConcurrent copia archivos usando concurrencia y verifica el checksum.
-func CopyFilesConcurrent(files []string, sourceBaseDir, destBaseDir string, concurrency int) error {
+// Un archivo que falla no detiene la copia del resto: se anota en Failed y
+// se devuelve el primer error. Si ctx se cancela, los archivos pendientes no se copian.
+func CopyFilesConcurrent(ctx context.Context, files []string, sourceBaseDir, destBaseDir string, concurrency int) (CopyResult, error) {
 	sourceBaseDir = filepath.Clean(sourceBaseDir)
 	destBaseDir = filepath.Clean(destBaseDir)
 
 	var wg sync.WaitGroup
 	limiter := NewLimiter(concurrency)
+	var result CopyResult
 	var firstErr error
-	var errMu sync.Mutex
+	var mu sync.Mutex
+
+	fail := func(file string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Failed = append(result.Failed, FileError{Path: file, Err: err})
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
 
+	lg := logger.FromContext(ctx)
 	for _, file := range files {
 		wg.Add(1)
 		go func(file string) {
@@ -26,75 +61,86 @@ func CopyFilesConcurrent(files []string, sourceBaseDir, destBaseDir string, conc
 			limiter.Acquire()
 			defer limiter.Release()
 
+			if ctx.Err() != nil {
+				return
+			}
+
 			relPath, err := filepath.Rel(sourceBaseDir, file)
 			if err != nil {
-				logger.Errorf("Error obteniendo ruta relativa: %v", err)
-				setFirstError(err, &firstErr, &errMu)
+				lg.Error("Error obteniendo ruta relativa", "file", file, "error", err)
+				fail(file, err)
 				return
 			}
 
 			destPath := filepath.Join(destBaseDir, relPath)
 			if err := os.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
-				logger.Errorf("Error creando directorio destino: %v", err)
-				setFirstError(err, &firstErr, &errMu)
+				lg.Error("Error creando directorio destino", "file", destPath, "error", err)
+				fail(file, err)
 				return
 			}
 
-			if err := copyFileAndVerify(file, destPath); err != nil {
-				logger.Errorf("Error copiando %s: %v", file, err)
-				setFirstError(err, &firstErr, &errMu)
+			n, sum, err := copyFileAndVerify(file, destPath)
+			if err != nil {
+				lg.Error("Error copiando archivo", "file", relPath, "error", err)
+				Status.AddError(fmt.Sprintf("%s: %v", relPath, err))
+				fail(file, err)
 				return
 			}
 
-			logger.Infof("Archivo copiado y verificado: %s", relPath)
+			mu.Lock()
+			result.Copied++
+			result.BytesRead += n
+			result.Files = append(result.Files, CopiedFile{Path: file, Size: n, Checksum: sum})
+			mu.Unlock()
+
+			lg.Info("Archivo copiado y verificado", "file", relPath, "bytes", n, "sha256", sum)
 			Status.IncrementFilesCopied()
 		}(file)
 	}
 
 	wg.Wait()
-	return firstErr
+	return result, firstErr
 }
 
-// copyFileAndVerify copia el archivo y verifica el checksum.
-func copyFileAndVerify(src, dst string) error {
+// copyFileAndVerify copia el archivo, verifica el checksum y devuelve los bytes copiados
+// junto con el SHA256 verificado.
+func copyFileAndVerify(src, dst string) (int64, string, error) {
 	in, err := os.Open(src)
 	if err != nil {
-		return err
+		return 0, "", err
 	}
 	defer in.Close()
 
 	info, err := in.Stat()
 	if err != nil {
-		return err
+		return 0, "", err
 	}
 
 	// Crea el archivo de destino con los permisos de origen
 	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
 	if err != nil {
-		return err
+		return 0, "", err
 	}
 	defer out.Close()
 
 	// Copia el contenido del archivo
-	if _, err := io.Copy(out, in); err != nil {
-		return err
+	n, err := io.Copy(out, in)
+	if err != nil {
+		return n, "", err
 	}
 
 	// Verifica el checksum del archivo de origen y destino
-	if equal, err := VerifyChecksum(src, dst); err != nil {
-		return err
-	} else if !equal {
-		return fmt.Errorf("checksum no coincide para %s", src)
+	srcSum, err := FileChecksum(src)
+	if err != nil {
+		return n, "", err
 	}
-
-	return nil
-}
-
-// setFirstError asegura que solo se guarde el primer error
-func setFirstError(err error, firstErr *error, mu *sync.Mutex) {
-	mu.Lock()
-	defer mu.Unlock()
-	if *firstErr == nil {
-		*firstErr = err
+	dstSum, err := FileChecksum(dst)
+	if err != nil {
+		return n, "", err
 	}
+	if srcSum != dstSum {
+		return n, "", fmt.Errorf("checksum no coincide para %s", src)
+	}
+
+	return n, srcSum, nil
 }
@@ -0,0 +1,193 @@
+package backup
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// EncryptedExt es la extensión que se agrega a los archivos cifrados (x.tar.zst.enc)
+const EncryptedExt = ".enc"
+
+// ErrEncrypted indica que el archivo está cifrado y no se puede leer sin la frase
+var ErrEncrypted = errors.New("el backup está cifrado")
+
+// Formato: magic | salt | prefijo de nonce | bloques [largo uint32 | AES-256-GCM].
+// Cada bloque usa el nonce prefijo+contador+marca de último bloque, así no se
+// pueden reordenar ni truncar bloques sin que falle el descifrado.
+const (
+	encMagic      = "GBENC1"
+	encSaltSize   = 16
+	encPrefixSize = 7
+	encChunkSize  = 64 * 1024
+	encIterations = 600000
+)
+
+func encryptionKey(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, encIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encPrefixSize:], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// Encrypt cifra r en w con una clave derivada de la frase
+func Encrypt(w io.Writer, r io.Reader, passphrase string) error {
+	header := make([]byte, len(encMagic)+encSaltSize+encPrefixSize)
+	copy(header, encMagic)
+	if _, err := rand.Read(header[len(encMagic):]); err != nil {
+		return err
+	}
+	salt := header[len(encMagic) : len(encMagic)+encSaltSize]
+	prefix := header[len(encMagic)+encSaltSize:]
+
+	aead, err := encryptionKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(r, encChunkSize)
+	buf := make([]byte, encChunkSize)
+	var size [4]byte
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(br, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		// Mirar si queda algo para saber si este es el último bloque
+		_, peekErr := br.Peek(1)
+		last := peekErr == io.EOF
+		if peekErr != nil && !last {
+			return peekErr
+		}
+
+		sealed := aead.Seal(nil, chunkNonce(prefix, counter, last), buf[:n], nil)
+		binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+		if _, err := w.Write(size[:]); err != nil {
+			return err
+		}
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// Decrypt descifra en w lo que generó Encrypt
+func Decrypt(w io.Writer, r io.Reader, passphrase string) error {
+	header := make([]byte, len(encMagic)+encSaltSize+encPrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(encMagic)]) != encMagic {
+		return fmt.Errorf("no es un archivo cifrado por gobackup")
+	}
+	salt := header[len(encMagic) : len(encMagic)+encSaltSize]
+	prefix := header[len(encMagic)+encSaltSize:]
+
+	aead, err := encryptionKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(r)
+	buf := make([]byte, encChunkSize+aead.Overhead())
+	var size [4]byte
+	for counter := uint32(0); ; counter++ {
+		if _, err := io.ReadFull(br, size[:]); err != nil {
+			return fmt.Errorf("archivo cifrado truncado")
+		}
+		n := binary.BigEndian.Uint32(size[:])
+		if int(n) > len(buf) {
+			return fmt.Errorf("bloque cifrado inválido")
+		}
+		if _, err := io.ReadFull(br, buf[:n]); err != nil {
+			return fmt.Errorf("archivo cifrado truncado")
+		}
+
+		_, peekErr := br.Peek(1)
+		last := peekErr == io.EOF
+		plain, err := aead.Open(buf[:0], chunkNonce(prefix, counter, last), buf[:n], nil)
+		if err != nil {
+			return fmt.Errorf("frase incorrecta o archivo dañado")
+		}
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// EncryptFile cifra path en path.enc y borra el original
+func EncryptFile(path, passphrase string) (string, error) {
+	encPath := path + EncryptedExt
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	out, err := os.Create(encPath)
+	if err != nil {
+		return "", err
+	}
+	err = Encrypt(out, in, passphrase)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(encPath)
+		return "", err
+	}
+	in.Close()
+	return encPath, os.Remove(path)
+}
+
+// DecryptFile descifra src en dst
+func DecryptFile(src, dst, passphrase string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(dst)
+		}
+	}()
+	return Decrypt(out, in, passphrase)
+}
@@ -0,0 +1,93 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown se devuelve al intentar lanzar un backup mientras se apaga el servidor
+var ErrShuttingDown = errors.New("el servidor se está apagando, no se aceptan backups nuevos")
+
+// jobCancelGrace es lo que se espera a que un backup cancelado limpie sus
+// archivos a medio escribir y registre su estado
+const jobCancelGrace = 10 * time.Second
+
+// Backups lanzados en segundo plano (web), para poder apagar sin cortarlos
+var jobs = struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	running  int
+	draining bool
+}{}
+
+// jobsCtx es el contexto de los backups en segundo plano; DrainJobs lo cancela
+var jobsCtx, cancelJobs = context.WithCancel(context.Background())
+
+// TrackJob registra un backup en segundo plano. Devuelve el contexto con el que
+// debe correr (se cancela si el apagado no puede esperarlo) y done, que hay que
+// llamar al terminar. Falla con ErrShuttingDown si ya se está apagando.
+func TrackJob() (context.Context, func(), error) {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	if jobs.draining {
+		return nil, nil, ErrShuttingDown
+	}
+	jobs.running++
+	jobs.wg.Add(1)
+
+	var once sync.Once
+	done := func() {
+		once.Do(func() {
+			jobs.mu.Lock()
+			jobs.running--
+			jobs.mu.Unlock()
+			jobs.wg.Done()
+		})
+	}
+	return jobsCtx, done, nil
+}
+
+// Draining indica si se dejaron de aceptar backups por el apagado
+func Draining() bool {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	return jobs.draining
+}
+
+// RunningJobs devuelve cuántos backups en segundo plano siguen corriendo
+func RunningJobs() int {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	return jobs.running
+}
+
+// DrainJobs deja de aceptar backups y espera a que terminen los que están en
+// curso hasta que venza ctx. Los que sigan se cancelan (borran lo que estaban
+// escribiendo y quedan registrados como cancelados). Devuelve cuántos se cortaron.
+func DrainJobs(ctx context.Context) int {
+	jobs.mu.Lock()
+	jobs.draining = true
+	jobs.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		jobs.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return 0
+	case <-ctx.Done():
+	}
+
+	interrupted := RunningJobs()
+	cancelJobs()
+	select {
+	case <-finished:
+	case <-time.After(jobCancelGrace):
+	}
+	return interrupted
+}
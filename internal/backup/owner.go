@@ -0,0 +1,81 @@
+package backup
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Owner es el dueño de una sesión de subida y de su backup. Se guarda en
+// BackupsDir/<id>.owner.json, junto al log y el informe, para que siga valiendo
+// cuando el GC borra la subida.
+type Owner struct {
+	User    string    `json:"user"`
+	Token   string    `json:"token,omitempty"` // ID del token de API si se creó con uno
+	Created time.Time `json:"created"`
+}
+
+// ownerPath devuelve el archivo del dueño; id tiene que estar validado
+func ownerPath(id string) string {
+	return filepath.Join(BackupsDir, id+".owner.json")
+}
+
+// SetOwner registra el dueño de una sesión
+func SetOwner(id string, o Owner) error {
+	if _, err := SessionDir(id); err != nil {
+		return err
+	}
+	if o.Created.IsZero() {
+		o.Created = time.Now()
+	}
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(BackupsDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(ownerPath(id), data, 0644)
+}
+
+// GetOwner devuelve el dueño de una sesión. Las sesiones creadas desde la terminal
+// o antes de que existieran los dueños no tienen.
+func GetOwner(id string) (Owner, bool) {
+	if _, err := SessionDir(id); err != nil {
+		return Owner{}, false
+	}
+	data, err := os.ReadFile(ownerPath(id))
+	if err != nil {
+		return Owner{}, false
+	}
+	var o Owner
+	if json.Unmarshal(data, &o) != nil || o.User == "" {
+		return Owner{}, false
+	}
+	return o, true
+}
+
+// ArchiveSessionID devuelve la sesión a la que pertenece un archivo de backup, si
+// es uno de BackupsDir (los de trabajos con otro destino no son de ninguna sesión)
+func ArchiveSessionID(archivePath string) (string, bool) {
+	dir, err := filepath.Abs(filepath.Dir(archivePath))
+	if err != nil {
+		return "", false
+	}
+	backups, err := filepath.Abs(BackupsDir)
+	if err != nil || dir != backups {
+		return "", false
+	}
+	return TrimArchiveExt(filepath.Base(archivePath)), true
+}
+
+// removeOwnerIfOrphan olvida el dueño de una sesión que ya no tiene subida ni backup
+func removeOwnerIfOrphan(id string) {
+	if BackupExists(id) {
+		return
+	}
+	if _, err := os.Stat(filepath.Join(UploadsDir, id)); os.IsNotExist(err) {
+		os.Remove(ownerPath(id))
+	}
+}
@@ -0,0 +1,220 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gobackup/internal/config"
+	"gobackup/internal/logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Profile es lo que el motor necesita para ejecutar un backup: orígenes,
+// destino, filtros, compresión, cifrado y retención
+type Profile struct {
+	Name        string        `json:"name"`
+	Sources     []string      `json:"sources"`
+	Dest        string        `json:"dest"`
+	Include     []string      `json:"include,omitempty"`
+	Exclude     []string      `json:"exclude,omitempty"`
+	Since       time.Duration `json:"since"` // solo archivos modificados en este período (0 = todos)
+	Concurrency int           `json:"concurrency"`
+	Format      string        `json:"format"`
+
+	Encryption config.EncryptionConfig `json:"encryption"`
+	Retention  config.RetentionConfig  `json:"retention"`
+}
+
+// MarshalJSON muestra Since como texto ("30m0s") en lugar de nanosegundos
+func (p Profile) MarshalJSON() ([]byte, error) {
+	type plain Profile
+	return json.Marshal(struct {
+		plain
+		Since string `json:"since"`
+	}{plain(p), p.Since.String()})
+}
+
+// Encrypted indica si el perfil cifra los archivos que genera
+func (p Profile) Encrypted() bool {
+	return p.Encryption.Enabled
+}
+
+// passphrase lee la frase de cifrado recién al usarla, para no guardarla en memoria
+func (p Profile) passphrase() (string, error) {
+	enc := p.Encryption
+	if enc.KeyFile != "" {
+		data, err := os.ReadFile(enc.KeyFile)
+		if err != nil {
+			return "", fmt.Errorf("leyendo key_file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	if s := os.Getenv(enc.PassphraseEnv); s != "" {
+		return s, nil
+	}
+	return "", fmt.Errorf("la variable %s no está definida", enc.PassphraseEnv)
+}
+
+// DecryptArchive descifra un archivo generado por este perfil
+func (p Profile) DecryptArchive(src, dst string) error {
+	if !p.Encrypted() {
+		return fmt.Errorf("el perfil %s no tiene cifrado configurado", p.Name)
+	}
+	passphrase, err := p.passphrase()
+	if err != nil {
+		return err
+	}
+	return DecryptFile(src, dst, passphrase)
+}
+
+// ProfileFromConfig arma el perfil del motor a partir de la configuración
+func ProfileFromConfig(cfg *config.Config, name string) (Profile, error) {
+	if name == "" {
+		name = config.DefaultProfile
+	}
+	cp, err := cfg.Profile(name)
+	if err != nil {
+		return Profile{}, err
+	}
+	format, err := ParseFormat(cp.Format)
+	if err != nil {
+		return Profile{}, err
+	}
+	return Profile{
+		Name:        name,
+		Sources:     cp.Sources,
+		Dest:        cp.Dest,
+		Include:     cp.Include,
+		Exclude:     cp.Exclude,
+		Since:       time.Duration(cp.ModifiedMinutes) * time.Minute,
+		Concurrency: cp.MaxConcurrency,
+		Format:      format,
+		Encryption:  cp.Encryption,
+		Retention:   cp.Retention,
+	}, nil
+}
+
+// Perfiles configurados y el elegido con --profile (los carga cmd/root.go)
+var (
+	profilesMu    sync.RWMutex
+	profiles      = map[string]Profile{}
+	activeProfile = config.DefaultProfile
+)
+
+// SetProfiles reemplaza los perfiles disponibles y el perfil activo
+func SetProfiles(list map[string]Profile, active string) {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profiles = list
+	activeProfile = active
+}
+
+// LoadProfiles arma todos los perfiles de la configuración
+func LoadProfiles(cfg *config.Config) (map[string]Profile, error) {
+	list := make(map[string]Profile)
+	for _, name := range cfg.ProfileNames() {
+		p, err := ProfileFromConfig(cfg, name)
+		if err != nil {
+			return nil, fmt.Errorf("perfil %s: %w", name, err)
+		}
+		list[name] = p
+	}
+	return list, nil
+}
+
+// GetProfile devuelve un perfil por nombre (vacío = el activo)
+func GetProfile(name string) (Profile, bool) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	if name == "" {
+		name = activeProfile
+	}
+	p, ok := profiles[name]
+	return p, ok
+}
+
+// ActiveProfile devuelve el perfil elegido con --profile
+func ActiveProfile() Profile {
+	p, _ := GetProfile("")
+	return p
+}
+
+// ListProfiles devuelve los perfiles ordenados por nombre
+func ListProfiles() []Profile {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	list := make([]Profile, 0, len(profiles))
+	for _, p := range profiles {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// jobTimeFormat es la marca de tiempo en el nombre de los archivos de un perfil
+const jobTimeFormat = "20060102-150405"
+
+// JobName arma el nombre del archivo de una ejecución del perfil
+func (p Profile) JobName(t time.Time) string {
+	return p.Name + "_" + t.Format(jobTimeFormat)
+}
+
+// ApplyRetention borra los archivos del perfil (con su log e informe) que
+// exceden keep_last o max_age_days. Devuelve las rutas borradas.
+func ApplyRetention(ctx context.Context, p Profile) ([]string, error) {
+	keep, maxAge := p.Retention.KeepLast, time.Duration(p.Retention.MaxAgeDays)*24*time.Hour
+	if keep <= 0 && maxAge <= 0 {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(p.Dest)
+	if err != nil {
+		return nil, err
+	}
+	type archive struct {
+		path string
+		when time.Time
+	}
+	var archives []archive
+	for _, e := range entries {
+		name := TrimArchiveExt(e.Name())
+		if e.IsDir() || name == e.Name() {
+			continue
+		}
+		// Solo los archivos de este perfil: <perfil>_<fecha>
+		stamp, ok := strings.CutPrefix(name, p.Name+"_")
+		if !ok {
+			continue
+		}
+		when, err := time.ParseInLocation(jobTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		archives = append(archives, archive{filepath.Join(p.Dest, e.Name()), when})
+	}
+	sort.Slice(archives, func(i, j int) bool { return archives[i].when.After(archives[j].when) })
+
+	lg := logger.FromContext(ctx)
+	var removed []string
+	for i, a := range archives {
+		expired := maxAge > 0 && time.Since(a.when) > maxAge
+		if !(keep > 0 && i >= keep) && !expired {
+			continue
+		}
+		if err := os.Remove(a.path); err != nil {
+			lg.Warn("No se pudo borrar el backup por retención", "file", a.path, "error", err)
+			continue
+		}
+		base := filepath.Join(p.Dest, TrimArchiveExt(filepath.Base(a.path)))
+		os.Remove(base + ".log")
+		os.Remove(base + ".report.json")
+		lg.Info("Backup borrado por retención", "profile", p.Name, "file", a.path)
+		removed = append(removed, a.path)
+	}
+	return removed, nil
+}
@@ -0,0 +1,301 @@
+package backup
+
+import (
+	"fmt"
+	"gobackup/internal/disk"
+	"gobackup/internal/safepath"
+	"gobackup/internal/utils"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Limits son los límites de subida en bytes (0 = sin límite)
+type Limits struct {
+	MaxFileSize     int64 `json:"max_file_size"`
+	MaxSessionSize  int64 `json:"max_session_size"`
+	MaxSessionFiles int   `json:"max_session_files"`
+	UserQuota       int64 `json:"user_quota"`
+	MinFreeSpace    int64 `json:"min_free_space"`      // en el disco de UploadsDir
+	MaxPending      int   `json:"max_pending_uploads"` // subidas por partes sin terminar por usuario
+}
+
+// Límites activos; cmd/root.go los fija desde quotas.* al arrancar y al recargar
+var uploadLimits atomic.Pointer[Limits]
+
+// SetUploadLimits reemplaza los límites de subida
+func SetUploadLimits(l Limits) {
+	uploadLimits.Store(&l)
+}
+
+// UploadLimits devuelve los límites de subida activos
+func UploadLimits() Limits {
+	if l := uploadLimits.Load(); l != nil {
+		return *l
+	}
+	return Limits{}
+}
+
+// QuotaError indica qué límite impide guardar un archivo; la web responde 413
+type QuotaError struct {
+	Limit string `json:"limit"` // clave de configuración, p. ej. quotas.max_file_mb
+	Max   int64  `json:"max"`   // valor del límite (bytes o archivos)
+	msg   string
+}
+
+func (e *QuotaError) Error() string { return e.msg }
+
+func quotaError(limit string, max int64, format string, args ...interface{}) *QuotaError {
+	return &QuotaError{Limit: limit, Max: max, msg: fmt.Sprintf(format, args...)}
+}
+
+// Usage es lo que ocupa un usuario entre sesiones de subida y backups
+type Usage struct {
+	User     string `json:"user"`
+	Sessions int    `json:"sessions"`
+	Uploads  int64  `json:"uploads"` // bytes en UploadsDir
+	Backups  int64  `json:"backups"` // bytes de sus archivos en BackupsDir
+	Pending  int64  `json:"pending"` // bytes recibidos de sus subidas por partes sin terminar
+	Total    int64  `json:"total"`
+	Quota    int64  `json:"quota"` // 0 = sin límite
+}
+
+// UserUsage suma lo que ocupan las sesiones, backups y subidas por partes sin
+// terminar de un usuario (ver Owner)
+func UserUsage(user string) (Usage, error) {
+	u := Usage{User: user, Quota: UploadLimits().UserQuota}
+	for _, c := range pendingUploads(user) {
+		u.Pending += c.Offset
+	}
+	u.Total = u.Pending
+	entries, err := os.ReadDir(BackupsDir)
+	if os.IsNotExist(err) {
+		return u, nil
+	}
+	if err != nil {
+		return u, err
+	}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".owner.json")
+		if !ok {
+			continue
+		}
+		if o, ok := GetOwner(id); !ok || o.User != user {
+			continue
+		}
+		u.Sessions++
+		if path, ok := findArchive(BackupsDir, id); ok {
+			if info, err := os.Stat(path); err == nil {
+				u.Backups += info.Size()
+			}
+		}
+		if dir, err := SessionDir(id); err == nil {
+			u.Uploads += dirSize(dir)
+		}
+	}
+	u.Total = u.Uploads + u.Backups + u.Pending
+	return u, nil
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// uploadAllowance devuelve cuántos bytes puede tener el próximo archivo de user
+// y el error a devolver si se pasa: el menor entre el máximo por archivo, lo que
+// le queda de cuota y el espacio libre por encima de la reserva
+func uploadAllowance(user string) (int64, *QuotaError, error) {
+	l := UploadLimits()
+	allowed, limitErr := int64(-1), (*QuotaError)(nil)
+	tighter := func(n int64, e *QuotaError) {
+		if n < 0 {
+			n = 0
+		}
+		if allowed < 0 || n < allowed {
+			allowed, limitErr = n, e
+		}
+	}
+
+	if l.MaxFileSize > 0 {
+		tighter(l.MaxFileSize, quotaError("quotas.max_file_mb", l.MaxFileSize,
+			"el archivo supera el máximo de %s por archivo", utils.FormatFileSize(l.MaxFileSize)))
+	}
+	if l.UserQuota > 0 && user != "" {
+		usage, err := UserUsage(user)
+		if err != nil {
+			return 0, nil, err
+		}
+		tighter(l.UserQuota-usage.Total, quotaError("quotas.user_quota_mb", l.UserQuota,
+			"se superaría la cuota de %s del usuario %s (usa %s)", utils.FormatFileSize(l.UserQuota), user, utils.FormatFileSize(usage.Total)))
+	}
+	if l.MinFreeSpace > 0 {
+		u, err := disk.GetUsage(UploadsDir)
+		if err != nil {
+			return 0, nil, err
+		}
+		tighter(int64(u.Free)-l.MinFreeSpace, quotaError("quotas.min_free_mb", l.MinFreeSpace,
+			"no queda espacio en el servidor (se reservan %s libres)", utils.FormatFileSize(l.MinFreeSpace)))
+	}
+	return allowed, limitErr, nil
+}
+
+// CheckUploadSize rechaza de entrada una subida que ya se sabe que no entra
+// (por ejemplo por su Content-Length)
+func CheckUploadSize(user string, size int64) error {
+	allowed, limitErr, err := uploadAllowance(user)
+	if err != nil {
+		return err
+	}
+	if allowed >= 0 && size > allowed {
+		return limitErr
+	}
+	return nil
+}
+
+// ReceiveUpload copia r a un archivo temporal en TempDir mientras llega y corta
+// apenas supera lo permitido para user. Devuelve la ruta del temporal y el tamaño.
+func ReceiveUpload(r io.Reader, user string) (string, int64, error) {
+	allowed, limitErr, err := uploadAllowance(user)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := os.MkdirAll(TempDir, 0755); err != nil {
+		return "", 0, err
+	}
+	tmp, err := os.CreateTemp(TempDir, "upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+
+	src := r
+	if allowed >= 0 {
+		src = io.LimitReader(r, allowed+1) // un byte de más alcanza para saber que se pasó
+	}
+	n, err := io.Copy(tmp, src)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && allowed >= 0 && n > allowed {
+		err = limitErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", n, err
+	}
+	return tmp.Name(), n, nil
+}
+
+// userLocks serializa lo que se agrega a las sesiones de cada usuario: los
+// límites se miran al recibir, pero dos subidas a la vez pasarían juntas, así
+// que se vuelven a mirar con el lock tomado justo antes de mover el archivo
+var userLocks sync.Map
+
+func lockUser(user string) func() {
+	mu, _ := userLocks.LoadOrStore(user, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// AddToSession mueve un archivo recibido con ReceiveUpload a la sesión, en la
+// ruta relativa rel, si la sesión no supera su tamaño ni su cantidad de archivos
+// y su dueño no supera la cuota. Si la sesión es nueva se le registra owner
+// (vacío = sin dueño) antes de mover, para que ya cuente en la cuota.
+func AddToSession(sessionID, rel, tmpPath string, size int64, owner Owner) error {
+	dir, err := SessionDir(sessionID)
+	if err != nil {
+		return err
+	}
+	target, err := safepath.Join(dir, rel)
+	if err != nil {
+		return err
+	}
+
+	_, statErr := os.Stat(dir)
+	created := os.IsNotExist(statErr)
+	if o, ok := GetOwner(sessionID); ok {
+		owner, created = o, false
+	}
+	defer lockUser(owner.User)()
+	// El GC borra con este mismo lock: si se llevó la sesión mientras se esperaba,
+	// no se recrea sin dueño
+	if _, err := os.Stat(dir); !created && os.IsNotExist(err) {
+		return fmt.Errorf("la sesión %s ya no existe: %w", sessionID, fs.ErrNotExist)
+	}
+
+	// Si reemplaza un archivo, el viejo deja de contar
+	var replaced int64
+	replacing := false
+	if info, err := os.Stat(target); err == nil && info.Mode().IsRegular() {
+		replaced, replacing = info.Size(), true
+	}
+
+	l := UploadLimits()
+	if l.MaxSessionSize > 0 || l.MaxSessionFiles > 0 {
+		files, total := 0, int64(0)
+		if s, err := GetSession(sessionID); err == nil {
+			files, total = s.Files, s.Size
+		}
+		if replacing {
+			files--
+			total -= replaced
+		}
+		if l.MaxSessionFiles > 0 && files+1 > l.MaxSessionFiles {
+			return quotaError("quotas.max_session_files", int64(l.MaxSessionFiles),
+				"la sesión ya tiene el máximo de %d archivos", l.MaxSessionFiles)
+		}
+		if l.MaxSessionSize > 0 && total+size > l.MaxSessionSize {
+			return quotaError("quotas.max_session_mb", l.MaxSessionSize,
+				"la sesión superaría el máximo de %s", utils.FormatFileSize(l.MaxSessionSize))
+		}
+	}
+	if l.UserQuota > 0 && owner.User != "" {
+		usage, err := UserUsage(owner.User)
+		if err != nil {
+			return err
+		}
+		if usage.Total-replaced+size > l.UserQuota {
+			return quotaError("quotas.user_quota_mb", l.UserQuota,
+				"se superaría la cuota de %s del usuario %s (usa %s)", utils.FormatFileSize(l.UserQuota), owner.User, utils.FormatFileSize(usage.Total))
+		}
+	}
+
+	if created && owner.User != "" {
+		if err := SetOwner(sessionID, owner); err != nil {
+			return err
+		}
+	}
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err == nil {
+		err = moveFile(tmpPath, target)
+	}
+	if err != nil && created {
+		os.RemoveAll(dir)
+		removeOwnerIfOrphan(sessionID)
+	}
+	return err
+}
+
+// moveFile renombra src a dst o, si están en discos distintos, lo copia y borra src
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if _, _, err := copyFileAndVerify(src, dst); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,91 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Report es el informe final de un trabajo, guardado junto al archivo de backup
+type Report struct {
+	JobID      string    `json:"job_id"`
+	SessionID  string    `json:"session_id,omitempty"`
+	BackupType string    `json:"backup_type"`
+	Profile    string    `json:"profile,omitempty"`
+	Status     string    `json:"status"`
+	Source     string    `json:"source"`
+	Dest       string    `json:"dest"`
+	Archive    string    `json:"archive,omitempty"`
+	LogFile    string    `json:"log_file"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Duration   float64   `json:"duration_seconds"`
+
+	// Duración de cada fase (scan, copy, compress) en segundos
+	Phases map[string]float64 `json:"phases"`
+
+	FilesProcessed int   `json:"files_processed"`
+	FilesSkipped   int   `json:"files_skipped"`
+	FilesFailed    int   `json:"files_failed"`
+	BytesRead      int64 `json:"bytes_read"`
+	BytesWritten   int64 `json:"bytes_written"`
+
+	Files    []ReportFile    `json:"files"`
+	Failures []ReportFailure `json:"failures"`
+	Error    string          `json:"error,omitempty"` // error que abortó el trabajo
+}
+
+// ReportFile es un archivo copiado con su checksum verificado
+type ReportFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ReportFailure es un archivo que no pudo copiarse
+type ReportFailure struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// legacyReportBase devuelve la ruta base del log y el informe del modo legacy,
+// que no genera un archivo junto al cual guardarlos
+func legacyReportBase(start time.Time) string {
+	return filepath.Join(BackupsDir, "reports", "legacy-"+start.Format("20060102-150405"))
+}
+
+// writeReport guarda el informe como JSON
+func writeReport(path string, r *Report) error {
+	sort.Slice(r.Files, func(i, j int) bool { return r.Files[i].Path < r.Files[j].Path })
+	sort.Slice(r.Failures, func(i, j int) bool { return r.Failures[i].Path < r.Failures[j].Path })
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Escribir a un temporal y renombrar para no dejar informes a medias
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadReport lee un informe guardado por writeReport
+func LoadReport(path string) (*Report, error) {
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("informe inválido %s: %w", path, err)
+	}
+	return &r, nil
+}
@@ -2,82 +2,28 @@ package backup
 
 import (
 	"archive/zip"
-	"encoding/json"
+	"context"
 	"fmt"
+	"gobackup/internal/config"
+	"gobackup/internal/disk"
+	"gobackup/internal/history"
+	"gobackup/internal/logger"
+	"gobackup/internal/metrics"
+	"gobackup/internal/safepath"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 )
 
-// Las variables globales serán inicializadas por el comando Cobra en cmd/root.go
-var SourceDir string
-var BackupDir string
-var ModifiedMinutes int
-var MaxConcurrency int
-
-// Variables globales para el nuevo sistema
+// Directorios del sistema de sesiones, inicializados por cmd/root.go desde la
+// configuración. Lo que define cada backup (orígenes, destino, filtros...) va en Profile.
 var UploadsDir string
 var BackupsDir string
 var TempDir string
 var CurrentSessionID string
 
-// Estructuras para estadísticas
-type BackupStats struct {
-	Timestamp  time.Time `json:"timestamp"`
-	TotalSize  int64     `json:"total_size"`
-	FilesCount int       `json:"files_count"`
-	BackupType string    `json:"backup_type"`
-	Duration   float64   `json:"duration_seconds"`
-	Status     string    `json:"status"`
-	SessionID  string    `json:"session_id"`
-}
-
-type FileStats struct {
-	Path     string    `json:"path"`
-	Size     int64     `json:"size"`
-	Modified time.Time `json:"modified"`
-}
-
-// Función para guardar estadísticas
-// Función para guardar estadísticas (VERSIÓN CORREGIDA)
-func saveBackupStats(stats BackupStats, fileStats []FileStats) error {
-	historyFile := filepath.Join(BackupsDir, "backup_history.json")
-
-	var history struct {
-		Backups []BackupStats `json:"backups"`
-		Files   []FileStats   `json:"files"`
-	}
-
-	// Cargar historial existente si existe
-	if data, err := os.ReadFile(historyFile); err == nil {
-		json.Unmarshal(data, &history)
-	}
-
-	// Agregar nuevas estadísticas
-	history.Backups = append(history.Backups, stats)
-
-	// Limitar a últimos 100 backups para no hacer el archivo muy grande
-	if len(history.Backups) > 100 {
-		history.Backups = history.Backups[len(history.Backups)-100:]
-	}
-
-	// Actualizar información de archivos (mantener solo los más recientes)
-	history.Files = append(history.Files, fileStats...)
-	if len(history.Files) > 1000 {
-		history.Files = history.Files[len(history.Files)-1000:]
-	}
-
-	// Guardar en archivo
-	data, err := json.MarshalIndent(history, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	return os.WriteFile(historyFile, data, 0644)
-}
 func analyzeFileTypes(files []string) map[string]int64 {
 	typeStats := make(map[string]int64)
 
@@ -109,164 +55,326 @@ func analyzeFileTypes(files []string) map[string]int64 {
 	return typeStats
 }
 
-// RunBackup ejecuta el proceso completo de backup.
-func RunBackup() error {
-	// Validar que al menos BackupDir esté configurado
-	if BackupDir == "" {
-		return fmt.Errorf("BackupDir no está configurado")
+// RunBackup ejecuta el backup del modo legacy: copia el primer origen del
+// perfil a su destino, sin comprimir.
+// Cancelar ctx detiene la copia y registra la ejecución como cancelada.
+// Devuelve el snapshot registrado (vacío si falló antes de empezar): un backup
+// con archivos fallidos termina sin error pero con estado partial.
+func RunBackup(ctx context.Context, p Profile) (snap history.Snapshot, err error) {
+	// Validar que al menos el destino esté configurado
+	if p.Dest == "" {
+		return snap, fmt.Errorf("el destino del perfil %s no está configurado", p.Name)
 	}
 
-	// Si SourceDir está vacío, es porque se usará drag & drop
-	if SourceDir == "" {
-		return fmt.Errorf("no se ha seleccionado ninguna carpeta fuente")
+	// Sin origen es porque se usará drag & drop
+	if len(p.Sources) == 0 {
+		return snap, fmt.Errorf("no se ha seleccionado ninguna carpeta fuente")
 	}
-
-	log.Printf("Iniciando backup desde: %s hacia: %s", SourceDir, BackupDir)
+	src := jobSource{Dir: p.Sources[0]}
 
 	// Validar que el directorio fuente existe
-	if _, err := os.Stat(SourceDir); os.IsNotExist(err) {
-		return fmt.Errorf("el directorio fuente no existe: %s", SourceDir)
+	if _, err := os.Stat(src.Dir); os.IsNotExist(err) {
+		return snap, fmt.Errorf("el directorio fuente no existe: %s", src.Dir)
 	}
 
 	// Validar que es un directorio
-	if info, err := os.Stat(SourceDir); err == nil && !info.IsDir() {
-		return fmt.Errorf("la ruta fuente no es un directorio: %s", SourceDir)
+	if info, err := os.Stat(src.Dir); err == nil && !info.IsDir() {
+		return snap, fmt.Errorf("la ruta fuente no es un directorio: %s", src.Dir)
 	}
 
-	files, err := ScanModifiedFiles(SourceDir, ModifiedMinutes)
+	// A partir de aquí toda ejecución queda registrada en el historial
+	run := newRunStats(src.Dir, "", "legacy", legacyReportBase(time.Now()))
+	run.snap.Profile = p.Name
+	run.report.Profile = p.Name
+	run.report.Source = src.Dir
+	run.report.Dest = p.Dest
+	defer func() { snap = run.finish(ctx, err) }()
+
+	// Todo lo que se registre con lg queda también en el log propio del trabajo
+	lg := run.capture(logger.With("job_id", src.Dir))
+	ctx = logger.NewContext(ctx, lg)
+	lg.Info("Iniciando backup", "source", src.Dir, "dest", p.Dest)
+	run.notify(config.WebhookJobStarted)
+
+	metrics.ActiveJobs.Inc()
+	defer metrics.ActiveJobs.Dec()
+
+	scanDone := run.phase("scan")
+	files, skipped, err := scanFiles(src.Dir, p.filter())
+	scanDone()
 	if err != nil {
-		errMsg := fmt.Sprintf("Error escaneando archivos: %v", err)
-		Status.SetError(errMsg)
-		log.Println(errMsg)
-		return err
+		Status.SetError(fmt.Sprintf("Error escaneando archivos: %v", err))
+		lg.Error("Error escaneando archivos", "error", err)
+		return snap, err
 	}
+	run.snap.FilesSkipped = skipped
+	run.collectFiles(src, files)
 
 	Status.Reset(len(files))
-	log.Printf("Archivos detectados para copiar: %d", len(files))
+	lg.Info("Archivos detectados para copiar", "files", len(files), "skipped", skipped, "bytes", run.snap.TotalSize)
 
 	if len(files) == 0 {
-		log.Println("No hay archivos para copiar. Backup completado.")
+		lg.Info("No hay archivos para copiar. Backup completado.")
 		Status.SetDone()
-		return nil
+		return snap, nil
 	}
 
-	err = CopyFilesConcurrent(files, SourceDir, BackupDir, MaxConcurrency)
-	if err != nil {
-		errMsg := fmt.Sprintf("Error copiando archivos: %v", err)
-		Status.SetError(errMsg)
-		log.Println(errMsg)
-		return err
+	// Verificar antes de empezar que la copia entra en el destino
+	if err := disk.CheckFree(p.Dest, uint64(run.snap.TotalSize)); err != nil {
+		Status.SetError(err.Error())
+		lg.Error("Verificación de espacio fallida", "error", err)
+		return snap, err
+	}
+
+	copyDone := run.phase("copy")
+	res, copyErr := CopyFilesConcurrent(ctx, files, src.Dir, p.Dest, p.Concurrency)
+	copyDone()
+	run.addCopyResult(src, res)
+	run.snap.BytesWritten = res.BytesRead
+	if ctx.Err() != nil {
+		Status.SetError("Backup cancelado")
+		return snap, ctx.Err()
+	}
+	if copyErr != nil && res.Copied == 0 {
+		Status.SetError(fmt.Sprintf("Error copiando archivos: %v", copyErr))
+		lg.Error("Error copiando archivos", "error", copyErr)
+		return snap, copyErr
 	}
 
 	Status.SetDone()
-	log.Println("Backup finalizado correctamente.")
-	return nil
+	if len(res.Failed) > 0 {
+		lg.Warn("Backup finalizado parcialmente", "files_failed", len(res.Failed))
+	} else {
+		lg.Info("Backup finalizado correctamente.")
+	}
+	return snap, nil
 }
 
-// RunBackupWithSession ejecuta el proceso completo de backup con sistema de sesiones.
-func RunBackupWithSession(sessionID string) error {
-	CurrentSessionID = sessionID
-	sourceDir := filepath.Join(UploadsDir, sessionID)
-	backupDir := filepath.Join(BackupsDir, sessionID)
-	startTime := time.Now()
-	var totalSize int64
-	var fileStats []FileStats
+// filter arma el filtro de escaneo del perfil
+func (p Profile) filter() scanFilter {
+	return scanFilter{Since: p.Since, Include: p.Include, Exclude: p.Exclude}
+}
+
+// JobOptions describe una ejecución del pipeline de backup: escaneo, copia
+// verificada, compresión, cifrado, registro en el historial y retención.
+type JobOptions struct {
+	JobID      string  // identifica el trabajo en el historial
+	SessionID  string  // nombre del archivo generado (sin extensión)
+	BackupType string  // "session", "cli", "profile"...
+	Profile    Profile // qué respaldar, dónde y cómo
+}
 
-	// Validar directorios
-	if sourceDir == "" || backupDir == "" {
-		return fmt.Errorf("SourceDir o BackupDir no están configurados")
+// RunBackupWithSession ejecuta el backup de una sesión de subida con la compresión,
+// filtros y cifrado del perfil. El archivo queda en BackupsDir para poder descargarlo.
+// Cada ejecución, exitosa o no, queda registrada en el historial.
+func RunBackupWithSession(ctx context.Context, p Profile, sessionID string) error {
+	dir, err := SessionDir(sessionID)
+	if err != nil {
+		return err
 	}
+	CurrentSessionID = sessionID
+	p.Sources = []string{dir}
+	p.Dest = BackupsDir
+	p.Retention = config.RetentionConfig{} // las sesiones se limpian con GCSessions
+	_, err = RunJob(ctx, JobOptions{
+		JobID:      sessionID,
+		SessionID:  sessionID,
+		BackupType: "session",
+		Profile:    p,
+	})
+	return err
+}
 
-	log.Printf("Iniciando backup desde: %s hacia: %s", sourceDir, backupDir)
+// jobSources asigna a cada origen su carpeta dentro del archivo. Con un solo
+// origen su contenido va a la raíz; con varios, cada uno en una carpeta con su nombre.
+func jobSources(dirs []string) []jobSource {
+	if len(dirs) == 1 {
+		return []jobSource{{Dir: dirs[0]}}
+	}
+	used := make(map[string]int)
+	sources := make([]jobSource, len(dirs))
+	for i, dir := range dirs {
+		name := filepath.Base(filepath.Clean(dir))
+		if used[name]++; used[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, used[name])
+		}
+		sources[i] = jobSource{Dir: dir, Name: name}
+	}
+	return sources
+}
 
-	// Validar que el directorio fuente existe y tiene archivos
-	if _, err := os.Stat(sourceDir); os.IsNotExist(err) {
-		return fmt.Errorf("el directorio fuente no existe: %s", sourceDir)
+// RunJob ejecuta el pipeline completo y devuelve el snapshot registrado en el historial.
+// El archivo queda en Dest/SessionID.<formato>[.enc], junto a su log e informe.
+func RunJob(ctx context.Context, opts JobOptions) (snap history.Snapshot, err error) {
+	p := opts.Profile
+	if len(p.Sources) == 0 || p.Dest == "" || opts.SessionID == "" {
+		return snap, fmt.Errorf("origen, destino o sesión no configurados")
+	}
+	// El nombre del archivo no puede salir de Dest
+	if err := safepath.ID(opts.SessionID); err != nil {
+		return snap, err
+	}
+	if p.Format == "" {
+		p.Format = FormatZip
+	}
+	if p.Concurrency <= 0 {
+		p.Concurrency = 1
+	}
+	sources := jobSources(p.Sources)
+	stagingDir := filepath.Join(p.Dest, opts.SessionID)
+	archivePath := stagingDir + "." + p.Format
+
+	run := newRunStats(opts.JobID, opts.SessionID, opts.BackupType, stagingDir)
+	run.snap.Profile = p.Name
+	run.report.Profile = p.Name
+	run.report.Source = strings.Join(p.Sources, ", ")
+	run.report.Dest = p.Dest
+	defer func() { snap = run.finish(ctx, err) }()
+
+	metrics.ActiveJobs.Inc()
+	defer metrics.ActiveJobs.Dec()
+
+	// Todo lo que se registre con lg queda también en el log propio del trabajo
+	lg := run.capture(logger.With("job_id", opts.JobID, "session_id", opts.SessionID))
+	ctx = logger.NewContext(ctx, lg)
+	lg.Info("Iniciando backup", "profile", p.Name, "sources", len(sources), "dest", p.Dest,
+		"format", p.Format, "encrypted", p.Encrypted())
+	run.notify(config.WebhookJobStarted)
+
+	// Leer la frase antes de copiar nada: sin ella no tiene sentido empezar
+	var passphrase string
+	if p.Encrypted() {
+		if passphrase, err = p.passphrase(); err != nil {
+			Status.SetError(err.Error())
+			return snap, err
+		}
 	}
 
-	files, err := ScanModifiedFiles(sourceDir, ModifiedMinutes)
-	if err != nil {
-		errMsg := fmt.Sprintf("Error escaneando archivos: %v", err)
-		Status.SetError(errMsg)
-		log.Println(errMsg)
-		return err
+	// Validar que los directorios fuente existen
+	for _, src := range sources {
+		if _, err := os.Stat(src.Dir); os.IsNotExist(err) {
+			return snap, fmt.Errorf("el directorio fuente no existe: %s", src.Dir)
+		}
 	}
 
-	// Calcular tamaño total y recopilar stats de archivos
-	for _, filePath := range files {
-		info, err := os.Stat(filePath)
-		if err == nil {
-			size := info.Size()
-			totalSize += size
-			relPath, _ := filepath.Rel(sourceDir, filePath)
-			fileStats = append(fileStats, FileStats{
-				Path:     relPath,
-				Size:     size,
-				Modified: info.ModTime(),
-			})
+	scanDone := run.phase("scan")
+	files := make([][]string, len(sources))
+	total := 0
+	for i, src := range sources {
+		found, skipped, err := scanFiles(src.Dir, p.filter())
+		if err != nil {
+			scanDone()
+			Status.SetError(fmt.Sprintf("Error escaneando archivos: %v", err))
+			lg.Error("Error escaneando archivos", "dir", src.Dir, "error", err)
+			return snap, err
 		}
+		files[i] = found
+		total += len(found)
+		run.snap.FilesSkipped += skipped
+		run.collectFiles(src, found)
 	}
+	scanDone()
 
-	Status.Reset(len(files))
-	log.Printf("Archivos detectados para copiar: %d", len(files))
+	Status.Reset(total)
+	lg.Info("Archivos detectados para copiar", "files", total, "skipped", run.snap.FilesSkipped, "bytes", run.snap.TotalSize)
 
-	if len(files) == 0 {
-		log.Println("No hay archivos para copiar. Backup completado.")
+	if total == 0 {
+		lg.Info("No hay archivos para copiar. Backup completado.")
 		Status.SetDone()
-		return nil
+		return snap, nil
 	}
 
-	// Crear directorio de backup
-	os.MkdirAll(backupDir, 0755)
+	// La copia sin comprimir y el archivo coexisten: se necesita el doble del tamaño
+	if err := disk.CheckFree(p.Dest, 2*uint64(run.snap.TotalSize)); err != nil {
+		Status.SetError(err.Error())
+		lg.Error("Verificación de espacio fallida", "error", err)
+		return snap, err
+	}
 
-	err = CopyFilesConcurrent(files, sourceDir, backupDir, MaxConcurrency)
-	if err != nil {
-		errMsg := fmt.Sprintf("Error copiando archivos: %v", err)
-		Status.SetError(errMsg)
-		log.Println(errMsg)
-		return err
+	// Crear directorio de backup
+	if err := os.MkdirAll(stagingDir, 0755); err != nil {
+		Status.SetError(err.Error())
+		return snap, err
+	}
+	// Limpiar directorio sin comprimir al terminar, haya error o no
+	defer os.RemoveAll(stagingDir)
+
+	copyDone := run.phase("copy")
+	var copyErr error
+	failed := 0
+	for i, src := range sources {
+		res, err := CopyFilesConcurrent(ctx, files[i], src.Dir, filepath.Join(stagingDir, src.Name), p.Concurrency)
+		run.addCopyResult(src, res)
+		failed += len(res.Failed)
+		if err != nil && copyErr == nil {
+			copyErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	copyDone()
+	if ctx.Err() != nil {
+		Status.SetError("Backup cancelado")
+		return snap, ctx.Err()
+	}
+	if copyErr != nil && run.snap.FilesCount == 0 {
+		Status.SetError(fmt.Sprintf("Error copiando archivos: %v", copyErr))
+		lg.Error("Error copiando archivos", "error", copyErr)
+		return snap, copyErr
 	}
 
-	// Comprimir el directorio de backup
-	zipPath := filepath.Join(BackupsDir, sessionID+".zip")
-	err = ZipDirectory(backupDir, zipPath)
+	// Comprimir el directorio de backup (solo los archivos copiados)
+	Status.SetPhase("compress")
+	compressDone := run.phase("compress")
+	err = CreateArchive(ctx, p.Format, stagingDir, archivePath)
+	compressDone()
 	if err != nil {
-		errMsg := fmt.Sprintf("Error comprimiendo backup: %v", err)
-		Status.SetError(errMsg)
-		log.Println(errMsg)
-		return err
+		os.Remove(archivePath)
+		Status.SetError(fmt.Sprintf("Error comprimiendo backup: %v", err))
+		lg.Error("Error comprimiendo backup", "file", archivePath, "error", err)
+		return snap, err
 	}
 
-	log.Printf("Backup comprimido creado: %s", zipPath)
-
-	// Opcional: Limpiar directorio sin comprimir después de comprimir
-	os.RemoveAll(backupDir)
-
-	// Guardar estadísticas
-	duration := time.Since(startTime).Seconds()
-	stats := BackupStats{
-		Timestamp:  time.Now(),
-		TotalSize:  totalSize,
-		FilesCount: len(files),
-		BackupType: "session",
-		Duration:   duration,
-		Status:     "success",
-		SessionID:  sessionID,
+	if p.Encrypted() {
+		Status.SetPhase("encrypt")
+		encryptDone := run.phase("encrypt")
+		encPath, err := EncryptFile(archivePath, passphrase)
+		encryptDone()
+		if err != nil {
+			os.Remove(archivePath)
+			Status.SetError(fmt.Sprintf("Error cifrando backup: %v", err))
+			lg.Error("Error cifrando backup", "file", archivePath, "error", err)
+			return snap, err
+		}
+		archivePath = encPath
 	}
 
-	if err != nil {
-		stats.Status = "failed"
+	run.report.Archive = archivePath
+	run.snap.Archive = archivePath
+	if info, err := os.Stat(archivePath); err == nil {
+		run.snap.BytesWritten = info.Size()
 	}
+	lg.Info("Backup comprimido creado", "file", archivePath, "bytes", run.snap.BytesWritten)
 
-	saveBackupStats(stats, fileStats)
+	// Retención: solo sobre los archivos del perfil (<perfil>_<fecha>)
+	if strings.HasPrefix(opts.SessionID, p.Name+"_") {
+		if _, err := ApplyRetention(ctx, p); err != nil {
+			lg.Warn("Error aplicando retención", "profile", p.Name, "error", err)
+		}
+	}
 
 	Status.SetDone()
-	log.Println("Backup finalizado correctamente.")
-	return nil
+	if failed > 0 {
+		lg.Warn("Backup finalizado parcialmente", "files_failed", failed)
+	} else {
+		lg.Info("Backup finalizado correctamente.")
+	}
+	return snap, nil
 }
 
 // ZipDirectory comprime un directorio completo a un archivo ZIP
-func ZipDirectory(sourceDir, zipPath string) error {
+func ZipDirectory(ctx context.Context, sourceDir, zipPath string) error {
 	// Crear archivo ZIP
 	zipFile, err := os.Create(zipPath)
 	if err != nil {
@@ -275,13 +383,15 @@ func ZipDirectory(sourceDir, zipPath string) error {
 	defer zipFile.Close()
 
 	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
 
 	// Función para caminar por el directorio y agregar archivos al ZIP
 	err = filepath.Walk(sourceDir, func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 
 		// Omitir directorios
 		if info.IsDir() {
@@ -328,21 +438,25 @@ func ZipDirectory(sourceDir, zipPath string) error {
 			return err
 		}
 
-		log.Printf("Comprimido: %s -> %s", filePath, relPath)
+		logger.FromContext(ctx).Debug("Comprimido", "file", relPath, "bytes", info.Size())
 		return nil
 	})
 
 	if err != nil {
-		return fmt.Errorf("error recorriendo directorio: %v", err)
+		zipWriter.Close()
+		return fmt.Errorf("error recorriendo directorio: %w", err)
 	}
 
-	return nil
+	// Cerrar explícitamente para detectar errores al escribir el índice del ZIP
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("error cerrando archivo ZIP: %w", err)
+	}
+	return zipFile.Close()
 }
 
 // GetBackupSize obtiene el tamaño del archivo de backup
 func GetBackupSize(sessionID string) (int64, error) {
-	zipPath := filepath.Join(BackupsDir, sessionID+".zip")
-	info, err := os.Stat(zipPath)
+	info, err := os.Stat(GetBackupPath(sessionID))
 	if err != nil {
 		return 0, err
 	}
@@ -351,28 +465,45 @@ func GetBackupSize(sessionID string) (int64, error) {
 
 // BackupExists verifica si existe un backup para la sesión
 func BackupExists(sessionID string) bool {
-	zipPath := filepath.Join(BackupsDir, sessionID+".zip")
-	_, err := os.Stat(zipPath)
-	return err == nil
+	_, ok := findArchive(BackupsDir, sessionID)
+	return ok
 }
 
-// GetBackupPath obtiene la ruta del archivo de backup
+// GetBackupPath obtiene la ruta del archivo de backup (ZIP si todavía no existe
+// ninguno). Con un ID inválido devuelve "", que no existe.
 func GetBackupPath(sessionID string) string {
+	if path, ok := findArchive(BackupsDir, sessionID); ok {
+		return path
+	}
+	if safepath.ID(sessionID) != nil {
+		return ""
+	}
 	return filepath.Join(BackupsDir, sessionID+".zip")
 }
 
 // CleanupSession limpia los archivos temporales de una sesión
 func CleanupSession(sessionID string) error {
-	sourceDir := filepath.Join(UploadsDir, sessionID)
-	backupDir := filepath.Join(BackupsDir, sessionID)
-	zipPath := filepath.Join(BackupsDir, sessionID+".zip")
+	sourceDir, err := SessionDir(sessionID)
+	if err != nil {
+		return err
+	}
+	backupDir, err := safepath.Join(BackupsDir, sessionID)
+	if err != nil {
+		return err
+	}
 
-	// Limpiar todos los archivos de la sesión
+	// Limpiar todos los archivos de la sesión (incluidos su log, informe y dueño)
 	os.RemoveAll(sourceDir)
 	os.RemoveAll(backupDir)
-	os.Remove(zipPath)
+	for _, f := range Formats {
+		os.Remove(backupDir + "." + f)
+		os.Remove(backupDir + "." + f + EncryptedExt)
+	}
+	os.Remove(backupDir + ".log")
+	os.Remove(backupDir + ".report.json")
+	os.Remove(backupDir + ".owner.json")
 
-	log.Printf("Sesión limpiada: %s", sessionID)
+	logger.Info("Sesión limpiada", "session_id", sessionID)
 	return nil
 }
 
@@ -385,7 +516,7 @@ func ListBackups() ([]string, error) {
 
 	var backups []string
 	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".zip") && !file.IsDir() {
+		if _, ok := archiveFormat(file.Name()); ok && !file.IsDir() {
 			backups = append(backups, file.Name())
 		}
 	}
@@ -395,15 +526,15 @@ func ListBackups() ([]string, error) {
 
 // GetBackupInfo obtiene información detallada del backup
 func GetBackupInfo(sessionID string) (map[string]interface{}, error) {
-	zipPath := GetBackupPath(sessionID)
-	info, err := os.Stat(zipPath)
+	archivePath := GetBackupPath(sessionID)
+	info, err := os.Stat(archivePath)
 	if err != nil {
 		return nil, err
 	}
 
 	return map[string]interface{}{
 		"sessionId":   sessionID,
-		"filename":    sessionID + ".zip",
+		"filename":    filepath.Base(archivePath),
 		"size":        info.Size(),
 		"sizeMB":      fmt.Sprintf("%.2f MB", float64(info.Size())/1024/1024),
 		"created":     info.ModTime(),
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"gobackup/internal/logger"
 	"os"
+	"path"
 	"path/filepath"
 	"time"
 )
@@ -12,51 +13,89 @@ import (
 // ScanModifiedFiles escanea rootDir recursivamente y devuelve las rutas
 // de archivos modificados en los últimos modifiedMinutes minutos.
 func ScanModifiedFiles(rootDir string, modifiedMinutes int) ([]string, error) {
-	var files []string
+	files, _, err := scanFiles(rootDir, scanFilter{Since: time.Duration(modifiedMinutes) * time.Minute})
+	return files, err
+}
 
-	logger.Infof("Escaneando directorio: %s (últimos %d minutos)", rootDir, modifiedMinutes)
-	if _, err := os.Stat(rootDir); os.IsNotExist(err) {
-		logger.Errorf("EL DIRECTORIO NO EXISTE: %s", rootDir)
-		return nil, fmt.Errorf("directorio no existe: %s", rootDir)
+// scanFilter elige qué archivos entran en el backup
+type scanFilter struct {
+	Since   time.Duration // solo modificados en este período (0 = todos)
+	Include []string      // patrones glob; vacío = todos
+	Exclude []string      // patrones glob; un directorio excluido se omite completo
+}
+
+// matchAny prueba los patrones contra la ruta relativa y contra el nombre
+func matchAny(patterns []string, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	base := path.Base(rel)
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, base); ok {
+			return true
+		}
 	}
-	// FIN DE VERIFICACIÓN
+	return false
+}
+
+// scanFiles hace el escaneo y además cuenta los archivos omitidos
+// (fuera de la ventana de tiempo, filtrados o inaccesibles).
+func scanFiles(rootDir string, filter scanFilter) ([]string, int, error) {
+	since := filter.Since
+	var files []string
+	skipped := 0
+
+	logger.Info("Escaneando directorio", "dir", rootDir, "since", since.String())
 	if _, err := os.Stat(rootDir); os.IsNotExist(err) {
-		logger.Errorf("EL DIRECTORIO NO EXISTE: %s", rootDir)
-		return nil, fmt.Errorf("directorio no existe: %s", rootDir)
+		logger.Error("El directorio no existe", "dir", rootDir)
+		return nil, 0, fmt.Errorf("directorio no existe: %s", rootDir)
 	}
 
 	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			logger.Warnf("No se puede acceder a %s: %v", path, err)
+			logger.Warn("No se puede acceder al archivo", "file", path, "error", err)
+			skipped++
 			return nil // ignoramos error pero continuamos
 		}
 
+		rel, _ := filepath.Rel(rootDir, path)
 		if info.IsDir() {
+			if rel != "." && matchAny(filter.Exclude, rel) {
+				logger.Debug("Directorio excluido", "dir", path)
+				return filepath.SkipDir
+			}
 			return nil // ignoramos directorios
 		}
+		if matchAny(filter.Exclude, rel) || (len(filter.Include) > 0 && !matchAny(filter.Include, rel)) {
+			skipped++
+			return nil
+		}
 
-		// Si modifiedMinutes es 0, incluimos todos los archivos.
-		if modifiedMinutes == 0 {
+		// Si since es 0, incluimos todos los archivos.
+		if since <= 0 {
 			files = append(files, path)
-			logger.Debugf("Archivo detectado (sin filtro de tiempo): %s", path)
+			logger.Debug("Archivo detectado (sin filtro de tiempo)", "file", path, "bytes", info.Size())
 			return nil
 		}
 
-		// Si modifiedMinutes es mayor a 0, aplicamos el filtro de tiempo.
-		cutoff := time.Now().Add(-time.Duration(modifiedMinutes) * time.Minute)
+		// Si since es mayor a 0, aplicamos el filtro de tiempo.
+		cutoff := time.Now().Add(-since)
 		if info.ModTime().After(cutoff) {
 			files = append(files, path)
-			logger.Debugf("Archivo modificado detectado: %s", path)
+			logger.Debug("Archivo modificado detectado", "file", path, "bytes", info.Size())
+		} else {
+			skipped++
 		}
 
 		return nil
 	})
 
 	if err != nil {
-		logger.Errorf("Error escaneando directorio %s: %v", rootDir, err)
-		return nil, err
+		logger.Error("Error escaneando directorio", "dir", rootDir, "error", err)
+		return nil, 0, err
 	}
 
-	logger.Infof("Escaneo completado. Archivos encontrados: %d", len(files))
-	return files, nil
+	logger.Info("Escaneo completado", "dir", rootDir, "files", len(files), "skipped", skipped)
+	return files, skipped, nil
 }
@@ -0,0 +1,348 @@
+package backup
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"gobackup/internal/logger"
+	"gobackup/internal/safepath"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// UploadTTL es la antigüedad a partir de la cual una sesión sin actividad se borra (0 = nunca).
+// Se inicializa desde la configuración en cmd/root.go.
+var UploadTTL time.Duration
+
+// Session describe una sesión de subida en UploadsDir
+type Session struct {
+	ID           string    `json:"id"`
+	Path         string    `json:"path"`
+	Files        int       `json:"files"`
+	Size         int64     `json:"size"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"last_modified"`
+	BackedUp     bool      `json:"backed_up"`             // existe el ZIP de la sesión
+	BackupTime   time.Time `json:"backup_time,omitempty"` // fecha del ZIP
+	InProgress   bool      `json:"in_progress"`           // hay un backup corriendo sobre la sesión
+	Owner        string    `json:"owner,omitempty"`       // usuario dueño (vacío = solo admin)
+}
+
+// SessionFile es un archivo dentro de una sesión de subida
+type SessionFile struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// NewSessionID genera un ID de sesión aleatorio (128 bits, imposible de adivinar)
+// que no exista en UploadsDir
+func NewSessionID() string {
+	for {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			panic(err) // crypto/rand no falla en los sistemas soportados
+		}
+		id := "session_" + hex.EncodeToString(b)
+		if _, err := os.Stat(filepath.Join(UploadsDir, id)); os.IsNotExist(err) && !BackupExists(id) {
+			return id
+		}
+	}
+}
+
+// SessionDir devuelve el directorio de una sesión, validando el ID
+func SessionDir(id string) (string, error) {
+	if err := safepath.ID(id); err != nil {
+		return "", fmt.Errorf("ID de sesión inválido %q: %w", id, safepath.ErrUnsafe)
+	}
+	return safepath.Join(UploadsDir, id)
+}
+
+// sessionBusy indica si hay un backup en curso sobre la sesión
+func sessionBusy(id string) bool {
+	return Status.Get().InProgress && CurrentSessionID == id
+}
+
+// GetSession devuelve la información de una sesión
+func GetSession(id string) (Session, error) {
+	dir, err := SessionDir(id)
+	if err != nil {
+		return Session{}, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return Session{}, err
+	}
+	if !info.IsDir() {
+		return Session{}, fmt.Errorf("%s no es un directorio", dir)
+	}
+
+	s := Session{
+		ID:           id,
+		Path:         dir,
+		Created:      info.ModTime(),
+		LastModified: info.ModTime(),
+		InProgress:   sessionBusy(id),
+	}
+	if o, ok := GetOwner(id); ok {
+		s.Owner = o.User
+	}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if fi.ModTime().After(s.LastModified) {
+			s.LastModified = fi.ModTime()
+		}
+		if fi.ModTime().Before(s.Created) {
+			s.Created = fi.ModTime()
+		}
+		if !d.IsDir() {
+			s.Files++
+			s.Size += fi.Size()
+		}
+		return nil
+	})
+
+	if zi, err := os.Stat(GetBackupPath(id)); err == nil {
+		s.BackedUp = true
+		s.BackupTime = zi.ModTime()
+	}
+	return s, nil
+}
+
+// ListSessions devuelve las sesiones de UploadsDir, de la más nueva a la más vieja
+func ListSessions() ([]Session, error) {
+	entries, err := os.ReadDir(UploadsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []Session
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		s, err := GetSession(entry.Name())
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastModified.After(sessions[j].LastModified)
+	})
+	return sessions, nil
+}
+
+// SessionFiles lista los archivos de una sesión con rutas relativas
+func SessionFiles(id string) ([]SessionFile, error) {
+	dir, err := SessionDir(id)
+	if err != nil {
+		return nil, err
+	}
+	var files []SessionFile
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, SessionFile{Path: filepath.ToSlash(rel), Size: info.Size(), Modified: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+// ImportDir copia el contenido de srcDir a una sesión nueva y devuelve su ID
+func ImportDir(ctx context.Context, srcDir string) (string, error) {
+	info, err := os.Stat(srcDir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s no es un directorio", srcDir)
+	}
+
+	var files []string
+	err = filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Solo archivos regulares (se ignoran enlaces, sockets, etc.)
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	id := NewSessionID()
+	dest := filepath.Join(UploadsDir, id)
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return "", err
+	}
+
+	lg := logger.With("session_id", id)
+	for _, file := range files {
+		if ctx.Err() != nil {
+			os.RemoveAll(dest)
+			return "", ctx.Err()
+		}
+		rel, err := filepath.Rel(srcDir, file)
+		if err != nil {
+			os.RemoveAll(dest)
+			return "", err
+		}
+		target, err := safepath.Join(dest, filepath.ToSlash(rel))
+		if err != nil {
+			os.RemoveAll(dest)
+			return "", err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			os.RemoveAll(dest)
+			return "", err
+		}
+		if _, _, err := copyFileAndVerify(file, target); err != nil {
+			os.RemoveAll(dest)
+			return "", fmt.Errorf("importando %s: %w", rel, err)
+		}
+	}
+
+	lg.Info("Directorio importado como sesión", "source", srcDir, "files", len(files))
+	return id, nil
+}
+
+// RemoveSession borra el directorio de subida de una sesión.
+// Con withBackup también borra su ZIP, log, informe y dueño.
+func RemoveSession(id string, withBackup bool) error {
+	dir, err := SessionDir(id)
+	if err != nil {
+		return err
+	}
+	if sessionBusy(id) {
+		return fmt.Errorf("la sesión %s tiene un backup en curso", id)
+	}
+	if withBackup {
+		return CleanupSession(id)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	removeOwnerIfOrphan(id)
+	logger.Info("Sesión de subida borrada", "session_id", id)
+	return nil
+}
+
+// GCResult indica qué sesión se borró (o se borraría) y por qué
+type GCResult struct {
+	Session Session `json:"session"`
+	Reason  string  `json:"reason"` // "expired" o "backed_up"
+}
+
+// GCSessions borra las sesiones sin actividad durante más de ttl (0 = sin límite)
+// y las que ya tienen un backup posterior a su última modificación.
+// Con dryRun solo devuelve lo que borraría.
+func GCSessions(ttl time.Duration, dryRun bool) ([]GCResult, error) {
+	sessions, err := ListSessions()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result []GCResult
+	for _, s := range sessions {
+		reason := gcReason(s, ttl)
+		if reason == "" {
+			continue
+		}
+		if !dryRun {
+			var ok bool
+			if s, reason, ok = gcRemove(s.ID, ttl); !ok {
+				continue
+			}
+		}
+		result = append(result, GCResult{Session: s, Reason: reason})
+	}
+	return result, nil
+}
+
+// gcReason dice por qué GCSessions borraría la sesión ("" = no se borra)
+func gcReason(s Session, ttl time.Duration) string {
+	switch {
+	case s.InProgress:
+		return ""
+	case s.BackedUp && !s.BackupTime.Before(s.LastModified):
+		return "backed_up"
+	case ttl > 0 && time.Since(s.LastModified) > ttl:
+		return "expired"
+	}
+	return ""
+}
+
+// gcRemove borra la sesión con el lock de su dueño tomado, como AddToSession, y
+// volviendo a mirarla: si llegó un archivo desde el listado ya no se borra
+func gcRemove(id string, ttl time.Duration) (Session, string, bool) {
+	owner, _ := GetOwner(id)
+	defer lockUser(owner.User)()
+	s, err := GetSession(id)
+	if err != nil {
+		return s, "", false
+	}
+	reason := gcReason(s, ttl)
+	if reason == "" {
+		return s, "", false
+	}
+	if err := os.RemoveAll(s.Path); err != nil {
+		logger.Warn("GC: no se pudo borrar la sesión", "session_id", s.ID, "error", err)
+		return s, "", false
+	}
+	removeOwnerIfOrphan(s.ID)
+	logger.Info("GC: sesión borrada", "session_id", s.ID, "reason", reason,
+		"files", s.Files, "bytes", s.Size)
+	return s, reason, true
+}
+
+// StartSessionGC ejecuta GCSessions y GCChunkedUploads con ttl cada interval
+// hasta que se cancele ctx
+func StartSessionGC(ctx context.Context, interval, ttl time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := GCSessions(ttl, false); err != nil {
+				logger.Warn("GC de sesiones fallido", "error", err)
+			}
+			GCChunkedUploads(ttl)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
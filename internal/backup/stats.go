@@ -0,0 +1,264 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"gobackup/internal/config"
+	"gobackup/internal/history"
+	"gobackup/internal/logger"
+	"gobackup/internal/webhook"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cantidad máxima de errores incluidos en el resumen del snapshot
+const maxErrorSummary = 3
+
+// runStats acumula las estadísticas de una ejecución de backup
+type runStats struct {
+	start  time.Time
+	snap   history.Snapshot
+	files  []history.File
+	errs   []history.ErrorRecord
+	report Report
+
+	base    string   // ruta base del log y el informe del trabajo
+	logFile *os.File // log propio del trabajo (nil si no se pudo crear)
+}
+
+// newRunStats inicia las estadísticas; base es la ruta (sin extensión) del log y el informe
+func newRunStats(jobID, sessionID, backupType, base string) *runStats {
+	start := time.Now()
+	return &runStats{
+		start: start,
+		snap: history.Snapshot{
+			JobID:      jobID,
+			SessionID:  sessionID,
+			BackupType: backupType,
+		},
+		report: Report{
+			JobID:      jobID,
+			SessionID:  sessionID,
+			BackupType: backupType,
+			StartedAt:  start,
+			Phases:     make(map[string]float64),
+		},
+		base: base,
+	}
+}
+
+// capture abre el log propio del trabajo y devuelve lg escribiendo también en él.
+// Si no se puede crear el archivo el trabajo sigue con el log global únicamente.
+func (r *runStats) capture(lg *logger.Scoped) *logger.Scoped {
+	path := r.base + ".log"
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		lg.Warn("No se pudo crear el log del trabajo", "file", path, "error", err)
+		return lg
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		lg.Warn("No se pudo crear el log del trabajo", "file", path, "error", err)
+		return lg
+	}
+	r.logFile = f
+	r.report.LogFile = path
+	return lg.Tee(f)
+}
+
+// phase mide la duración de una fase; llamar a la función devuelta al terminarla
+func (r *runStats) phase(name string) func() {
+	start := time.Now()
+	return func() {
+		r.report.Phases[name] = time.Since(start).Seconds()
+	}
+}
+
+// addError registra un error asociado (opcionalmente) a un archivo
+func (r *runStats) addError(path string, err error) {
+	r.errs = append(r.errs, history.ErrorRecord{
+		Time:    time.Now(),
+		Path:    path,
+		Message: err.Error(),
+	})
+}
+
+// addCopyResult incorpora el resultado de CopyFilesConcurrent para un origen
+func (r *runStats) addCopyResult(src jobSource, res CopyResult) {
+	r.snap.FilesCount += res.Copied
+	r.snap.FilesFailed += len(res.Failed)
+	r.snap.BytesRead += res.BytesRead
+	for _, f := range res.Files {
+		r.report.Files = append(r.report.Files, ReportFile{
+			Path:   src.rel(f.Path),
+			Size:   f.Size,
+			SHA256: f.Checksum,
+		})
+	}
+	for _, f := range res.Failed {
+		relPath := src.rel(f.Path)
+		r.addError(relPath, f.Err)
+		r.report.Failures = append(r.report.Failures, ReportFailure{Path: relPath, Reason: f.Err.Error()})
+	}
+}
+
+// jobSource es un directorio de origen y la carpeta que ocupa dentro del archivo
+// ("" si el trabajo tiene un solo origen y su contenido va a la raíz)
+type jobSource struct {
+	Dir  string
+	Name string
+}
+
+// rel devuelve la ruta de un archivo del origen tal como queda en el archivo
+func (s jobSource) rel(path string) string {
+	return filepath.Join(s.Name, relOrAbs(s.Dir, path))
+}
+
+func relOrAbs(base, path string) string {
+	if rel, err := filepath.Rel(base, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// finish determina el estado final, calcula métricas derivadas y guarda el snapshot.
+// fatalErr es el error que abortó la ejecución (nil si llegó al final).
+func (r *runStats) finish(ctx context.Context, fatalErr error) history.Snapshot {
+	if fatalErr != nil && !isCancelled(ctx, fatalErr) {
+		r.addError("", fatalErr)
+	}
+
+	snap := &r.snap
+	snap.Timestamp = time.Now()
+	snap.Duration = time.Since(r.start).Seconds()
+
+	switch {
+	case isCancelled(ctx, fatalErr):
+		snap.Status = history.StatusCancelled
+	case fatalErr != nil:
+		snap.Status = history.StatusFailed
+	case snap.FilesFailed > 0 && snap.FilesCount == 0:
+		snap.Status = history.StatusFailed
+	case snap.FilesFailed > 0:
+		snap.Status = history.StatusPartial
+	default:
+		snap.Status = history.StatusSuccess
+	}
+
+	if snap.BytesRead > 0 {
+		snap.CompressionRatio = float64(snap.BytesWritten) / float64(snap.BytesRead)
+	}
+	if snap.Duration > 0 {
+		snap.Throughput = float64(snap.BytesRead) / snap.Duration
+	}
+	snap.ErrorSummary = summarizeErrors(r.errs)
+
+	logger.FromContext(ctx).Info("Trabajo finalizado", "status", snap.Status, "duration", snap.Duration)
+	if r.logFile != nil {
+		r.logFile.Close()
+		snap.LogPath = r.report.LogFile
+	}
+	if err := r.writeReport(fatalErr); err != nil {
+		logger.Error("Error guardando informe del trabajo", "job_id", snap.JobID, "error", err)
+	} else {
+		snap.ReportPath = r.base + ".report.json"
+	}
+
+	saveBackupStats(snap, r.files, r.errs)
+	logger.Info("Backup registrado en el historial",
+		"job_id", snap.JobID, "session_id", snap.SessionID, "status", snap.Status,
+		"files", snap.FilesCount, "files_failed", snap.FilesFailed, "files_skipped", snap.FilesSkipped,
+		"bytes_read", snap.BytesRead, "bytes_written", snap.BytesWritten, "duration", snap.Duration)
+	r.notify("job." + snap.Status)
+	return *snap
+}
+
+// notify avisa a los webhooks de un evento del trabajo (ver config.WebhookEvents).
+// Los que terminan llevan las estadísticas y los errores.
+func (r *runStats) notify(event string) {
+	p := webhook.Payload{
+		Event:      event,
+		JobID:      r.snap.JobID,
+		SessionID:  r.snap.SessionID,
+		Profile:    r.snap.Profile,
+		BackupType: r.snap.BackupType,
+	}
+	if event != config.WebhookJobStarted {
+		snap := r.snap
+		p.Status, p.Stats = snap.Status, &snap
+		p.Errors = webhook.ErrorMessages(r.errs)
+	}
+	webhook.Notify(p)
+}
+
+// writeReport completa el informe con los datos del snapshot y lo guarda
+func (r *runStats) writeReport(fatalErr error) error {
+	rep := &r.report
+	rep.Status = r.snap.Status
+	rep.FinishedAt = r.snap.Timestamp
+	rep.Duration = r.snap.Duration
+	rep.FilesProcessed = r.snap.FilesCount
+	rep.FilesSkipped = r.snap.FilesSkipped
+	rep.FilesFailed = r.snap.FilesFailed
+	rep.BytesRead = r.snap.BytesRead
+	rep.BytesWritten = r.snap.BytesWritten
+	if fatalErr != nil {
+		rep.Error = fatalErr.Error()
+	}
+
+	path := r.base + ".report.json"
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeReport(path, rep)
+}
+
+func isCancelled(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || err == context.Canceled
+}
+
+// summarizeErrors genera un texto corto con los primeros errores
+func summarizeErrors(errs []history.ErrorRecord) string {
+	if len(errs) == 0 {
+		return ""
+	}
+	var parts []string
+	for i, e := range errs {
+		if i == maxErrorSummary {
+			parts = append(parts, fmt.Sprintf("(+%d más)", len(errs)-maxErrorSummary))
+			break
+		}
+		if e.Path != "" {
+			parts = append(parts, e.Path+": "+e.Message)
+		} else {
+			parts = append(parts, e.Message)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// saveBackupStats guarda las estadísticas del backup en el historial
+func saveBackupStats(stats *history.Snapshot, fileStats []history.File, errs []history.ErrorRecord) error {
+	if err := history.Record(stats, fileStats, errs); err != nil {
+		logger.Error("Error guardando estadísticas", "job_id", stats.JobID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// collectFiles registra tamaño y fecha de los archivos seleccionados de un origen
+func (r *runStats) collectFiles(src jobSource, files []string) {
+	for _, filePath := range files {
+		info, err := os.Stat(filePath)
+		if err != nil {
+			continue
+		}
+		r.snap.TotalSize += info.Size()
+		r.files = append(r.files, history.File{
+			Path:     src.rel(filePath),
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+		})
+	}
+}
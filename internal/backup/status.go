@@ -8,6 +8,7 @@ type BackupStatus struct {
 	FilesCopied int
 	Errors      []string
 	InProgress  bool
+	Phase       string // scan, copy, compress
 }
 
 var Status = &BackupStatus{}
@@ -20,6 +21,30 @@ func (s *BackupStatus) Reset(total int) {
 	s.FilesCopied = 0
 	s.Errors = nil
 	s.InProgress = true
+	s.Phase = "copy"
+}
+
+// SetPhase indica la fase actual del backup en curso
+func (s *BackupStatus) SetPhase(phase string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Phase = phase
+}
+
+// TryStart marca un backup como en curso si no hay otro corriendo.
+// Devuelve false si ya había uno en progreso.
+func (s *BackupStatus) TryStart() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.InProgress {
+		return false
+	}
+	s.TotalFiles = 0
+	s.FilesCopied = 0
+	s.Errors = nil
+	s.InProgress = true
+	s.Phase = "scan"
+	return true
 }
 
 // IncrementFilesCopied aumenta el contador de archivos copiados
@@ -62,5 +87,6 @@ func (s *BackupStatus) Get() BackupStatus {
 		FilesCopied: s.FilesCopied,
 		Errors:      errorsCopy,
 		InProgress:  s.InProgress,
+		Phase:       s.Phase,
 	}
 }
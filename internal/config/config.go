@@ -1,10 +1,12 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
 )
 
 type Config struct {
@@ -19,62 +21,305 @@ type Config struct {
 	ModifiedMinutes int    `json:"modified_minutes"`
 	MaxConcurrency  int    `json:"max_concurrency"`
 	ServerPort      int    `json:"server_port"`
+
+	// Al apagar el servidor, espera máxima a los backups en curso antes de cancelarlos
+	ShutdownTimeoutSeconds int `json:"shutdown_timeout_seconds"`
+
+	// Limpieza de sesiones de subida
+	UploadTTLHours  int `json:"upload_ttl_hours"`           // borrar sesiones sin actividad (0 = nunca)
+	UploadGCMinutes int `json:"upload_gc_interval_minutes"` // cada cuánto corre el GC en el servidor (0 = desactivado)
+
+	Log LogConfig `json:"log"`
+
+	// Límites de subida y espacio por usuario
+	Quotas QuotaConfig `json:"quotas"`
+
+	// Límites de peticiones de la API web
+	RateLimit RateLimitConfig `json:"rate_limit"`
+
+	// Proxies (IPs o redes CIDR separadas por comas) cuyo X-Forwarded-For se cree.
+	// Vacío = ninguno: la IP del cliente es siempre la de la conexión.
+	TrustedProxies string `json:"trusted_proxies"`
+
+	// Inicio de sesión en el panel web
+	Auth AuthConfig `json:"auth"`
+
+	// Registro de auditoría (quién subió, descargó o borró qué), aparte del log
+	AuditFile string `json:"audit_file"`
+
+	// HTTPS en el servidor web
+	TLS TLSConfig `json:"tls"`
+
+	// Perfiles de backup con nombre (ver Profile y --profile)
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+
+	// Avisos a otros sistemas cuando empieza o termina un backup, por nombre
+	Webhooks map[string]WebhookConfig `json:"webhooks,omitempty"`
+
+	// Resultado de cada envío de los webhooks
+	WebhookLogFile string `json:"webhook_log_file"`
 }
 
-func LoadConfig(path string) (*Config, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+// DefaultProfile es el perfil que se usa si no se indica --profile. Si no está
+// definido en profiles se arma con los campos de primer nivel.
+const DefaultProfile = "default"
+
+// Profile es un trabajo de backup con nombre: qué respaldar, dónde y cómo
+type Profile struct {
+	Sources         []string         `json:"sources"`
+	Dest            string           `json:"dest"`             // vacío = backups_dir
+	Include         []string         `json:"include"`          // patrones glob; vacío = todo
+	Exclude         []string         `json:"exclude"`          // patrones glob sobre ruta relativa o nombre
+	ModifiedMinutes int              `json:"modified_minutes"` // 0 = todos los archivos
+	MaxConcurrency  int              `json:"max_concurrency"`  // 0 = max_concurrency global
+	Format          string           `json:"format"`           // zip, tar.gz o tar.zst
+	Encryption      EncryptionConfig `json:"encryption"`
+	Retention       RetentionConfig  `json:"retention"`
+}
+
+// EncryptionConfig cifra el archivo generado con una frase de paso
+type EncryptionConfig struct {
+	Enabled       bool   `json:"enabled"`
+	PassphraseEnv string `json:"passphrase_env"` // variable de entorno con la frase
+	KeyFile       string `json:"key_file"`       // o archivo que la contiene
+}
+
+// RetentionConfig indica qué archivos viejos del perfil se borran tras cada backup
+type RetentionConfig struct {
+	KeepLast   int `json:"keep_last"`    // conservar solo los N más recientes (0 = sin límite)
+	MaxAgeDays int `json:"max_age_days"` // borrar los más viejos (0 = sin límite)
+}
+
+// Profile devuelve el perfil con ese nombre (vacío = DefaultProfile) con los
+// valores heredados de la configuración global ya aplicados
+func (c *Config) Profile(name string) (Profile, error) {
+	if name == "" {
+		name = DefaultProfile
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		if name != DefaultProfile {
+			return Profile{}, fmt.Errorf("perfil desconocido %q (disponibles: %s)", name, strings.Join(c.ProfileNames(), ", "))
+		}
+		// Perfil implícito con los campos de primer nivel (modo legacy)
+		p = Profile{ModifiedMinutes: c.ModifiedMinutes, Dest: c.BackupDir}
+		if c.SourceDir != "" {
+			p.Sources = []string{c.SourceDir}
+		}
+	}
+	if p.Dest == "" {
+		p.Dest = c.BackupsDir
+	}
+	if p.MaxConcurrency <= 0 {
+		p.MaxConcurrency = c.MaxConcurrency
+	}
+	if p.Format == "" {
+		p.Format = "zip"
 	}
-	defer file.Close()
+	return p, nil
+}
 
-	bytes, err := ioutil.ReadAll(file)
-	if err != nil {
-		return nil, err
+// ProfileNames lista los perfiles disponibles, incluido el implícito
+func (c *Config) ProfileNames() []string {
+	names := []string{}
+	if _, ok := c.Profiles[DefaultProfile]; !ok {
+		names = append(names, DefaultProfile)
 	}
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Eventos de los webhooks
+const (
+	WebhookJobStarted   = "job.started"
+	WebhookJobSuccess   = "job.success"
+	WebhookJobPartial   = "job.partial"
+	WebhookJobFailed    = "job.failed"
+	WebhookJobCancelled = "job.cancelled"
+)
+
+// WebhookEvents son los eventos a los que se puede suscribir un webhook
+var WebhookEvents = []string{WebhookJobStarted, WebhookJobSuccess, WebhookJobPartial, WebhookJobFailed, WebhookJobCancelled}
+
+// WebhookConfig es una URL a la que se manda un POST con cada evento de los backups
+type WebhookConfig struct {
+	URL            string   `json:"url"`
+	Events         []string `json:"events"`          // vacío = todos (ver WebhookEvents)
+	Format         string   `json:"format"`          // json (por defecto), slack, teams o discord
+	SecretEnv      string   `json:"secret_env"`      // variable de entorno con la clave HMAC de la firma
+	SecretFile     string   `json:"secret_file"`     // o archivo que la contiene (sin ninguna no se firma)
+	TimeoutSeconds int      `json:"timeout_seconds"` // de cada intento (0 = 10)
+	MaxAttempts    int      `json:"max_attempts"`    // intentos antes de darlo por fallido (0 = 5)
+}
+
+// LogConfig configura el logger: nivel, formato, rotación y retención
+type LogConfig struct {
+	Dir         string `json:"dir"`
+	Level       string `json:"level"`        // debug, info, warn, error
+	Format      string `json:"format"`       // text o json
+	MaxSizeMB   int    `json:"max_size_mb"`  // rotar al superar este tamaño
+	MaxAgeDays  int    `json:"max_age_days"` // borrar archivos rotados más viejos
+	MaxBackups  int    `json:"max_backups"`  // archivos rotados a conservar
+	RotateHours int    `json:"rotate_hours"` // rotar periódicamente (0 = solo por tamaño)
+	Compress    bool   `json:"compress"`     // comprimir archivos rotados con gzip
+}
+
+// AuthConfig configura los usuarios y sesiones del panel web
+type AuthConfig struct {
+	Enabled      bool   `json:"enabled"`       // false = panel abierto a cualquiera (solo en redes de confianza)
+	UsersFile    string `json:"users_file"`    // usuarios con hash bcrypt (ver gobackup user)
+	TokensFile   string `json:"tokens_file"`   // tokens de API con hash SHA-256 (ver gobackup token)
+	SessionHours int    `json:"session_hours"` // duración de una sesión iniciada
+}
+
+// TLSConfig configura HTTPS en el servidor web (en server_port)
+type TLSConfig struct {
+	Enabled        bool   `json:"enabled"`
+	CertFile       string `json:"cert_file"`         // si no existen cert y key se genera uno autofirmado
+	KeyFile        string `json:"key_file"`          // clave privada en PEM
+	MinVersion     string `json:"min_version"`       // 1.2 o 1.3
+	ClientCAFile   string `json:"client_ca_file"`    // CA de los certificados de cliente (vacío = no se aceptan)
+	RedirectPort   int    `json:"redirect_port"`     // puerto HTTP que redirige a HTTPS (0 = ninguno)
+	HSTSMaxAgeDays int    `json:"hsts_max_age_days"` // Strict-Transport-Security (0 = no se manda)
+}
+
+// QuotaConfig limita lo que se puede subir desde la web. 0 = sin límite.
+type QuotaConfig struct {
+	MaxFileMB       int `json:"max_file_mb"`         // tamaño de cada archivo
+	MaxSessionMB    int `json:"max_session_mb"`      // total de una sesión de subida
+	MaxSessionFiles int `json:"max_session_files"`   // archivos por sesión
+	UserQuotaMB     int `json:"user_quota_mb"`       // subidas y backups de cada usuario
+	MinFreeMB       int `json:"min_free_mb"`         // espacio libre que se reserva en el disco de uploads_dir
+	MaxPending      int `json:"max_pending_uploads"` // subidas por partes sin terminar de cada usuario
+}
 
-	var cfg Config
-	if err := json.Unmarshal(bytes, &cfg); err != nil {
-		return nil, err
+// RateLimitConfig protege al servidor de un cliente que lo satura. 0 = sin límite.
+type RateLimitConfig struct {
+	IPPerMinute    int `json:"ip_per_minute"`    // peticiones por minuto de cada IP
+	TokenPerMinute int `json:"token_per_minute"` // de cada token de API o usuario con sesión
+	LoginPerMinute int `json:"login_per_minute"` // intentos de inicio de sesión por IP
+	MaxBodyKB      int `json:"max_body_kb"`      // cuerpo de las peticiones que no son subidas
+	MaxChunkMB     int `json:"max_chunk_mb"`     // cada fragmento de una subida por partes
+	MaxExpensive   int `json:"max_expensive"`    // estadísticas, explorador y logs atendidos a la vez
+	MaxUploads     int `json:"max_uploads"`      // subidas y descargas atendidas a la vez
+}
+
+// TrustedProxyList separa trusted_proxies en una lista (nil si está vacío)
+func (c *Config) TrustedProxyList() []string {
+	var list []string
+	for _, p := range strings.Split(c.TrustedProxies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			list = append(list, p)
+		}
 	}
+	return list
+}
 
-	// MANTENER COMPATIBILIDAD: Si usan los campos viejos, mapear a los nuevos
-	if cfg.SourceDir != "" && cfg.UploadsDir == "" {
-		cfg.UploadsDir = cfg.SourceDir
+// DefaultLogConfig devuelve la configuración de logs por defecto
+func DefaultLogConfig() LogConfig {
+	return LogConfig{
+		Dir:         "logs",
+		Level:       "info",
+		Format:      "text",
+		MaxSizeMB:   10,
+		MaxAgeDays:  30,
+		MaxBackups:  10,
+		RotateHours: 24,
+		Compress:    true,
 	}
-	if cfg.BackupDir != "" && cfg.BackupsDir == "" {
-		cfg.BackupsDir = cfg.BackupDir
+}
+
+// Defaults devuelve la configuración por defecto, la capa más baja
+func Defaults() Config {
+	return Config{
+		UploadsDir:             "uploads",
+		BackupsDir:             "backups",
+		TempDir:                "temp",
+		MaxConcurrency:         5,
+		ServerPort:             8080,
+		ShutdownTimeoutSeconds: 30,
+		UploadTTLHours:         72,
+		UploadGCMinutes:        60,
+		Log:                    DefaultLogConfig(),
+		Quotas:                 QuotaConfig{MaxFileMB: 100, MinFreeMB: 512, MaxPending: 10},
+		RateLimit:              RateLimitConfig{IPPerMinute: 300, TokenPerMinute: 600, LoginPerMinute: 10, MaxBodyKB: 1024, MaxChunkMB: 64, MaxExpensive: 4, MaxUploads: 8},
+		AuditFile:              "config/audit.jsonl",
+		WebhookLogFile:         "config/webhook_deliveries.jsonl",
+		Auth:                   AuthConfig{Enabled: true, UsersFile: "config/users.json", TokensFile: "config/tokens.json", SessionHours: 12},
+		TLS:                    TLSConfig{CertFile: "config/tls/cert.pem", KeyFile: "config/tls/key.pem", MinVersion: "1.2", HSTSMaxAgeDays: 180},
 	}
+}
 
-	// Establecer valores por defecto para nuevos campos
-	if cfg.UploadsDir == "" {
-		cfg.UploadsDir = "uploads"
+// LoadConfig carga la configuración de un archivo sin flags (ver Load)
+func LoadConfig(path string) (*Config, error) {
+	cfg, _, err := Load(path, nil)
+	return cfg, err
+}
+
+// Load arma la configuración por capas: valores por defecto < archivo (JSON, YAML
+// o TOML) < variables GOBACKUP_* < flags. Con path vacío no se lee ningún archivo.
+// Valida el resultado, crea los directorios y devuelve el origen de cada valor.
+func Load(path string, flags *pflag.FlagSet) (*Config, Origins, error) {
+	cfg, origins, err := Check(path, flags, true)
+	if err != nil {
+		return nil, nil, err
 	}
-	if cfg.BackupsDir == "" {
-		cfg.BackupsDir = "backups"
+
+	// Crear directorios si no existen
+	var problems []Problem
+	for field, dir := range map[string]string{
+		"uploads_dir": cfg.UploadsDir,
+		"backups_dir": cfg.BackupsDir,
+		"temp_dir":    cfg.TempDir,
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			problems = append(problems, Problem{Field: field, Origin: origins[field], Message: err.Error()})
+		}
 	}
-	if cfg.TempDir == "" {
-		cfg.TempDir = "temp"
+	if len(problems) > 0 {
+		return nil, nil, newValidationError(problems)
 	}
-	if cfg.MaxConcurrency <= 0 {
-		cfg.MaxConcurrency = 5
+	return cfg, origins, nil
+}
+
+// Check combina las capas como Load y valida el resultado sin crear nada.
+// Todos los problemas se devuelven juntos en un *ValidationError.
+func Check(path string, flags *pflag.FlagSet, checkDirs bool) (*Config, Origins, error) {
+	cfg := Defaults()
+	origins := make(Origins)
+	for _, f := range Fields() {
+		origins[f.Key] = OriginDefault
 	}
-	if cfg.ServerPort == 0 {
-		cfg.ServerPort = 8080
+
+	var problems []Problem
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		problems = append(problems, applyFile(&cfg, values, origins)...)
 	}
-	if cfg.ModifiedMinutes < 0 {
-		cfg.ModifiedMinutes = 0
+	problems = append(problems, applyEnv(&cfg, origins)...)
+	if err := applyFlags(&cfg, flags, origins); err != nil {
+		return nil, nil, err
 	}
 
-	// Validaciones
-	if cfg.BackupsDir == "" {
-		return nil, fmt.Errorf("backups_dir no puede estar vacío")
+	// MANTENER COMPATIBILIDAD: Si usan los campos viejos, mapear a los nuevos
+	if cfg.SourceDir != "" && origins["uploads_dir"] == OriginDefault {
+		cfg.UploadsDir = cfg.SourceDir
+		origins["uploads_dir"] = origins["source_dir"]
+	}
+	if cfg.BackupDir != "" && origins["backups_dir"] == OriginDefault {
+		cfg.BackupsDir = cfg.BackupDir
+		origins["backups_dir"] = origins["backup_dir"]
 	}
 
-	// Crear directorios si no existen
-	os.MkdirAll(cfg.UploadsDir, 0755)
-	os.MkdirAll(cfg.BackupsDir, 0755)
-	os.MkdirAll(cfg.TempDir, 0755)
-
-	return &cfg, nil
+	problems = append(problems, validate(&cfg, origins, checkDirs)...)
+	if len(problems) > 0 {
+		return nil, nil, newValidationError(problems)
+	}
+	return &cfg, origins, nil
 }
@@ -0,0 +1,309 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Orígenes posibles de un valor, de menor a mayor prioridad
+const (
+	OriginDefault = "default"
+	OriginFile    = "file"
+	OriginEnv     = "env"
+	OriginFlag    = "flag"
+)
+
+// EnvPrefix es el prefijo de las variables de entorno (GOBACKUP_LOG_LEVEL -> log.level)
+const EnvPrefix = "GOBACKUP_"
+
+// Origins indica de dónde salió cada valor de la configuración, por clave ("log.level")
+type Origins map[string]string
+
+// Field es un campo configurable de Config
+type Field struct {
+	Key   string // clave con puntos: "log.max_size_mb"
+	Env   string // GOBACKUP_LOG_MAX_SIZE_MB
+	Flag  string // log-max-size-mb
+	index []int
+	kind  reflect.Kind
+}
+
+// Fields lista todos los campos configurables, en el orden del struct
+func Fields() []Field {
+	return collectFields(reflect.TypeOf(Config{}), "", nil)
+}
+
+func collectFields(t reflect.Type, prefix string, index []int) []Field {
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+		idx := append(append([]int{}, index...), i)
+		if sf.Type.Kind() == reflect.Map {
+			continue // profiles y webhooks se leen aparte (ver applySection)
+		}
+		if sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, collectFields(sf.Type, key+".", idx)...)
+			continue
+		}
+		fields = append(fields, Field{
+			Key:   key,
+			Env:   EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_")),
+			Flag:  strings.NewReplacer(".", "-", "_", "-").Replace(key),
+			index: idx,
+			kind:  sf.Type.Kind(),
+		})
+	}
+	return fields
+}
+
+// Value devuelve el valor del campo en cfg
+func (f Field) Value(cfg *Config) interface{} {
+	return reflect.ValueOf(cfg).Elem().FieldByIndex(f.index).Interface()
+}
+
+func (f Field) ptr(cfg *Config) interface{} {
+	return reflect.ValueOf(cfg).Elem().FieldByIndex(f.index).Addr().Interface()
+}
+
+// set asigna un valor en texto (de una variable de entorno o un flag)
+func (f Field) set(cfg *Config, s string) error {
+	v := reflect.ValueOf(cfg).Elem().FieldByIndex(f.index)
+	switch f.kind {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("se esperaba un número entero, no %q", s)
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("se esperaba true o false, no %q", s)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("tipo no soportado %s", f.kind)
+	}
+	return nil
+}
+
+// BindFlags registra un flag por cada campo de Config (--server-port, --log-level, ...)
+func BindFlags(fs *pflag.FlagSet) {
+	for _, f := range Fields() {
+		usage := "Override " + f.Key + " (env " + f.Env + ")"
+		switch f.kind {
+		case reflect.Int:
+			fs.Int(f.Flag, 0, usage)
+		case reflect.Bool:
+			fs.Bool(f.Flag, false, usage)
+		default:
+			fs.String(f.Flag, "", usage)
+		}
+	}
+}
+
+// readFile lee un archivo de configuración JSON, YAML o TOML según su extensión.
+// Devuelve el contenido como mapa para saber qué claves define.
+func readFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("formato de configuración no soportado %q (use .json, .yaml o .toml)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// applyFile vuelca el mapa leído sobre cfg campo por campo y marca el origen de
+// cada clave. Las claves desconocidas y los tipos incorrectos se devuelven como
+// problemas para informarlos todos juntos.
+func applyFile(cfg *Config, values map[string]interface{}, origins Origins) []Problem {
+	var problems []Problem
+	fields := make(map[string]Field)
+	for _, f := range Fields() {
+		fields[f.Key] = f
+	}
+
+	if raw, ok := values["profiles"]; ok {
+		var p []Problem
+		cfg.Profiles, p = applySection[Profile]("profiles", raw, "se esperaba una sección con un perfil por nombre")
+		problems = append(problems, p...)
+		origins["profiles"] = OriginFile
+		delete(values, "profiles")
+	}
+	if raw, ok := values["webhooks"]; ok {
+		var p []Problem
+		cfg.Webhooks, p = applySection[WebhookConfig]("webhooks", raw, "se esperaba una sección con un webhook por nombre")
+		problems = append(problems, p...)
+		origins["webhooks"] = OriginFile
+		delete(values, "webhooks")
+	}
+
+	for _, key := range flattenKeys(values, "") {
+		f, ok := fields[key]
+		if !ok {
+			problems = append(problems, unknownField(key))
+			continue
+		}
+		// Pasar por JSON para usar las mismas etiquetas en los tres formatos
+		data, err := json.Marshal(lookup(values, key))
+		if err == nil {
+			err = json.Unmarshal(data, f.ptr(cfg))
+		}
+		if err != nil {
+			problems = append(problems, Problem{Field: key, Origin: OriginFile,
+				Message: fmt.Sprintf("se esperaba un valor de tipo %s", kindName(f.kind))})
+			continue
+		}
+		origins[key] = OriginFile
+	}
+	return problems
+}
+
+// applySection lee una sección con un elemento por nombre (profiles, webhooks)
+// rechazando campos desconocidos
+func applySection[T any](key string, raw interface{}, expected string) (map[string]T, []Problem) {
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, []Problem{{Field: key, Origin: OriginFile, Message: expected}}
+	}
+
+	var problems []Problem
+	items := make(map[string]T)
+	for name, v := range section {
+		field := key + "." + name
+		data, err := json.Marshal(v)
+		if err != nil {
+			problems = append(problems, Problem{Field: field, Origin: OriginFile, Message: err.Error()})
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			problems = append(problems, Problem{Field: field, Origin: OriginFile, Message: profileDecodeError(err)})
+			continue
+		}
+		items[name] = item
+	}
+	return items, problems
+}
+
+// profileDecodeError traduce los errores de encoding/json a algo accionable
+func profileDecodeError(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Sprintf("%s: se esperaba un valor de tipo %s", typeErr.Field, typeErr.Type)
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return "campo desconocido " + field
+	}
+	return err.Error()
+}
+
+// lookup devuelve el valor de una clave con puntos en un mapa anidado
+func lookup(values map[string]interface{}, key string) interface{} {
+	parts := strings.Split(key, ".")
+	for _, p := range parts[:len(parts)-1] {
+		values, _ = values[p].(map[string]interface{})
+	}
+	return values[parts[len(parts)-1]]
+}
+
+func kindName(k reflect.Kind) string {
+	switch k {
+	case reflect.Int:
+		return "entero"
+	case reflect.Bool:
+		return "booleano"
+	}
+	return "texto"
+}
+
+// flattenKeys devuelve las claves de un mapa anidado con puntos ("log.level")
+func flattenKeys(values map[string]interface{}, prefix string) []string {
+	var keys []string
+	for k, v := range values {
+		if sub, ok := v.(map[string]interface{}); ok {
+			keys = append(keys, flattenKeys(sub, prefix+k+".")...)
+			continue
+		}
+		keys = append(keys, prefix+k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// applyEnv aplica las variables GOBACKUP_* definidas
+func applyEnv(cfg *Config, origins Origins) []Problem {
+	var problems []Problem
+	for _, f := range Fields() {
+		s, ok := os.LookupEnv(f.Env)
+		if !ok {
+			continue
+		}
+		if err := f.set(cfg, s); err != nil {
+			problems = append(problems, Problem{Field: f.Key, Origin: OriginEnv, Message: err.Error()})
+			continue
+		}
+		origins[f.Key] = OriginEnv
+	}
+	return problems
+}
+
+// applyFlags aplica los flags cambiados en la línea de comandos.
+// --port es un atajo de --server-port.
+func applyFlags(cfg *Config, fs *pflag.FlagSet, origins Origins) error {
+	if fs == nil {
+		return nil
+	}
+	for _, f := range Fields() {
+		names := []string{f.Flag}
+		if f.Key == "server_port" {
+			names = append(names, "port")
+		}
+		for _, name := range names {
+			flag := fs.Lookup(name)
+			if flag == nil || !flag.Changed {
+				continue
+			}
+			if err := f.set(cfg, flag.Value.String()); err != nil {
+				return fmt.Errorf("--%s: %w", name, err)
+			}
+			origins[f.Key] = OriginFlag
+		}
+	}
+	return nil
+}
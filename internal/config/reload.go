@@ -0,0 +1,200 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// RestartKeys son los campos que no se pueden cambiar con el servidor andando:
+// el puerto, la espera al apagar, los directorios (el historial y las sesiones
+// viven ahí), los archivos de log, auditoría y envíos de webhooks, el inicio de
+// sesión, los proxies de confianza y HTTPS. Si cambian se conserva el valor
+// anterior hasta reiniciar.
+var RestartKeys = []string{
+	"server_port", "shutdown_timeout_seconds", "uploads_dir", "backups_dir", "temp_dir",
+	"audit_file", "webhook_log_file", "trusted_proxies",
+	"log.dir", "log.max_size_mb", "log.max_age_days", "log.max_backups", "log.rotate_hours", "log.compress",
+	"auth.enabled", "auth.users_file", "auth.tokens_file", "auth.session_hours",
+	"tls.enabled", "tls.cert_file", "tls.key_file", "tls.min_version", "tls.client_ca_file",
+	"tls.redirect_port", "tls.hsts_max_age_days",
+}
+
+// ReloadInfo es el estado de la configuración activa (ver /api/system)
+type ReloadInfo struct {
+	File           string    `json:"file"`
+	Revision       int       `json:"revision"`        // sube con cada recarga aplicada
+	Hash           string    `json:"hash"`            // sha256 del archivo aplicado
+	LoadedAt       time.Time `json:"loaded_at"`       // cuándo se aplicó
+	LastAttempt    time.Time `json:"last_attempt"`    // último intento de recarga
+	LastError      string    `json:"last_error"`      // vacío si el último intento salió bien
+	PendingRestart []string  `json:"pending_restart"` // cambios que esperan un reinicio
+}
+
+// Watcher vuelve a cargar la configuración cuando cambia el archivo o llega
+// SIGHUP. Si la nueva versión es inválida se sigue usando la anterior.
+type Watcher struct {
+	path    string
+	flags   *pflag.FlagSet
+	apply   func(cfg *Config, origins Origins) error
+	current *Config
+
+	mu   sync.Mutex
+	info ReloadInfo
+}
+
+// NewWatcher arma un Watcher sobre la configuración ya aplicada cfg. apply
+// recibe cada versión nueva válida; si devuelve error la recarga se descarta.
+func NewWatcher(path string, flags *pflag.FlagSet, cfg *Config, apply func(*Config, Origins) error) *Watcher {
+	hash, _ := fileHash(path)
+	return &Watcher{
+		path:    path,
+		flags:   flags,
+		apply:   apply,
+		current: cfg,
+		info: ReloadInfo{
+			File:           path,
+			Revision:       1,
+			Hash:           hash,
+			LoadedAt:       time.Now(),
+			PendingRestart: []string{},
+		},
+	}
+}
+
+// Info devuelve el estado actual
+func (w *Watcher) Info() ReloadInfo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	info := w.info
+	info.PendingRestart = append([]string{}, w.info.PendingRestart...)
+	return info
+}
+
+// Run revisa el archivo cada interval y atiende SIGHUP hasta que se cancele ctx.
+// onReload se llama después de cada intento con su resultado (para loguearlo).
+func (w *Watcher) Run(ctx context.Context, interval time.Duration, onReload func(changed []string, err error)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if w.path != "" && interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			changed, err := w.Reload()
+			onReload(changed, err)
+		case <-tick:
+			// Solo recargar si el contenido cambió (guardar sin cambios no cuenta)
+			hash, err := fileHash(w.path)
+			if err != nil || hash == w.Info().Hash {
+				continue
+			}
+			changed, err := w.Reload()
+			onReload(changed, err)
+		}
+	}
+}
+
+// Reload lee y valida la configuración y, si es válida, la aplica. Devuelve las
+// claves que cambiaron.
+func (w *Watcher) Reload() ([]string, error) {
+	hash, _ := fileHash(w.path)
+	changed, pending, err := w.reload()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.info.LastAttempt = time.Now()
+	w.info.Hash = hash // no reintentar en cada vuelta un archivo que ya falló
+	if err != nil {
+		w.info.LastError = err.Error()
+		return nil, err
+	}
+	w.info.LastError = ""
+	w.info.PendingRestart = pending
+	if len(changed) > 0 {
+		w.info.Revision++
+		w.info.LoadedAt = w.info.LastAttempt
+	}
+	return changed, nil
+}
+
+func (w *Watcher) reload() (changed, pending []string, err error) {
+	cfg, origins, err := Load(w.path, w.flags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Los campos que requieren reinicio conservan el valor en uso
+	restart := make(map[string]bool)
+	for _, key := range RestartKeys {
+		restart[key] = true
+	}
+	for _, f := range Fields() {
+		if !restart[f.Key] {
+			continue
+		}
+		if !reflect.DeepEqual(f.Value(cfg), f.Value(w.current)) {
+			pending = append(pending, f.Key)
+		}
+		reflect.ValueOf(cfg).Elem().FieldByIndex(f.index).Set(reflect.ValueOf(f.Value(w.current)))
+	}
+
+	changed = Diff(w.current, cfg)
+	if len(changed) == 0 {
+		return nil, pending, nil
+	}
+	if err := w.apply(cfg, origins); err != nil {
+		return nil, nil, err
+	}
+	w.current = cfg
+	return changed, pending, nil
+}
+
+// Diff devuelve las claves con distinto valor entre a y b ("profiles" o
+// "webhooks" si cambió alguno)
+func Diff(a, b *Config) []string {
+	var keys []string
+	for _, f := range Fields() {
+		if !reflect.DeepEqual(f.Value(a), f.Value(b)) {
+			keys = append(keys, f.Key)
+		}
+	}
+	if !reflect.DeepEqual(a.Profiles, b.Profiles) {
+		keys = append(keys, "profiles")
+	}
+	if !reflect.DeepEqual(a.Webhooks, b.Webhooks) {
+		keys = append(keys, "webhooks")
+	}
+	return keys
+}
+
+// fileHash devuelve el sha256 del archivo (vacío si no hay archivo)
+func fileHash(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("leyendo %s: %w", path, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
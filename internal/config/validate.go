@@ -0,0 +1,431 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Problem es un error de configuración en un campo concreto
+type Problem struct {
+	Field   string `json:"field"`
+	Origin  string `json:"origin"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	where := p.Field
+	switch p.Origin {
+	case OriginEnv:
+		where += " (" + EnvPrefix + strings.ToUpper(strings.ReplaceAll(p.Field, ".", "_")) + ")"
+	case OriginFlag:
+		where += " (--" + strings.NewReplacer(".", "-", "_", "-").Replace(p.Field) + ")"
+	}
+	return where + ": " + p.Message
+}
+
+// ValidationError reúne todos los problemas encontrados en la configuración
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = "  - " + p.String()
+	}
+	return fmt.Sprintf("configuración inválida (%d problema(s)):\n%s", len(e.Problems), strings.Join(lines, "\n"))
+}
+
+// newValidationError ordena los problemas por campo
+func newValidationError(problems []Problem) *ValidationError {
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Field < problems[j].Field })
+	return &ValidationError{Problems: problems}
+}
+
+// validate revisa los valores ya combinados. Con checkDirs también comprueba que
+// los directorios se puedan escribir (o crear), sin crearlos.
+func validate(cfg *Config, origins Origins, checkDirs bool) []Problem {
+	var problems []Problem
+	add := func(field, format string, args ...interface{}) {
+		problems = append(problems, Problem{Field: field, Origin: origins[field], Message: fmt.Sprintf(format, args...)})
+	}
+
+	dirs := []struct {
+		field string
+		path  string
+	}{
+		{"uploads_dir", cfg.UploadsDir},
+		{"backups_dir", cfg.BackupsDir},
+		{"temp_dir", cfg.TempDir},
+		{"log.dir", cfg.Log.Dir},
+	}
+	for _, d := range dirs {
+		if strings.TrimSpace(d.path) == "" {
+			add(d.field, "no puede estar vacío")
+			continue
+		}
+		if checkDirs {
+			if err := checkWritable(d.path); err != nil {
+				add(d.field, "%v", err)
+			}
+		}
+	}
+	if cfg.SourceDir != "" && checkDirs {
+		if info, err := os.Stat(cfg.SourceDir); err != nil {
+			add("source_dir", "no existe: %s", cfg.SourceDir)
+		} else if !info.IsDir() {
+			add("source_dir", "no es un directorio: %s", cfg.SourceDir)
+		}
+	}
+
+	// Un backup dentro de su propio origen se copiaría a sí mismo en cada ejecución
+	if cfg.UploadsDir != "" && cfg.BackupsDir != "" && Overlaps(cfg.UploadsDir, cfg.BackupsDir) {
+		add("backups_dir", "no puede estar dentro de uploads_dir (%s): el backup se copiaría a sí mismo", cfg.UploadsDir)
+	}
+	if cfg.SourceDir != "" && cfg.BackupDir != "" && Overlaps(cfg.SourceDir, cfg.BackupDir) &&
+		(cfg.SourceDir != cfg.UploadsDir || cfg.BackupDir != cfg.BackupsDir) {
+		add("backup_dir", "no puede estar dentro de source_dir (%s): el backup se copiaría a sí mismo", cfg.SourceDir)
+	}
+
+	if cfg.ServerPort < 1 || cfg.ServerPort > 65535 {
+		add("server_port", "%d fuera de rango (1-65535)", cfg.ServerPort)
+	}
+	if cfg.MaxConcurrency < 1 {
+		add("max_concurrency", "debe ser al menos 1, no %d", cfg.MaxConcurrency)
+	}
+	if cfg.ShutdownTimeoutSeconds < 0 {
+		add("shutdown_timeout_seconds", "no puede ser negativo (0 = cancelar los backups en curso sin esperar)")
+	}
+	if cfg.ModifiedMinutes < 0 {
+		add("modified_minutes", "no puede ser negativo (0 = todos los archivos)")
+	}
+	if cfg.UploadTTLHours < 0 {
+		add("upload_ttl_hours", "no puede ser negativo (0 = nunca vencen)")
+	}
+	if cfg.UploadGCMinutes < 0 {
+		add("upload_gc_interval_minutes", "no puede ser negativo (0 = desactivado)")
+	}
+
+	for _, q := range []struct {
+		key   string
+		value int
+	}{
+		{"quotas.max_file_mb", cfg.Quotas.MaxFileMB},
+		{"quotas.max_session_mb", cfg.Quotas.MaxSessionMB},
+		{"quotas.max_session_files", cfg.Quotas.MaxSessionFiles},
+		{"quotas.user_quota_mb", cfg.Quotas.UserQuotaMB},
+		{"quotas.min_free_mb", cfg.Quotas.MinFreeMB},
+		{"quotas.max_pending_uploads", cfg.Quotas.MaxPending},
+		{"rate_limit.ip_per_minute", cfg.RateLimit.IPPerMinute},
+		{"rate_limit.token_per_minute", cfg.RateLimit.TokenPerMinute},
+		{"rate_limit.login_per_minute", cfg.RateLimit.LoginPerMinute},
+		{"rate_limit.max_body_kb", cfg.RateLimit.MaxBodyKB},
+		{"rate_limit.max_chunk_mb", cfg.RateLimit.MaxChunkMB},
+		{"rate_limit.max_expensive", cfg.RateLimit.MaxExpensive},
+		{"rate_limit.max_uploads", cfg.RateLimit.MaxUploads},
+	} {
+		if q.value < 0 {
+			add(q.key, "no puede ser negativo (0 = sin límite)")
+		}
+	}
+
+	for _, proxy := range cfg.TrustedProxyList() {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			add("trusted_proxies", "%q no es una IP ni una red CIDR", proxy)
+		}
+	}
+
+	switch strings.ToLower(cfg.Log.Level) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		add("log.level", "nivel desconocido %q (use debug, info, warn o error)", cfg.Log.Level)
+	}
+	if cfg.Log.Format != "text" && cfg.Log.Format != "json" {
+		add("log.format", "formato desconocido %q (use text o json)", cfg.Log.Format)
+	}
+	if cfg.TLS.Enabled {
+		if strings.TrimSpace(cfg.TLS.CertFile) == "" {
+			add("tls.cert_file", "no puede estar vacío con tls.enabled")
+		}
+		if strings.TrimSpace(cfg.TLS.KeyFile) == "" {
+			add("tls.key_file", "no puede estar vacío con tls.enabled")
+		}
+		if cfg.TLS.ClientCAFile != "" {
+			if _, err := os.Stat(cfg.TLS.ClientCAFile); err != nil {
+				add("tls.client_ca_file", "no existe: %s", cfg.TLS.ClientCAFile)
+			}
+		}
+		if cfg.TLS.RedirectPort == cfg.ServerPort {
+			add("tls.redirect_port", "no puede ser igual a server_port (%d)", cfg.ServerPort)
+		}
+	}
+	if cfg.TLS.MinVersion != "1.2" && cfg.TLS.MinVersion != "1.3" {
+		add("tls.min_version", "versión desconocida %q (use 1.2 o 1.3)", cfg.TLS.MinVersion)
+	}
+	if cfg.TLS.RedirectPort < 0 || cfg.TLS.RedirectPort > 65535 {
+		add("tls.redirect_port", "%d fuera de rango (0-65535, 0 = sin redirección)", cfg.TLS.RedirectPort)
+	}
+	if cfg.TLS.HSTSMaxAgeDays < 0 {
+		add("tls.hsts_max_age_days", "no puede ser negativo (0 = sin HSTS)")
+	}
+	if strings.TrimSpace(cfg.AuditFile) == "" {
+		add("audit_file", "no puede estar vacío")
+	}
+	if cfg.Auth.Enabled && strings.TrimSpace(cfg.Auth.UsersFile) == "" {
+		add("auth.users_file", "no puede estar vacío con auth.enabled")
+	}
+	if cfg.Auth.Enabled && strings.TrimSpace(cfg.Auth.TokensFile) == "" {
+		add("auth.tokens_file", "no puede estar vacío con auth.enabled")
+	}
+	if cfg.Auth.SessionHours < 1 {
+		add("auth.session_hours", "debe ser al menos 1, no %d", cfg.Auth.SessionHours)
+	}
+	for _, name := range cfg.ProfileNames() {
+		problems = append(problems, validateProfile(cfg, name, origins, checkDirs)...)
+	}
+	if len(cfg.Webhooks) > 0 && strings.TrimSpace(cfg.WebhookLogFile) == "" {
+		add("webhook_log_file", "no puede estar vacío si hay webhooks")
+	}
+	for name, w := range cfg.Webhooks {
+		problems = append(problems, validateWebhook(name, w, origins["webhooks"], checkDirs)...)
+	}
+
+	for field, v := range map[string]int{
+		"log.max_size_mb":  cfg.Log.MaxSizeMB,
+		"log.max_age_days": cfg.Log.MaxAgeDays,
+		"log.max_backups":  cfg.Log.MaxBackups,
+		"log.rotate_hours": cfg.Log.RotateHours,
+	} {
+		if v < 0 {
+			add(field, "no puede ser negativo")
+		}
+	}
+	return problems
+}
+
+var profileNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// validateProfile revisa un perfil ya combinado con los valores globales
+func validateProfile(cfg *Config, name string, origins Origins, checkDirs bool) []Problem {
+	var problems []Problem
+	prefix := "profiles." + name
+	origin := origins["profiles"]
+	add := func(field, format string, args ...interface{}) {
+		problems = append(problems, Problem{Field: prefix + field, Origin: origin, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// El perfil implícito sin source_dir solo sirve para las sesiones de subida
+	_, explicit := cfg.Profiles[name]
+	if !profileNameRe.MatchString(name) {
+		add("", "nombre inválido (use letras, números, - o _)")
+	}
+	p, err := cfg.Profile(name)
+	if err != nil {
+		add("", "%v", err)
+		return problems
+	}
+
+	if explicit && len(p.Sources) == 0 {
+		add(".sources", "debe indicar al menos un directorio")
+	}
+	for i, src := range p.Sources {
+		field := fmt.Sprintf(".sources[%d]", i)
+		if strings.TrimSpace(src) == "" {
+			add(field, "no puede estar vacío")
+			continue
+		}
+		if explicit && Overlaps(src, p.Dest) {
+			add(".dest", "no puede estar dentro de %s: el backup se copiaría a sí mismo", src)
+		}
+		if !checkDirs || !explicit {
+			continue // el perfil implícito ya se revisa como source_dir
+		}
+		if info, err := os.Stat(src); err != nil {
+			add(field, "no existe: %s", src)
+		} else if !info.IsDir() {
+			add(field, "no es un directorio: %s", src)
+		}
+	}
+	if explicit && checkDirs {
+		if err := checkWritable(p.Dest); err != nil {
+			add(".dest", "%v", err)
+		}
+	}
+
+	for _, list := range []struct {
+		field    string
+		patterns []string
+	}{{".include", p.Include}, {".exclude", p.Exclude}} {
+		for i, pattern := range list.patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				add(fmt.Sprintf("%s[%d]", list.field, i), "patrón inválido %q", pattern)
+			}
+		}
+	}
+
+	switch p.Format {
+	case "zip", "tar.gz", "tar.zst":
+	default:
+		add(".format", "formato desconocido %q (use zip, tar.gz o tar.zst)", p.Format)
+	}
+	if p.ModifiedMinutes < 0 {
+		add(".modified_minutes", "no puede ser negativo (0 = todos los archivos)")
+	}
+	if p.Retention.KeepLast < 0 {
+		add(".retention.keep_last", "no puede ser negativo (0 = sin límite)")
+	}
+	if p.Retention.MaxAgeDays < 0 {
+		add(".retention.max_age_days", "no puede ser negativo (0 = sin límite)")
+	}
+
+	enc := p.Encryption
+	if enc.Enabled {
+		switch {
+		case enc.PassphraseEnv == "" && enc.KeyFile == "":
+			add(".encryption", "indique passphrase_env o key_file")
+		case enc.PassphraseEnv != "" && enc.KeyFile != "":
+			add(".encryption", "indique passphrase_env o key_file, no ambos")
+		case !checkDirs:
+		case enc.PassphraseEnv != "" && os.Getenv(enc.PassphraseEnv) == "":
+			add(".encryption.passphrase_env", "la variable %s no está definida", enc.PassphraseEnv)
+		case enc.KeyFile != "":
+			if _, err := os.Stat(enc.KeyFile); err != nil {
+				add(".encryption.key_file", "no se puede leer %s", enc.KeyFile)
+			}
+		}
+	}
+	return problems
+}
+
+// validateWebhook revisa la URL, los eventos, el formato y la clave de un webhook
+func validateWebhook(name string, w WebhookConfig, origin string, checkDirs bool) []Problem {
+	var problems []Problem
+	prefix := "webhooks." + name
+	add := func(field, format string, args ...interface{}) {
+		problems = append(problems, Problem{Field: prefix + field, Origin: origin, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !profileNameRe.MatchString(name) {
+		add("", "nombre inválido (use letras, números, - o _)")
+	}
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		add(".url", "URL inválida %q (se espera http:// o https://)", w.URL)
+	}
+	for i, event := range w.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			add(fmt.Sprintf(".events[%d]", i), "evento desconocido %q (use %s)", event, strings.Join(WebhookEvents, ", "))
+		}
+	}
+	switch w.Format {
+	case "", "json", "slack", "teams", "discord":
+	default:
+		add(".format", "formato desconocido %q (use json, slack, teams o discord)", w.Format)
+	}
+	if w.TimeoutSeconds < 0 {
+		add(".timeout_seconds", "no puede ser negativo (0 = 10 segundos)")
+	}
+	if w.MaxAttempts < 0 {
+		add(".max_attempts", "no puede ser negativo (0 = 5 intentos)")
+	}
+	switch {
+	case w.SecretEnv != "" && w.SecretFile != "":
+		add("", "indique secret_env o secret_file, no ambos")
+	case !checkDirs:
+	case w.SecretEnv != "" && os.Getenv(w.SecretEnv) == "":
+		add(".secret_env", "la variable %s no está definida", w.SecretEnv)
+	case w.SecretFile != "":
+		if _, err := os.Stat(w.SecretFile); err != nil {
+			add(".secret_file", "no se puede leer %s", w.SecretFile)
+		}
+	}
+	return problems
+}
+
+// Overlaps indica si dest es src o está dentro de src
+func Overlaps(src, dest string) bool {
+	absSrc, err1 := filepath.Abs(src)
+	absDest, err2 := filepath.Abs(dest)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absSrc, absDest)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkWritable comprueba que se pueda escribir en dir o, si no existe, crearlo
+// dentro de su primer padre existente
+func checkWritable(dir string) error {
+	path := dir
+	for {
+		info, err := os.Stat(path)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s no es un directorio", path)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return fmt.Errorf("no se puede crear %s", dir)
+		}
+		path = parent
+	}
+
+	f, err := os.CreateTemp(path, ".gobackup-check-*")
+	if err != nil {
+		if path != dir {
+			return fmt.Errorf("no se puede crear %s: %s no es escribible", dir, path)
+		}
+		return fmt.Errorf("%s no es escribible", dir)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+// unknownField arma el problema de una clave desconocida, con una sugerencia si
+// se parece a una existente
+func unknownField(key string) Problem {
+	msg := "campo desconocido"
+	best, bestDist := "", 4
+	for _, f := range Fields() {
+		if d := editDistance(key, f.Key); d < bestDist {
+			best, bestDist = f.Key, d
+		}
+	}
+	if best != "" {
+		msg += fmt.Sprintf(", ¿quiso decir %s?", best)
+	}
+	return Problem{Field: key, Origin: OriginFile, Message: msg}
+}
+
+// editDistance es la distancia de Levenshtein entre dos claves
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
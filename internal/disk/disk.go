@@ -0,0 +1,68 @@
+// internal/disk/disk.go
+package disk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Usage es la capacidad y ocupación del sistema de archivos que contiene una ruta
+type Usage struct {
+	Path        string  `json:"path"`
+	Total       uint64  `json:"total"`
+	Used        uint64  `json:"used"`
+	Free        uint64  `json:"free"` // disponible para usuarios sin privilegios
+	UsedPercent float64 `json:"used_percent"`
+	InodesTotal uint64  `json:"inodes_total"`
+	InodesUsed  uint64  `json:"inodes_used"`
+	InodesFree  uint64  `json:"inodes_free"`
+}
+
+// GetUsage devuelve el uso real del disco para path.
+// Si path todavía no existe se consulta el primer directorio padre existente.
+func GetUsage(path string) (Usage, error) {
+	dir, err := existingParent(path)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	u, err := statfs(dir)
+	if err != nil {
+		return Usage{}, fmt.Errorf("no se pudo obtener información del disco para %s: %w", path, err)
+	}
+	u.Path = path
+	if u.Total > 0 {
+		u.UsedPercent = float64(u.Used) / float64(u.Total) * 100
+	}
+	return u, nil
+}
+
+// CheckFree verifica que haya al menos required bytes libres en el disco de path
+func CheckFree(path string, required uint64) error {
+	u, err := GetUsage(path)
+	if err != nil {
+		return err
+	}
+	if u.Free < required {
+		return fmt.Errorf("espacio insuficiente en %s: se necesitan %d bytes y hay %d libres", path, required, u.Free)
+	}
+	return nil
+}
+
+func existingParent(path string) (string, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("ruta inexistente: %s", path)
+		}
+		dir = parent
+	}
+}
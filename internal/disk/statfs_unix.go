@@ -0,0 +1,24 @@
+//go:build !windows
+
+package disk
+
+import "syscall"
+
+func statfs(path string) (Usage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return Usage{}, err
+	}
+
+	bsize := uint64(st.Bsize)
+	u := Usage{
+		Total:       uint64(st.Blocks) * bsize,
+		Free:        uint64(st.Bavail) * bsize,
+		InodesTotal: uint64(st.Files),
+		InodesFree:  uint64(st.Ffree),
+	}
+	// Los bloques reservados para root no cuentan como libres pero tampoco como usados
+	u.Used = (uint64(st.Blocks) - uint64(st.Bfree)) * bsize
+	u.InodesUsed = u.InodesTotal - u.InodesFree
+	return u, nil
+}
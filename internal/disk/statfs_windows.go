@@ -0,0 +1,24 @@
+//go:build windows
+
+package disk
+
+import "golang.org/x/sys/windows"
+
+// En Windows no existen inodos: esos campos quedan en cero
+func statfs(path string) (Usage, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	var freeAvail, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &freeAvail, &total, &totalFree); err != nil {
+		return Usage{}, err
+	}
+
+	return Usage{
+		Total: total,
+		Free:  freeAvail,
+		Used:  total - totalFree,
+	}, nil
+}
@@ -0,0 +1,480 @@
+// internal/history/history.go
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Nombre del archivo de base de datos dentro de BackupsDir
+const dbFileName = "history.db"
+
+// Buckets de la base de datos
+var (
+	bucketMeta      = []byte("meta")
+	bucketJobs      = []byte("jobs")
+	bucketSnapshots = []byte("snapshots")
+	bucketFiles     = []byte("files")
+	bucketErrors    = []byte("errors")
+	bucketIdxJob    = []byte("idx_job")
+	bucketIdxTime   = []byte("idx_time")
+
+	allBuckets = [][]byte{
+		bucketMeta, bucketJobs, bucketSnapshots, bucketFiles,
+		bucketErrors, bucketIdxJob, bucketIdxTime,
+	}
+)
+
+// Estados posibles de un snapshot
+const (
+	StatusSuccess   = "success"
+	StatusPartial   = "partial"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Job agrupa todas las ejecuciones de un mismo trabajo (sesión, perfil, etc.)
+type Job struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Runs       int       `json:"runs"`
+	LastRun    time.Time `json:"last_run"`
+	LastStatus string    `json:"last_status"`
+}
+
+// Snapshot es el registro de una ejecución de backup
+type Snapshot struct {
+	ID         uint64    `json:"id"`
+	JobID      string    `json:"job_id"`
+	Timestamp  time.Time `json:"timestamp"`
+	TotalSize  int64     `json:"total_size"`
+	FilesCount int       `json:"files_count"`
+	BackupType string    `json:"backup_type"`
+	Duration   float64   `json:"duration_seconds"`
+	Status     string    `json:"status"`
+	SessionID  string    `json:"session_id"`
+	Profile    string    `json:"profile,omitempty"`
+
+	// Detalle de la ejecución
+	FilesSkipped     int     `json:"files_skipped"`
+	FilesFailed      int     `json:"files_failed"`
+	BytesRead        int64   `json:"bytes_read"`
+	BytesWritten     int64   `json:"bytes_written"`
+	CompressionRatio float64 `json:"compression_ratio"`
+	Throughput       float64 `json:"throughput_bps"`
+	ErrorSummary     string  `json:"error_summary,omitempty"`
+
+	// Archivo generado (vacío en modo legacy)
+	Archive string `json:"archive,omitempty"`
+
+	// Informe y log propio del trabajo (ver backup.Report)
+	ReportPath string `json:"report_path,omitempty"`
+	LogPath    string `json:"log_path,omitempty"`
+}
+
+// File es el registro de un archivo incluido en un snapshot
+type File struct {
+	SnapshotID uint64    `json:"snapshot_id"`
+	Path       string    `json:"path"`
+	Size       int64     `json:"size"`
+	Modified   time.Time `json:"modified"`
+}
+
+// ErrorRecord es un error ocurrido durante un snapshot
+type ErrorRecord struct {
+	SnapshotID uint64    `json:"snapshot_id"`
+	Time       time.Time `json:"time"`
+	Path       string    `json:"path,omitempty"`
+	Message    string    `json:"message"`
+}
+
+// Query filtra los snapshots devueltos por Snapshots
+type Query struct {
+	JobID  string
+	Status string
+	Since  time.Time
+	Until  time.Time
+	Limit  int // 0 = sin límite; se devuelven los más recientes
+}
+
+// Store encapsula el acceso a la base de datos de historial.
+// La base se abre en cada operación para que varios procesos
+// (servidor web y comandos CLI) puedan compartirla.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	closed bool
+}
+
+var (
+	instance *Store
+
+	// ErrNotInitialized se devuelve si se usa el historial antes de Init
+	ErrNotInitialized = errors.New("historial no inicializado")
+	// ErrClosed se devuelve si se usa el historial después de Close
+	ErrClosed = errors.New("historial cerrado")
+	// ErrNotFound se devuelve cuando no existe el registro pedido
+	ErrNotFound = errors.New("registro no encontrado")
+)
+
+// Init abre (o crea) la base de historial dentro de dir y migra
+// el antiguo backup_history.json si todavía existe.
+func Init(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	s := &Store{path: filepath.Join(dir, dbFileName)}
+
+	err := s.update(func(tx *bolt.Tx) error {
+		for _, name := range allBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error abriendo historial: %w", err)
+	}
+
+	if err := s.migrateJSON(filepath.Join(dir, legacyFileName)); err != nil {
+		return fmt.Errorf("error migrando historial JSON: %w", err)
+	}
+
+	instance = s
+	return nil
+}
+
+// Close espera a que termine la escritura en curso y rechaza las siguientes.
+// La base se abre en cada operación, así que no queda nada pendiente en memoria.
+func Close() {
+	if instance == nil {
+		return
+	}
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	instance.closed = true
+}
+
+// Path devuelve la ruta de la base de datos en uso
+func Path() string {
+	if instance == nil {
+		return ""
+	}
+	return instance.path
+}
+
+func (s *Store) open() (*bolt.DB, error) {
+	if s.closed {
+		return nil, ErrClosed
+	}
+	return bolt.Open(s.path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+}
+
+func (s *Store) update(fn func(tx *bolt.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(fn)
+}
+
+func (s *Store) view(fn func(tx *bolt.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+// Record guarda un snapshot con sus archivos y errores, asignándole un ID.
+func Record(snap *Snapshot, files []File, errs []ErrorRecord) error {
+	if instance == nil {
+		return ErrNotInitialized
+	}
+	return instance.update(func(tx *bolt.Tx) error {
+		return putSnapshot(tx, snap, files, errs)
+	})
+}
+
+func putSnapshot(tx *bolt.Tx, snap *Snapshot, files []File, errs []ErrorRecord) error {
+	snaps := tx.Bucket(bucketSnapshots)
+	id, err := snaps.NextSequence()
+	if err != nil {
+		return err
+	}
+	snap.ID = id
+	if snap.JobID == "" {
+		snap.JobID = snap.SessionID
+	}
+	key := itob(id)
+
+	if err := putJSON(snaps, key, snap); err != nil {
+		return err
+	}
+
+	// Índices por trabajo y por fecha
+	if err := tx.Bucket(bucketIdxJob).Put(joinKey([]byte(snap.JobID+"\x00"), key), nil); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketIdxTime).Put(joinKey(itob(uint64(snap.Timestamp.UnixNano())), key), nil); err != nil {
+		return err
+	}
+
+	// Resumen del trabajo
+	jobs := tx.Bucket(bucketJobs)
+	var job Job
+	if data := jobs.Get([]byte(snap.JobID)); data != nil {
+		if err := json.Unmarshal(data, &job); err != nil {
+			return err
+		}
+	}
+	job.ID = snap.JobID
+	job.Type = snap.BackupType
+	job.Runs++
+	if !snap.Timestamp.Before(job.LastRun) {
+		job.LastRun = snap.Timestamp
+		job.LastStatus = snap.Status
+	}
+	if err := putJSON(jobs, []byte(job.ID), job); err != nil {
+		return err
+	}
+
+	fb := tx.Bucket(bucketFiles)
+	for _, f := range files {
+		f.SnapshotID = id
+		if err := putJSON(fb, joinKey(key, []byte(f.Path)), f); err != nil {
+			return err
+		}
+	}
+
+	eb := tx.Bucket(bucketErrors)
+	for i, e := range errs {
+		e.SnapshotID = id
+		if err := putJSON(eb, joinKey(key, itob(uint64(i))), e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshots devuelve los snapshots que cumplen la consulta, del más antiguo al más reciente.
+func Snapshots(q Query) ([]Snapshot, error) {
+	if instance == nil {
+		return nil, ErrNotInitialized
+	}
+	var result []Snapshot
+	err := instance.view(func(tx *bolt.Tx) error {
+		snaps := tx.Bucket(bucketSnapshots)
+		c := tx.Bucket(bucketIdxTime).Cursor()
+
+		var k []byte
+		if q.Since.IsZero() {
+			k, _ = c.First()
+		} else {
+			k, _ = c.Seek(itob(uint64(q.Since.UnixNano())))
+		}
+		for ; k != nil; k, _ = c.Next() {
+			var snap Snapshot
+			if err := json.Unmarshal(snaps.Get(k[8:]), &snap); err != nil {
+				return err
+			}
+			if !q.Until.IsZero() && snap.Timestamp.After(q.Until) {
+				break
+			}
+			if q.JobID != "" && snap.JobID != q.JobID {
+				continue
+			}
+			if q.Status != "" && snap.Status != q.Status {
+				continue
+			}
+			result = append(result, snap)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+	if result == nil {
+		result = []Snapshot{}
+	}
+	return result, nil
+}
+
+// SnapshotsAfter devuelve los snapshots con ID mayor que id, en el orden en que
+// se registraron (el historial solo crece, así se puede leer de a partes)
+func SnapshotsAfter(id uint64) ([]Snapshot, error) {
+	if instance == nil {
+		return nil, ErrNotInitialized
+	}
+	result := []Snapshot{}
+	err := instance.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketSnapshots).Cursor()
+		for k, v := c.Seek(itob(id + 1)); k != nil; k, v = c.Next() {
+			var snap Snapshot
+			if err := json.Unmarshal(v, &snap); err != nil {
+				return err
+			}
+			result = append(result, snap)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// GetSnapshot devuelve un snapshot por ID
+func GetSnapshot(id uint64) (Snapshot, error) {
+	var snap Snapshot
+	if instance == nil {
+		return snap, ErrNotInitialized
+	}
+	err := instance.view(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketSnapshots).Get(itob(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &snap)
+	})
+	return snap, err
+}
+
+// JobSnapshots devuelve los snapshots de un trabajo usando el índice por trabajo
+func JobSnapshots(jobID string) ([]Snapshot, error) {
+	if instance == nil {
+		return nil, ErrNotInitialized
+	}
+	result := []Snapshot{}
+	err := instance.view(func(tx *bolt.Tx) error {
+		snaps := tx.Bucket(bucketSnapshots)
+		prefix := []byte(jobID + "\x00")
+		c := tx.Bucket(bucketIdxJob).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && hasPrefix(k, prefix); k, _ = c.Next() {
+			var snap Snapshot
+			if err := json.Unmarshal(snaps.Get(k[len(prefix):]), &snap); err != nil {
+				return err
+			}
+			result = append(result, snap)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Jobs devuelve el resumen de todos los trabajos registrados
+func Jobs() ([]Job, error) {
+	if instance == nil {
+		return nil, ErrNotInitialized
+	}
+	result := []Job{}
+	err := instance.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketJobs).ForEach(func(k, v []byte) error {
+			var job Job
+			if err := json.Unmarshal(v, &job); err != nil {
+				return err
+			}
+			result = append(result, job)
+			return nil
+		})
+	})
+	return result, err
+}
+
+// Files devuelve los archivos registrados para un snapshot
+func Files(snapshotID uint64) ([]File, error) {
+	result := []File{}
+	err := eachWithPrefix(bucketFiles, itob(snapshotID), func(v []byte) error {
+		var f File
+		if err := json.Unmarshal(v, &f); err != nil {
+			return err
+		}
+		result = append(result, f)
+		return nil
+	})
+	return result, err
+}
+
+// Errors devuelve los errores registrados para un snapshot
+func Errors(snapshotID uint64) ([]ErrorRecord, error) {
+	result := []ErrorRecord{}
+	err := eachWithPrefix(bucketErrors, itob(snapshotID), func(v []byte) error {
+		var e ErrorRecord
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		result = append(result, e)
+		return nil
+	})
+	return result, err
+}
+
+// EachFile recorre todos los archivos registrados en el historial
+func EachFile(fn func(File) error) error {
+	return eachWithPrefix(bucketFiles, nil, func(v []byte) error {
+		var f File
+		if err := json.Unmarshal(v, &f); err != nil {
+			return err
+		}
+		return fn(f)
+	})
+}
+
+func eachWithPrefix(bucket, prefix []byte, fn func(v []byte) error) error {
+	if instance == nil {
+		return ErrNotInitialized
+	}
+	return instance.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		k, v := c.First()
+		if prefix != nil {
+			k, v = c.Seek(prefix)
+		}
+		for ; k != nil && hasPrefix(k, prefix); k, v = c.Next() {
+			if err := fn(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func putJSON(b *bolt.Bucket, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+// itob codifica un uint64 en big endian para que las claves queden ordenadas
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func joinKey(a, b []byte) []byte {
+	k := make([]byte, 0, len(a)+len(b))
+	return append(append(k, a...), b...)
+}
+
+func hasPrefix(k, prefix []byte) bool {
+	return len(k) >= len(prefix) && string(k[:len(prefix)]) == string(prefix)
+}
@@ -0,0 +1,73 @@
+// internal/history/migrate.go
+package history
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Archivo JSON usado por versiones anteriores para guardar el historial
+const legacyFileName = "backup_history.json"
+
+var keyMigratedJSON = []byte("migrated_json")
+
+// legacyHistory refleja el formato de backup_history.json
+type legacyHistory struct {
+	Backups []Snapshot `json:"backups"`
+	Files   []File     `json:"files"`
+}
+
+// migrateJSON importa una sola vez el historial JSON antiguo y lo renombra
+// a backup_history.json.migrated para no volver a procesarlo.
+func (s *Store) migrateJSON(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var legacy legacyHistory
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return err
+		}
+	}
+
+	err = s.update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(bucketMeta)
+		if meta.Get(keyMigratedJSON) != nil {
+			return nil
+		}
+
+		for i := range legacy.Backups {
+			snap := legacy.Backups[i]
+			if snap.Timestamp.IsZero() {
+				snap.Timestamp = time.Unix(0, 0)
+			}
+			if err := putSnapshot(tx, &snap, nil, nil); err != nil {
+				return err
+			}
+		}
+
+		// El formato antiguo no asociaba archivos a backups: se guardan con snapshot 0
+		fb := tx.Bucket(bucketFiles)
+		for _, f := range legacy.Files {
+			f.SnapshotID = 0
+			if err := putJSON(fb, joinKey(itob(0), []byte(f.Path)), f); err != nil {
+				return err
+			}
+		}
+
+		return meta.Put(keyMigratedJSON, []byte(time.Now().Format(time.RFC3339)))
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path, path+".migrated")
+}
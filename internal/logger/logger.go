@@ -2,26 +2,57 @@
 package logger
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+// LogEntry es una línea de log con sus campos estructurados
 type LogEntry struct {
-	Time    time.Time
-	Level   string
-	Message string
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"msg"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
 }
 
+// Options configura el logger global
+type Options struct {
+	Dir            string        // Directorio de los archivos de log
+	MaxLines       int           // Entradas conservadas en memoria
+	Level          int           // Nivel mínimo
+	Format         string        // "text" o "json"
+	MaxSizeMB      int           // Rotar al superar este tamaño (0 = sin límite)
+	MaxAgeDays     int           // Borrar archivos rotados más viejos (0 = sin límite)
+	MaxBackups     int           // Cantidad máxima de archivos rotados (0 = sin límite)
+	RotateInterval time.Duration // Rotar periódicamente (0 = desactivado)
+	Compress       bool          // Comprimir con gzip los archivos rotados
+	Console        io.Writer     // Salida adicional, normalmente os.Stdout (nil = sin consola)
+}
+
+// Formatos de salida
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Nombre del archivo de log activo dentro de Options.Dir
+const FileName = "gobackup.log"
+
 type Logger struct {
 	mu       sync.Mutex
 	entries  []LogEntry
-	file     *os.File
+	file     *rotatingFile
+	console  io.Writer
 	maxLines int
 	minLevel int
+	format   string
 }
 
 var (
@@ -52,41 +83,146 @@ func levelToString(level int) string {
 	}
 }
 
-// Init inicializa el logger global y permite configurar nivel mínimo de logs
-func Init(logDir string, maxLines int, minLevel int) error {
+// ParseLevel convierte "debug", "info", "warn" o "error" en el nivel correspondiente
+func ParseLevel(s string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("nivel de log desconocido: %q", s)
+}
+
+// Init inicializa el logger global con salida a archivo rotado y consola
+func Init(opts Options) error {
 	var err error
 	once.Do(func() {
-		if err = os.MkdirAll(logDir, 0755); err != nil {
+		if err = os.MkdirAll(opts.Dir, 0755); err != nil {
 			return
 		}
-		logPath := filepath.Join(logDir, "gobackup.log")
-		var f *os.File
-		f, err = os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		var f *rotatingFile
+		f, err = newRotatingFile(filepath.Join(opts.Dir, FileName), opts)
 		if err != nil {
 			return
 		}
+		if opts.Format != FormatJSON {
+			opts.Format = FormatText
+		}
 		instance = &Logger{
 			file:     f,
-			maxLines: maxLines,
-			minLevel: minLevel,
+			console:  opts.Console,
+			maxLines: opts.MaxLines,
+			minLevel: opts.Level,
+			format:   opts.Format,
 		}
 	})
 	return err
 }
 
-func logMessage(level int, msg string) {
+// Close vuelca y cierra el archivo de log
+func Close() error {
+	if instance == nil {
+		return nil
+	}
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	instance.file.Sync()
+	return instance.file.Close()
+}
+
+// SetLevel cambia el nivel mínimo en caliente
+func SetLevel(level int) {
 	if instance == nil {
-		fmt.Println("[LOGGER] Logger no inicializado")
 		return
 	}
-	if level < instance.minLevel {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	instance.minLevel = level
+}
+
+// SetFormat cambia el formato de salida en caliente ("text" o "json")
+func SetFormat(format string) {
+	if instance == nil {
 		return
 	}
+	if format != FormatJSON {
+		format = FormatText
+	}
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	instance.format = format
+}
+
+// currentFormat devuelve el formato en uso (text antes de Init)
+func currentFormat() string {
+	if instance == nil {
+		return FormatText
+	}
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	return instance.format
+}
+
+// Level devuelve el nivel mínimo actual
+func Level() int {
+	if instance == nil {
+		return LevelInfo
+	}
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	return instance.minLevel
+}
+
+// Rotate fuerza la rotación del archivo de log
+func Rotate() error {
+	if instance == nil {
+		return nil
+	}
+	return instance.file.Rotate()
+}
 
+// fieldsFromKV convierte pares clave/valor en un mapa. Una clave sin valor
+// se guarda bajo "!BADKEY" para no perder información.
+func fieldsFromKV(kv []interface{}) map[string]interface{} {
+	if len(kv) == 0 {
+		return nil
+	}
+	fields := make(map[string]interface{}, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok || i+1 >= len(kv) {
+			fields["!BADKEY"] = kv[i]
+			continue
+		}
+		value := kv[i+1]
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
+func logMessage(level int, msg string, fields map[string]interface{}) {
 	entry := LogEntry{
 		Time:    time.Now(),
 		Level:   levelToString(level),
 		Message: msg,
+		Fields:  fields,
+	}
+
+	if instance == nil {
+		// Antes de Init los mensajes van a stderr para no perderlos
+		fmt.Fprint(os.Stderr, formatText(entry))
+		return
+	}
+	if level < Level() {
+		return
 	}
 
 	instance.mu.Lock()
@@ -97,29 +233,82 @@ func logMessage(level int, msg string) {
 		instance.entries = instance.entries[len(instance.entries)-instance.maxLines:]
 	}
 
-	line := fmt.Sprintf("%s [%s] %s\n", entry.Time.Format("2006-01-02 15:04:05"), entry.Level, msg)
-	if _, err := instance.file.WriteString(line); err != nil {
-		log.Println("Error escribiendo en log:", err)
+	line := formatLine(entry, instance.format)
+	if _, err := instance.file.Write([]byte(line)); err != nil {
+		fmt.Fprintln(os.Stderr, "Error escribiendo en log:", err)
+	}
+
+	if instance.console != nil {
+		io.WriteString(instance.console, line)
+	}
+}
+
+// formatLine formatea la entrada con el formato indicado
+func formatLine(entry LogEntry, format string) string {
+	if format == FormatJSON {
+		return formatJSON(entry)
+	}
+	return formatText(entry)
+}
+
+// formatText genera "fecha [NIVEL] mensaje clave=valor ..."
+func formatText(entry LogEntry) string {
+	var b strings.Builder
+	b.WriteString(entry.Time.Format("2006-01-02 15:04:05"))
+	b.WriteString(" [")
+	b.WriteString(entry.Level)
+	b.WriteString("] ")
+	b.WriteString(entry.Message)
+
+	keys := make([]string, 0, len(entry.Fields))
+	for k := range entry.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := fmt.Sprint(entry.Fields[k])
+		if strings.ContainsAny(v, " \"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+		fmt.Fprintf(&b, " %s=%s", k, v)
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// formatJSON genera un objeto JSON por línea con los campos al primer nivel
+func formatJSON(entry LogEntry) string {
+	obj := make(map[string]interface{}, len(entry.Fields)+3)
+	for k, v := range entry.Fields {
+		obj[k] = v
 	}
+	obj["time"] = entry.Time.Format(time.RFC3339Nano)
+	obj["level"] = entry.Level
+	obj["msg"] = entry.Message
 
-	fmt.Print(line)
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return formatText(entry)
+	}
+	return string(data) + "\n"
 }
 
-// Métodos con nivel específico
-func Info(msg string) {
-	logMessage(LevelInfo, msg)
+// Métodos con nivel específico. Los argumentos extra son pares clave/valor:
+// logger.Info("Backup creado", "session_id", id, "bytes", n)
+func Info(msg string, kv ...interface{}) {
+	logMessage(LevelInfo, msg, fieldsFromKV(kv))
 }
 
-func Error(msg string) {
-	logMessage(LevelError, msg)
+func Error(msg string, kv ...interface{}) {
+	logMessage(LevelError, msg, fieldsFromKV(kv))
 }
 
-func Warn(msg string) {
-	logMessage(LevelWarn, msg)
+func Warn(msg string, kv ...interface{}) {
+	logMessage(LevelWarn, msg, fieldsFromKV(kv))
 }
 
-func Debug(msg string) {
-	logMessage(LevelDebug, msg)
+func Debug(msg string, kv ...interface{}) {
+	logMessage(LevelDebug, msg, fieldsFromKV(kv))
 }
 
 func Infof(format string, args ...interface{}) {
@@ -138,6 +327,77 @@ func Debugf(format string, args ...interface{}) {
 	Debug(fmt.Sprintf(format, args...))
 }
 
+// Scoped es un logger con campos fijos (por ejemplo job_id o session_id)
+type Scoped struct {
+	fields []interface{}
+	sinks  []*sink
+}
+
+// sink es una salida adicional de un Scoped (por ejemplo el log propio de un trabajo)
+type sink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// With devuelve un logger que agrega los pares clave/valor a cada mensaje
+func With(kv ...interface{}) *Scoped {
+	return &Scoped{fields: kv}
+}
+
+// With agrega más campos fijos
+func (s *Scoped) With(kv ...interface{}) *Scoped {
+	return &Scoped{
+		fields: append(append([]interface{}(nil), s.fields...), kv...),
+		sinks:  s.sinks,
+	}
+}
+
+// Tee devuelve un logger que además escribe cada mensaje en w, sin filtrar por
+// nivel. Sirve para capturar el log completo de un trabajo en su propio archivo.
+func (s *Scoped) Tee(w io.Writer) *Scoped {
+	sinks := append(append([]*sink(nil), s.sinks...), &sink{w: w})
+	return &Scoped{fields: s.fields, sinks: sinks}
+}
+
+func (s *Scoped) log(level int, msg string, kv []interface{}) {
+	all := append(append([]interface{}(nil), s.fields...), kv...)
+	fields := fieldsFromKV(all)
+	logMessage(level, msg, fields)
+
+	if len(s.sinks) == 0 {
+		return
+	}
+	line := formatLine(LogEntry{
+		Time:    time.Now(),
+		Level:   levelToString(level),
+		Message: msg,
+		Fields:  fields,
+	}, currentFormat())
+	for _, out := range s.sinks {
+		out.mu.Lock()
+		io.WriteString(out.w, line)
+		out.mu.Unlock()
+	}
+}
+
+func (s *Scoped) Debug(msg string, kv ...interface{}) { s.log(LevelDebug, msg, kv) }
+func (s *Scoped) Info(msg string, kv ...interface{})  { s.log(LevelInfo, msg, kv) }
+func (s *Scoped) Warn(msg string, kv ...interface{})  { s.log(LevelWarn, msg, kv) }
+func (s *Scoped) Error(msg string, kv ...interface{}) { s.log(LevelError, msg, kv) }
+
+func (s *Scoped) Debugf(format string, args ...interface{}) {
+	s.log(LevelDebug, fmt.Sprintf(format, args...), nil)
+}
+func (s *Scoped) Infof(format string, args ...interface{}) {
+	s.log(LevelInfo, fmt.Sprintf(format, args...), nil)
+}
+func (s *Scoped) Warnf(format string, args ...interface{}) {
+	s.log(LevelWarn, fmt.Sprintf(format, args...), nil)
+}
+func (s *Scoped) Errorf(format string, args ...interface{}) {
+	s.log(LevelError, fmt.Sprintf(format, args...), nil)
+}
+
 func GetLogs() []LogEntry {
 	if instance == nil {
 		return nil
@@ -146,3 +406,18 @@ func GetLogs() []LogEntry {
 	defer instance.mu.Unlock()
 	return append([]LogEntry(nil), instance.entries...)
 }
+
+type ctxKey struct{}
+
+// NewContext guarda un logger con campos en el contexto
+func NewContext(ctx context.Context, s *Scoped) context.Context {
+	return context.WithValue(ctx, ctxKey{}, s)
+}
+
+// FromContext devuelve el logger guardado en ctx, o uno sin campos
+func FromContext(ctx context.Context) *Scoped {
+	if s, ok := ctx.Value(ctxKey{}).(*Scoped); ok {
+		return s
+	}
+	return &Scoped{}
+}
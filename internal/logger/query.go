@@ -0,0 +1,328 @@
+// internal/logger/query.go
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter selecciona entradas de log
+type Filter struct {
+	MinLevel int       // nivel mínimo (LevelDebug incluye todo)
+	Since    time.Time // inclusive; cero = sin límite
+	Until    time.Time // inclusive; cero = sin límite
+	JobID    string    // campo job_id (o session_id)
+	Text     string    // búsqueda sin distinguir mayúsculas en mensaje y campos
+	Limit    int       // devolver solo las últimas N coincidencias (0 = todas)
+}
+
+// Match indica si la entrada cumple el filtro
+func (f Filter) Match(e LogEntry) bool {
+	if levelFromString(e.Level) < f.MinLevel {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	if f.JobID != "" {
+		job := fieldString(e.Fields, "job_id")
+		session := fieldString(e.Fields, "session_id")
+		if job != f.JobID && session != f.JobID {
+			return false
+		}
+	}
+	if f.Text != "" {
+		text := strings.ToLower(f.Text)
+		if !strings.Contains(strings.ToLower(formatText(e)), text) {
+			return false
+		}
+	}
+	return true
+}
+
+func levelFromString(level string) int {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return LevelDebug
+	}
+	return l
+}
+
+func fieldString(fields map[string]interface{}, key string) string {
+	v, ok := fields[key]
+	if !ok {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return strings.TrimSpace(formatValue(v))
+}
+
+func formatValue(v interface{}) string {
+	switch x := v.(type) {
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case string:
+		return x
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// String devuelve la entrada en formato texto, con la hora local y sin salto final
+func (e LogEntry) String() string {
+	e.Time = e.Time.Local()
+	return strings.TrimSuffix(formatText(e), "\n")
+}
+
+// ParseSince acepta una fecha RFC3339 o una duración relativa a ahora ("30m", "24h")
+func ParseSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// Dir devuelve el directorio de logs configurado
+func Dir() string {
+	if instance == nil {
+		return ""
+	}
+	return filepath.Dir(instance.file.path)
+}
+
+// LogFiles devuelve los archivos de log de dir, del más antiguo al actual
+func LogFiles(dir string) ([]string, error) {
+	current := filepath.Join(dir, FileName)
+	files, err := RotatedFiles(current)
+	if err != nil {
+		return nil, err
+	}
+	if fileExists(current) {
+		files = append(files, current)
+	}
+	return files, nil
+}
+
+// ReadFiles lee los logs persistidos en dir (incluidos los rotados y comprimidos)
+// y devuelve las entradas que cumplen el filtro, en orden cronológico.
+func ReadFiles(dir string, f Filter) ([]LogEntry, error) {
+	files, err := LogFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []LogEntry
+	for _, path := range files {
+		err := readFile(path, func(e LogEntry) {
+			if !f.Match(e) {
+				return
+			}
+			result = append(result, e)
+			// Mantener acotada la memoria cuando solo interesan las últimas N
+			if f.Limit > 0 && len(result) > 2*f.Limit {
+				result = append(result[:0], result[len(result)-f.Limit:]...)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if f.Limit > 0 && len(result) > f.Limit {
+		result = result[len(result)-f.Limit:]
+	}
+	return result, nil
+}
+
+func readFile(path string, fn func(LogEntry)) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		// Rotado o borrado por la retención mientras se leía
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	_, err = scanEntries(r, fn)
+	return err
+}
+
+// scanEntries parsea línea a línea y devuelve los bytes consumidos (solo líneas completas)
+func scanEntries(r io.Reader, fn func(LogEntry)) (int64, error) {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	var consumed int64
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			// Una línea sin salto final puede estar a medio escribir
+			return consumed, nil
+		}
+		if err != nil {
+			return consumed, err
+		}
+		consumed += int64(len(line))
+		if e, ok := ParseLine(line); ok {
+			fn(e)
+		}
+	}
+}
+
+// Campos clave=valor al final de una línea de texto
+var textFieldRe = regexp.MustCompile(` ([A-Za-z_!][\w.!-]*)=("(?:[^"\\]|\\.)*"|[^ "]*)$`)
+
+// ParseLine interpreta una línea en formato JSON o texto
+func ParseLine(line string) (LogEntry, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return LogEntry{}, false
+	}
+	if strings.HasPrefix(line, "{") {
+		return parseJSONLine(line)
+	}
+	return parseTextLine(line)
+}
+
+func parseJSONLine(line string) (LogEntry, bool) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &obj); err != nil {
+		return LogEntry{}, false
+	}
+
+	var e LogEntry
+	if ts, ok := obj["time"].(string); ok {
+		e.Time, _ = time.Parse(time.RFC3339Nano, ts)
+	}
+	e.Level, _ = obj["level"].(string)
+	e.Message, _ = obj["msg"].(string)
+	delete(obj, "time")
+	delete(obj, "level")
+	delete(obj, "msg")
+	if len(obj) > 0 {
+		e.Fields = obj
+	}
+	return e, true
+}
+
+func parseTextLine(line string) (LogEntry, bool) {
+	// "2006-01-02 15:04:05 [LEVEL] mensaje k=v ..."
+	const tsLen = len("2006-01-02 15:04:05")
+	if len(line) < tsLen+3 {
+		return LogEntry{}, false
+	}
+	ts, err := time.ParseInLocation("2006-01-02 15:04:05", line[:tsLen], time.Local)
+	if err != nil {
+		return LogEntry{}, false
+	}
+	rest := strings.TrimPrefix(line[tsLen:], " ")
+	if !strings.HasPrefix(rest, "[") {
+		return LogEntry{}, false
+	}
+	end := strings.Index(rest, "]")
+	if end < 0 {
+		return LogEntry{}, false
+	}
+
+	e := LogEntry{Time: ts, Level: rest[1:end]}
+	msg := strings.TrimPrefix(rest[end+1:], " ")
+
+	// Extraer los campos desde el final
+	for {
+		m := textFieldRe.FindStringSubmatchIndex(msg)
+		if m == nil {
+			break
+		}
+		key := msg[m[2]:m[3]]
+		value := msg[m[4]:m[5]]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		if e.Fields == nil {
+			e.Fields = make(map[string]interface{})
+		}
+		e.Fields[key] = value
+		msg = msg[:m[0]]
+	}
+	e.Message = msg
+	return e, true
+}
+
+// Follow sigue el archivo de log activo de dir y llama a fn con cada entrada nueva
+// que cumpla el filtro, hasta que se cancele ctx. Detecta rotaciones.
+func Follow(ctx context.Context, dir string, f Filter, fn func(LogEntry)) error {
+	path := filepath.Join(dir, FileName)
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		// Si el archivo es más chico que lo leído, fue rotado: empezar de cero
+		if info.Size() < offset {
+			offset = 0
+		}
+		if info.Size() == offset {
+			continue
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			continue
+		}
+		n, err := scanEntries(file, func(e LogEntry) {
+			if f.Match(e) {
+				fn(e)
+			}
+		})
+		file.Close()
+		offset += n
+		if err != nil {
+			return err
+		}
+	}
+}
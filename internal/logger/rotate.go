@@ -0,0 +1,242 @@
+// internal/logger/rotate.go
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Formato de fecha usado en el nombre de los archivos rotados (ancho fijo para que ordenen bien)
+const rotateTimeFormat = "20060102-150405.000000000"
+
+// rotatingFile es un io.Writer que rota el archivo de log por tamaño o antigüedad,
+// comprime los archivos rotados y borra los que superan la retención.
+type rotatingFile struct {
+	mu sync.Mutex
+
+	path       string
+	maxSize    int64         // 0 = sin límite de tamaño
+	maxAge     time.Duration // retención de archivos rotados; 0 = sin límite
+	maxBackups int           // cantidad máxima de archivos rotados; 0 = sin límite
+	interval   time.Duration // rotación periódica; 0 = desactivada
+	compress   bool
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	wg       sync.WaitGroup // compresiones en curso
+	bgMu     sync.Mutex     // serializa compresión y limpieza
+}
+
+func newRotatingFile(path string, opts Options) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:       path,
+		maxSize:    int64(opts.MaxSizeMB) * 1024 * 1024,
+		maxAge:     time.Duration(opts.MaxAgeDays) * 24 * time.Hour,
+		maxBackups: opts.MaxBackups,
+		interval:   opts.RotateInterval,
+		compress:   opts.Compress,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	r.openedAt = info.ModTime()
+	if r.size == 0 {
+		r.openedAt = time.Now()
+	}
+	return nil
+}
+
+// Write escribe p rotando antes si el archivo superaría el tamaño máximo
+// o si ya pasó el intervalo de rotación.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	tooBig := r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize
+	tooOld := r.interval > 0 && r.size > 0 && time.Since(r.openedAt) >= r.interval
+	if tooBig || tooOld {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate fuerza la rotación del archivo actual
+func (r *rotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rotate()
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(r.path)
+	base := strings.TrimSuffix(r.path, ext)
+	rotated := fmt.Sprintf("%s-%s%s", base, time.Now().Format(rotateTimeFormat), ext)
+	if fileExists(rotated) || fileExists(rotated+".gz") {
+		r.open()
+		return fmt.Errorf("el archivo rotado ya existe: %s", rotated)
+	}
+
+	// Si el renombrado falla se sigue escribiendo en el archivo actual
+	renameErr := os.Rename(r.path, rotated)
+	if err := r.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.bgMu.Lock()
+		defer r.bgMu.Unlock()
+		if r.compress {
+			// Puede haber sido borrado por la retención antes de comprimirse
+			if err := compressFile(rotated); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "[LOGGER] Error comprimiendo %s: %v\n", rotated, err)
+			}
+		}
+		r.cleanup()
+	}()
+	return nil
+}
+
+// Close cierra el archivo y espera las compresiones pendientes
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+	return err
+}
+
+// Sync vuelca a disco el archivo actual
+func (r *rotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Sync()
+}
+
+// RotatedFiles devuelve los archivos rotados (comprimidos o no), del más antiguo al más nuevo
+func RotatedFiles(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	pattern := strings.TrimSuffix(path, ext) + "-*" + ext + "*"
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	// El nombre incluye la fecha en formato ordenable
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// cleanup borra los archivos rotados fuera de la retención configurada
+func (r *rotatingFile) cleanup() {
+	files, err := RotatedFiles(r.path)
+	if err != nil {
+		return
+	}
+
+	var keep []string
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		if r.maxAge > 0 && time.Since(info.ModTime()) > r.maxAge {
+			os.Remove(f)
+			continue
+		}
+		keep = append(keep, f)
+	}
+
+	if r.maxBackups > 0 && len(keep) > r.maxBackups {
+		for _, f := range keep[:len(keep)-r.maxBackups] {
+			os.Remove(f)
+		}
+	}
+}
+
+// compressFile comprime path a path.gz y borra el original
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	// Conservar la fecha del original para que la retención por antigüedad sea exacta
+	if info, err := in.Stat(); err == nil {
+		os.Chtimes(path+".gz", info.ModTime(), info.ModTime())
+	}
+	in.Close()
+	return os.Remove(path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,17 @@
+package metrics
+
+// Métricas propias del proceso, actualizadas por los distintos paquetes
+var (
+	// ActiveJobs cuenta los backups en ejecución
+	ActiveJobs = NewGaugeVec("gobackup_active_jobs", "Backups en ejecución en este momento.")
+
+	// HTTPRequests cuenta las peticiones atendidas por el servidor web
+	HTTPRequests = NewCounterVec("gobackup_http_requests_total", "Peticiones HTTP atendidas.", "method", "route", "code")
+
+	// HTTPDuration mide la latencia de las peticiones HTTP
+	HTTPDuration = NewHistogramVec("gobackup_http_request_duration_seconds", "Duración de las peticiones HTTP.", DefBuckets, "method", "route")
+)
+
+func init() {
+	Default.Register(ActiveJobs, HTTPRequests, HTTPDuration)
+}
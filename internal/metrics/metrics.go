@@ -0,0 +1,313 @@
+// internal/metrics/metrics.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Buckets por defecto para histogramas de duración (segundos)
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Buckets para duraciones de backup (segundos)
+var BackupBuckets = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 7200}
+
+// Metric es cualquier valor que sabe escribirse en formato de exposición de Prometheus
+type Metric interface {
+	Write(w io.Writer) error
+}
+
+// Registry agrupa métricas para exponerlas juntas en /metrics
+type Registry struct {
+	mu      sync.Mutex
+	metrics []Metric
+}
+
+// Default es el registro global del proceso
+var Default = &Registry{}
+
+// Register agrega métricas al registro
+func (r *Registry) Register(m ...Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m...)
+}
+
+// Write escribe todas las métricas registradas
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]Metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		if err := m.Write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// desc contiene los datos comunes a todas las métricas
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d desc) header(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.typ)
+	return err
+}
+
+// series es un valor con sus etiquetas
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+// valueVec guarda valores por combinación de etiquetas (counters y gauges)
+type valueVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*series
+}
+
+func newValueVec(typ, name, help string, labels []string) *valueVec {
+	return &valueVec{
+		desc:   desc{name: name, help: help, typ: typ, labels: labels},
+		values: make(map[string]*series),
+	}
+}
+
+func (v *valueVec) add(delta float64, labelValues []string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.get(labelValues).value += delta
+}
+
+func (v *valueVec) set(value float64, labelValues []string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.get(labelValues).value = value
+}
+
+func (v *valueVec) get(labelValues []string) *series {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s espera %d etiquetas, recibió %d", v.name, len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.values[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		v.values[key] = s
+	}
+	return s
+}
+
+// Reset elimina todas las series (útil para métricas recalculadas en cada scrape)
+func (v *valueVec) Reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values = make(map[string]*series)
+}
+
+func (v *valueVec) Write(w io.Writer) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.header(w); err != nil {
+		return err
+	}
+	if len(v.labels) == 0 && len(v.values) == 0 {
+		_, err := fmt.Fprintf(w, "%s 0\n", v.name)
+		return err
+	}
+	for _, key := range sortedKeys(v.values) {
+		s := v.values[key]
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", v.name, formatLabels(v.labels, s.labelValues, "", ""), formatFloat(s.value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CounterVec es un contador con etiquetas
+type CounterVec struct{ *valueVec }
+
+// NewCounterVec crea un contador con las etiquetas indicadas
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{newValueVec("counter", name, help, labels)}
+}
+
+// Inc incrementa en uno la serie con esas etiquetas
+func (c *CounterVec) Inc(labelValues ...string) { c.add(1, labelValues) }
+
+// Add suma delta (debe ser positivo) a la serie con esas etiquetas
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.add(delta, labelValues)
+}
+
+// Set fija el valor; solo para contadores derivados de datos persistidos
+func (c *CounterVec) Set(value float64, labelValues ...string) { c.set(value, labelValues) }
+
+// GaugeVec es un valor que puede subir y bajar, con etiquetas
+type GaugeVec struct{ *valueVec }
+
+// NewGaugeVec crea un gauge con las etiquetas indicadas
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{newValueVec("gauge", name, help, labels)}
+}
+
+// Set fija el valor de la serie
+func (g *GaugeVec) Set(value float64, labelValues ...string) { g.set(value, labelValues) }
+
+// Inc incrementa en uno la serie
+func (g *GaugeVec) Inc(labelValues ...string) { g.add(1, labelValues) }
+
+// Dec decrementa en uno la serie
+func (g *GaugeVec) Dec(labelValues ...string) { g.add(-1, labelValues) }
+
+// histSeries acumula las observaciones de una combinación de etiquetas
+type histSeries struct {
+	labelValues []string
+	counts      []uint64 // por bucket, no acumulado
+	sum         float64
+	count       uint64
+}
+
+// HistogramVec es un histograma con etiquetas
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histSeries
+}
+
+// NewHistogramVec crea un histograma con los buckets (límites superiores) indicados
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &HistogramVec{
+		desc:    desc{name: name, help: help, typ: "histogram", labels: labels},
+		buckets: b,
+		values:  make(map[string]*histSeries),
+	}
+}
+
+// Observe registra un valor en la serie con esas etiquetas
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	if len(labelValues) != len(h.labels) {
+		panic(fmt.Sprintf("metrics: %s espera %d etiquetas, recibió %d", h.name, len(h.labels), len(labelValues)))
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := h.values[key]
+	if !ok {
+		s = &histSeries{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = s
+	}
+
+	for i, upper := range h.buckets {
+		if value <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// Reset elimina todas las observaciones
+func (h *HistogramVec) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.values = make(map[string]*histSeries)
+}
+
+func (h *HistogramVec) Write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.header(w); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(h.values) {
+		s := h.values[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, s.labelValues, "le", formatFloat(upper)), cumulative); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, s.labelValues, "le", "+Inf"), s.count); err != nil {
+			return err
+		}
+		labels := formatLabels(h.labels, s.labelValues, "", "")
+		if _, err := fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n", h.name, labels, formatFloat(s.sum), h.name, labels, s.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", name, escapeLabel(values[i]))
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", extraName, extraValue)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
@@ -0,0 +1,101 @@
+// Package safepath valida los IDs y rutas que llegan de afuera (API, formularios,
+// archivos comprimidos) para que ninguna operación salga de los directorios configurados
+package safepath
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrUnsafe se devuelve para IDs o rutas que podrían salir del directorio raíz
+var ErrUnsafe = errors.New("ruta no permitida")
+
+// MaxIDLength es el largo máximo de un ID
+const MaxIDLength = 128
+
+// Un ID es un solo componente de ruta: sin separadores y sin empezar con punto
+// (así tampoco puede ser "." ni "..")
+var idRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ID valida un identificador que se usa como nombre de archivo o directorio
+// (sesiones, trabajos, backups)
+func ID(id string) error {
+	if len(id) > MaxIDLength || !idRe.MatchString(id) {
+		return fmt.Errorf("%w: ID inválido %q", ErrUnsafe, id)
+	}
+	return nil
+}
+
+// Rel valida una ruta relativa que viene del cliente o de un archivo comprimido y
+// la devuelve normalizada con "/". Rechaza rutas vacías, absolutas, con unidad de
+// Windows, con componentes ".." o con bytes nulos.
+func Rel(p string) (string, error) {
+	unsafe := func(reason string) (string, error) {
+		return "", fmt.Errorf("%w: %q (%s)", ErrUnsafe, p, reason)
+	}
+	if strings.ContainsRune(p, 0) {
+		return unsafe("contiene un byte nulo")
+	}
+	slashed := strings.ReplaceAll(p, `\`, "/")
+	if strings.HasPrefix(slashed, "/") {
+		return unsafe("es absoluta")
+	}
+
+	var parts []string
+	for _, part := range strings.Split(slashed, "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			return unsafe("sale del directorio")
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return unsafe("está vacía")
+	}
+	// Después de quitar los "./" del principio: "./C:x" también es una unidad
+	if len(parts[0]) >= 2 && parts[0][1] == ':' {
+		return unsafe("tiene unidad")
+	}
+	return path.Join(parts...), nil
+}
+
+// Join une root con rutas relativas validadas con Rel y comprueba que el
+// resultado quede dentro de root
+func Join(root string, elems ...string) (string, error) {
+	full := filepath.Clean(root)
+	for _, elem := range elems {
+		rel, err := Rel(elem)
+		if err != nil {
+			return "", err
+		}
+		full = filepath.Join(full, filepath.FromSlash(rel))
+	}
+	if !Within(root, full) {
+		return "", fmt.Errorf("%w: %s está fuera de %s", ErrUnsafe, full, root)
+	}
+	return full, nil
+}
+
+// Within indica si p es root o está dentro de root. Compara las rutas absolutas
+// limpias, sin seguir enlaces simbólicos.
+func Within(root, p string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	absP, err := filepath.Abs(p)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absRoot, absP)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel))
+}
@@ -0,0 +1,124 @@
+package safepath
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Casos conocidos que tienen que rechazarse (y semillas de los fuzz)
+var badPaths = []string{
+	"../../etc",
+	`..\..\windows`,
+	"a/../../b",
+	`C:\x`,
+	"C:x",
+	"./C:x",
+	`\\server\share`,
+	"//server/share",
+	"/etc/passwd",
+	"a/\x00b",
+	"",
+	".",
+	"./.",
+}
+
+func TestRelRejects(t *testing.T) {
+	for _, p := range badPaths {
+		if out, err := Rel(p); err == nil {
+			t.Errorf("Rel(%q) = %q, se esperaba error", p, out)
+		}
+	}
+}
+
+// checkRel revisa que una salida de Rel no pueda salir de la raíz
+func checkRel(t *testing.T, in, out string) {
+	t.Helper()
+	switch {
+	case out == "":
+		t.Fatalf("Rel(%q) devolvió una ruta vacía", in)
+	case strings.HasPrefix(out, "/"):
+		t.Fatalf("Rel(%q) = %q es absoluta", in, out)
+	case len(out) >= 2 && out[1] == ':':
+		t.Fatalf("Rel(%q) = %q tiene unidad", in, out)
+	case strings.ContainsRune(out, 0):
+		t.Fatalf("Rel(%q) = %q tiene un byte nulo", in, out)
+	case strings.Contains(out, `\`):
+		t.Fatalf("Rel(%q) = %q no está normalizada", in, out)
+	}
+	for _, part := range strings.Split(out, "/") {
+		if part == ".." || part == "." || part == "" {
+			t.Fatalf("Rel(%q) = %q tiene el componente %q", in, out, part)
+		}
+	}
+}
+
+func FuzzRel(f *testing.F) {
+	for _, p := range badPaths {
+		f.Add(p)
+	}
+	f.Add("fotos/2024/img.jpg")
+	f.Add(`docs\informe.pdf`)
+	f.Add("a/./b//c/")
+
+	root := f.TempDir()
+	f.Fuzz(func(t *testing.T, p string) {
+		out, err := Rel(p)
+		if err != nil {
+			return
+		}
+		checkRel(t, p, out)
+		if again, err := Rel(out); err != nil || again != out {
+			t.Fatalf("Rel(%q) = %q no es estable: %q, %v", p, out, again, err)
+		}
+		if full := filepath.Join(root, filepath.FromSlash(out)); !Within(root, full) {
+			t.Fatalf("Rel(%q) = %q sale de la raíz: %s", p, out, full)
+		}
+	})
+}
+
+func FuzzID(f *testing.F) {
+	for _, p := range badPaths {
+		f.Add(p)
+	}
+	f.Add("session_0123456789abcdef0123456789abcdef")
+	f.Add("job-20240101.1")
+	f.Add("..")
+	f.Add(".hidden")
+
+	root := f.TempDir()
+	f.Fuzz(func(t *testing.T, id string) {
+		if ID(id) != nil {
+			return
+		}
+		if id == "." || id == ".." || strings.ContainsAny(id, `/\:`+"\x00") {
+			t.Fatalf("ID(%q) aceptado", id)
+		}
+		full := filepath.Join(root, id)
+		if !Within(root, full) || filepath.Dir(full) != root {
+			t.Fatalf("ID(%q) no queda directamente dentro de la raíz: %s", id, full)
+		}
+	})
+}
+
+func FuzzJoin(f *testing.F) {
+	for _, p := range badPaths {
+		f.Add("uploads", p)
+	}
+	f.Add("session_1", "fotos/img.jpg")
+	f.Add("a/b", "../c")
+
+	root := f.TempDir()
+	f.Fuzz(func(t *testing.T, a, b string) {
+		full, err := Join(root, a, b)
+		if err != nil {
+			return
+		}
+		if !Within(root, full) {
+			t.Fatalf("Join(%q, %q) = %s está fuera de la raíz", a, b, full)
+		}
+		if full == filepath.Clean(root) {
+			t.Fatalf("Join(%q, %q) devolvió la raíz misma", a, b)
+		}
+	})
+}
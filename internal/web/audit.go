@@ -0,0 +1,119 @@
+package web
+
+import (
+	"fmt"
+	"gobackup/internal/audit"
+	"gobackup/internal/auth"
+	"gobackup/internal/logger"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAuditLimit es el máximo de eventos que devuelve /api/audit en JSON
+const maxAuditLimit = 5000
+
+// RegisterAuditRoutes registra la consulta, exportación y verificación del
+// registro de auditoría (solo admin)
+func RegisterAuditRoutes(router *gin.Engine) {
+	router.GET("/api/audit", requireScope(auth.ScopeAdmin), expensive(), getAudit)
+	router.GET("/api/audit/verify", requireScope(auth.ScopeAdmin), expensive(), verifyAudit)
+}
+
+// recordAudit anota una acción de quien hace la petición; un fallo no corta la petición
+func recordAudit(c *gin.Context, action, target, outcome, detail string) {
+	actor := currentPrincipal(c).actor()
+	if actor == "" {
+		actor = "anónimo" // auth.enabled=false
+	}
+	ip, remote := clientAddr(c)
+	recordAuditEvent(audit.Event{Actor: actor, IP: ip, Remote: remote, Action: action, Target: target, Outcome: outcome, Detail: detail})
+}
+
+// clientAddr devuelve la IP del cliente y, si salió del X-Forwarded-For de un
+// proxy de confianza (trusted_proxies), la de la conexión
+func clientAddr(c *gin.Context) (ip, remote string) {
+	ip, remote = c.ClientIP(), c.RemoteIP()
+	if remote == ip {
+		remote = ""
+	}
+	return ip, remote
+}
+
+func recordAuditEvent(e audit.Event) {
+	if err := audit.Record(e); err != nil {
+		logger.Error("Error guardando el registro de auditoría", "action", e.Action, "error", err)
+	}
+}
+
+// parseAuditFilter arma el filtro con actor, action, target, outcome, since y
+// until (RFC3339 o duración como "24h")
+func parseAuditFilter(c *gin.Context) (audit.Filter, error) {
+	f := audit.Filter{
+		Actor:   c.Query("actor"),
+		Action:  c.Query("action"),
+		Target:  c.Query("target"),
+		Outcome: c.Query("outcome"),
+	}
+	var err error
+	if f.Since, err = logger.ParseSince(c.Query("since")); err != nil {
+		return f, fmt.Errorf("since inválido: %w", err)
+	}
+	if f.Until, err = logger.ParseSince(c.Query("until")); err != nil {
+		return f, fmt.Errorf("until inválido: %w", err)
+	}
+	return f, nil
+}
+
+// getAudit - Eventos de auditoría filtrados; con ?format=jsonl descarga las
+// líneas originales (con sus hashes) para guardarlas o verificarlas afuera
+func getAudit(c *gin.Context) {
+	f, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "jsonl" {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", `attachment; filename="audit.jsonl"`)
+		if err := audit.Export(c.Writer, f); err != nil {
+			logger.Error("Error exportando la auditoría", "error", err)
+		}
+		return
+	}
+
+	f.Limit = 500
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit inválido"})
+			return
+		}
+		f.Limit = n
+	}
+	if f.Limit > maxAuditLimit {
+		f.Limit = maxAuditLimit
+	}
+	events, err := audit.Read(f)
+	if err != nil {
+		logger.Error("Error leyendo la auditoría", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo la auditoría"})
+		return
+	}
+	if events == nil {
+		events = []audit.Event{}
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events, "count": len(events)})
+}
+
+// verifyAudit - Recalcula la cadena de hashes del registro
+func verifyAudit(c *gin.Context) {
+	v, err := audit.Verify()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
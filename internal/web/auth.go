@@ -0,0 +1,277 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"gobackup/internal/audit"
+	"gobackup/internal/auth"
+	"gobackup/internal/logger"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthEnabled exige iniciar sesión en el panel (lo inicializa cmd/web.go desde auth.enabled)
+var AuthEnabled = true
+
+const (
+	sessionCookie   = "gobackup_session"
+	csrfCookie      = "gobackup_csrf" // legible desde JS para mandarlo en csrfHeader
+	csrfHeader      = "X-CSRF-Token"
+	ctxPrincipalKey = "auth_principal"
+)
+
+// publicPaths no requieren sesión: la página de login, sus recursos y el propio login
+var publicPaths = map[string]bool{
+	"/login":          true,
+	"/api/auth/login": true,
+}
+
+// RegisterAuthRoutes registra el inicio y cierre de sesión
+func RegisterAuthRoutes(router *gin.Engine) {
+	router.GET("/login", func(c *gin.Context) {
+		c.File("./internal/web/static/login.html")
+	})
+	router.POST("/api/auth/login", loginRateLimit(), login)
+	router.POST("/api/auth/logout", logout)
+	router.GET("/api/auth/me", getCurrentUser)
+}
+
+// principal es quien hace la petición: un usuario con sesión o un token de API
+type principal struct {
+	User   string
+	Role   auth.Role // rol del usuario (el del dueño para los tokens)
+	Scopes []auth.Scope
+	Token  *auth.Token // nil si entró con usuario y contraseña
+}
+
+// can indica si la petición tiene el permiso need
+func (p principal) can(need auth.Scope) bool {
+	return auth.ScopesAllow(p.Scopes, need)
+}
+
+// actor describe al principal en logs y en el historial
+func (p principal) actor() string {
+	if p.Token != nil {
+		return "token:" + p.Token.ID + " (" + p.Token.Name + ")"
+	}
+	return p.User
+}
+
+// authMiddleware exige una sesión válida, un token de API (Authorization: Bearer)
+// o un certificado de cliente en todas las rutas salvo las públicas, y el token
+// CSRF en las que modifican algo con sesión de navegador
+func authMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if !AuthEnabled || publicPaths[path] || strings.HasPrefix(path, "/static/") {
+			c.Next()
+			return
+		}
+
+		if header := c.GetHeader("Authorization"); header != "" {
+			bearerAuth(c, header)
+			return
+		}
+
+		token, _ := c.Cookie(sessionCookie)
+		if token == "" && clientCertAuth(c) {
+			return
+		}
+		session, user, ok := auth.LookupSession(token)
+		if !ok {
+			unauthorized(c)
+			return
+		}
+		if !safeMethod(c.Request.Method) && !session.CheckCSRF(c.GetHeader(csrfHeader)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token CSRF inválido o ausente"})
+			return
+		}
+		c.Set(ctxPrincipalKey, principal{User: user.Name, Role: user.Role, Scopes: user.Role.Scopes()})
+		c.Next()
+	}
+}
+
+// bearerAuth valida un token de API. No usa CSRF: el navegador nunca manda la
+// cabecera Authorization por su cuenta. Las acciones que modifican algo y las
+// descargas quedan en el historial del token.
+func bearerAuth(c *gin.Context, header string) {
+	scheme, secret, _ := strings.Cut(header, " ")
+	if !strings.EqualFold(scheme, "Bearer") || secret == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Cabecera Authorization inválida (use Bearer <token>)"})
+		return
+	}
+
+	token, err := auth.AuthenticateToken(strings.TrimSpace(secret), c.ClientIP())
+	if err != nil {
+		if token.ID != "" {
+			// Token conocido pero vencido o revocado: vale la pena anotarlo
+			ip, remote := clientAddr(c)
+			recordTokenEvent(auth.TokenEvent{TokenID: token.ID, Name: token.Name, Action: "denied", IP: ip, Remote: remote,
+				Detail: c.Request.Method + " " + c.Request.URL.Path + " (" + token.Status() + ")"})
+		}
+		if !errors.Is(err, auth.ErrBadToken) {
+			logger.Error("Error leyendo tokens", "error", err)
+		}
+		logger.Warn("Token de API rechazado", "ip", c.ClientIP(), "path", c.Request.URL.Path)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": auth.ErrBadToken.Error()})
+		return
+	}
+
+	// El token nunca puede más que su dueño: si le bajaron el rol o lo borraron, se recorta
+	owner, err := auth.GetUser(token.Owner)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "El dueño del token ya no existe"})
+		return
+	}
+	var scopes []auth.Scope
+	for _, s := range token.Scopes {
+		if auth.ScopesAllow(owner.Role.Scopes(), s) {
+			scopes = append(scopes, s)
+		}
+	}
+
+	c.Set(ctxPrincipalKey, principal{User: owner.Name, Role: owner.Role, Scopes: scopes, Token: &token})
+	c.Next()
+
+	if !safeMethod(c.Request.Method) || strings.HasPrefix(c.FullPath(), "/download/") {
+		ip, remote := clientAddr(c)
+		recordTokenEvent(auth.TokenEvent{TokenID: token.ID, Name: token.Name, Action: "request", IP: ip, Remote: remote,
+			Detail: fmt.Sprintf("%s %s %d", c.Request.Method, c.Request.URL.Path, c.Writer.Status())})
+	}
+}
+
+// recordTokenEvent anota en el historial de tokens; un fallo no corta la petición.
+// Las altas, bajas y rechazos van también a la auditoría (las peticiones no,
+// cada acción ya se anota por su lado).
+func recordTokenEvent(e auth.TokenEvent) {
+	if err := auth.RecordTokenEvent(e); err != nil {
+		logger.Error("Error guardando el historial de tokens", "error", err)
+	}
+	if e.Action == "request" {
+		return
+	}
+	ae := audit.Event{Actor: e.Actor, IP: e.IP, Remote: e.Remote, Action: audit.ActionToken, Target: "token:" + e.TokenID + " (" + e.Name + ")",
+		Outcome: audit.Success, Detail: e.Action}
+	if e.Action == "denied" {
+		ae.Actor, ae.Outcome, ae.Detail = ae.Target, audit.Denied, e.Detail
+	}
+	recordAuditEvent(ae)
+}
+
+// requireScope corta la petición si el usuario o el token no tienen el permiso need
+func requireScope(need auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !currentPrincipal(c).can(need) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permiso denegado: se requiere el permiso " + string(need)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// currentPrincipal devuelve quién hace la petición. Sin autenticación todos son
+// admin, como antes de que existieran los usuarios.
+func currentPrincipal(c *gin.Context) principal {
+	if !AuthEnabled {
+		return principal{Role: auth.RoleAdmin, Scopes: auth.RoleAdmin.Scopes()}
+	}
+	v, _ := c.Get(ctxPrincipalKey)
+	p, _ := v.(principal)
+	return p
+}
+
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// unauthorized manda al navegador a /login y responde 401 a la API
+func unauthorized(c *gin.Context) {
+	if c.Request.Method == http.MethodGet && strings.Contains(c.GetHeader("Accept"), "text/html") {
+		c.Redirect(http.StatusFound, "/login?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+		c.Abort()
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Debe iniciar sesión"})
+}
+
+// setAuthCookies guarda la sesión (solo HTTP) y el token CSRF (legible por JS)
+func setAuthCookies(c *gin.Context, session auth.Session, maxAge int) {
+	secure := c.Request.TLS != nil
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(sessionCookie, session.Token, maxAge, "/", "", secure, true)
+	c.SetCookie(csrfCookie, session.CSRF, maxAge, "/", "", secure, false)
+}
+
+// login - Inicia sesión con usuario y contraseña (JSON)
+func login(c *gin.Context) {
+	// Exigir JSON evita que otro sitio envíe el formulario sin permiso (CORS)
+	if c.ContentType() != "application/json" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Se esperaba JSON"})
+		return
+	}
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Username == "" || req.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Faltan usuario o contraseña"})
+		return
+	}
+
+	user, err := auth.Authenticate(req.Username, req.Password)
+	if errors.Is(err, auth.ErrBadCredentials) {
+		logger.Warn("Inicio de sesión fallido", "user", req.Username, "ip", c.ClientIP())
+		ip, remote := clientAddr(c)
+		recordAuditEvent(audit.Event{Actor: req.Username, IP: ip, Remote: remote, Action: audit.ActionLogin, Outcome: audit.Denied, Detail: err.Error()})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Error("Error leyendo usuarios", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando usuario"})
+		return
+	}
+
+	session := auth.NewSession(user)
+	setAuthCookies(c, session, int(auth.SessionTTL.Seconds()))
+	logger.Info("Sesión iniciada", "user", user.Name, "role", user.Role, "ip", c.ClientIP())
+	ip, remote := clientAddr(c)
+	recordAuditEvent(audit.Event{Actor: user.Name, IP: ip, Remote: remote, Action: audit.ActionLogin, Outcome: audit.Success, Detail: "rol " + string(user.Role)})
+	c.JSON(http.StatusOK, gin.H{
+		"user": user.Name,
+		"role": user.Role,
+		"csrf": session.CSRF,
+	})
+}
+
+// logout - Cierra la sesión actual
+func logout(c *gin.Context) {
+	if token, err := c.Cookie(sessionCookie); err == nil {
+		auth.EndSession(token)
+		recordAudit(c, audit.ActionLogout, "", audit.Success, "")
+	}
+	setAuthCookies(c, auth.Session{}, -1)
+	c.JSON(http.StatusOK, gin.H{"message": "Sesión cerrada"})
+}
+
+// getCurrentUser - Devuelve el usuario de la sesión para la interfaz
+func getCurrentUser(c *gin.Context) {
+	if !AuthEnabled {
+		c.JSON(http.StatusOK, gin.H{"auth_enabled": false, "role": auth.RoleAdmin, "scopes": auth.RoleAdmin.Scopes()})
+		return
+	}
+	p := currentPrincipal(c)
+	resp := gin.H{
+		"auth_enabled": true,
+		"user":         p.User,
+		"role":         p.Role,
+		"scopes":       p.Scopes,
+	}
+	if p.Token != nil {
+		resp["token"] = p.Token.ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,56 @@
+package web
+
+import (
+	"errors"
+	"gobackup/internal/backup"
+	"gobackup/internal/history"
+	"gobackup/internal/logger"
+	"gobackup/internal/safepath"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterBrowseRoutes registra el explorador de archivos de los backups
+func RegisterBrowseRoutes(router *gin.Engine) {
+	router.GET("/api/backups/:id/files", expensive(), getBackupFiles)
+}
+
+// getBackupFiles - Lista el contenido de un backup (?path=sub/dir, ?recursive=1)
+func getBackupFiles(c *gin.Context) {
+	zipPath, err := ownedArchive(c, c.Param("id"))
+	switch {
+	case errors.Is(err, history.ErrNotFound), errors.Is(err, os.ErrNotExist):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup no encontrado"})
+		return
+	case errors.Is(err, backup.ErrNoArchive):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dir := c.Query("path")
+	entries, err := backup.ListArchive(zipPath, dir, c.Query("recursive") != "")
+	if errors.Is(err, safepath.ErrUnsafe) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Warn("Error listando backup", "id", c.Param("id"), "path", dir, "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if entries == nil {
+		entries = []backup.ArchiveEntry{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":      c.Param("id"),
+		"path":    dir,
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
@@ -0,0 +1,178 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"gobackup/internal/audit"
+	"gobackup/internal/auth"
+	"gobackup/internal/backup"
+	"gobackup/internal/logger"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterChunkedUploadRoutes registra las subidas por partes, que se pueden
+// retomar si se corta la conexión (para archivos grandes)
+func RegisterChunkedUploadRoutes(router *gin.Engine) {
+	chunked := router.Group("/upload/chunked", requireScope(auth.ScopeBackup))
+	chunked.POST("", startChunkedUpload)
+	chunked.GET("/:id", getChunkedUpload)
+	chunked.PATCH("/:id", transfer(), appendChunkedUpload)
+	chunked.POST("/:id/finish", finishChunkedUpload)
+	chunked.DELETE("/:id", deleteChunkedUpload)
+}
+
+// startChunkedUpload - Declara un archivo a subir por partes
+func startChunkedUpload(c *gin.Context) {
+	if backup.Draining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": backup.ErrShuttingDown.Error()})
+		return
+	}
+	var req struct {
+		SessionID string `json:"sessionId"` // vacío = sesión nueva al terminar
+		Path      string `json:"path"`
+		Size      *int64 `json:"size"`
+		SHA256    string `json:"sha256"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Path == "" || req.Size == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Faltan path y size"})
+		return
+	}
+	if req.SessionID != "" {
+		if _, err := backup.GetSession(req.SessionID); err != nil || !ownsSession(c, req.SessionID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sesión no encontrada"})
+			return
+		}
+	}
+	u, err := backup.NewChunkedUpload(req.SessionID, req.Path, *req.Size, req.SHA256, currentPrincipal(c).User)
+	if err != nil {
+		var qe *backup.QuotaError
+		if errors.As(err, &qe) {
+			uploadError(c, req.SessionID, req.Path, err)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Location", "/upload/chunked/"+u.ID)
+	c.JSON(http.StatusCreated, u)
+}
+
+// ownedChunkedUpload carga la subida :id si es de quien la pide; si no responde 404
+func ownedChunkedUpload(c *gin.Context) (backup.ChunkedUpload, bool) {
+	u, err := backup.GetChunkedUpload(c.Param("id"))
+	p := currentPrincipal(c)
+	if err != nil || (u.User != p.User && !p.can(auth.ScopeAdmin)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subida no encontrada"})
+		return u, false
+	}
+	return u, true
+}
+
+// getChunkedUpload - Estado de una subida: offset es desde dónde seguir
+func getChunkedUpload(c *gin.Context) {
+	u, ok := ownedChunkedUpload(c)
+	if !ok {
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	c.JSON(http.StatusOK, u)
+}
+
+// appendChunkedUpload - Agrega el cuerpo de la petición a partir del byte
+// indicado en Upload-Offset. Upload-Checksum ("sha256 <base64>") es opcional.
+func appendChunkedUpload(c *gin.Context) {
+	if backup.Draining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": backup.ErrShuttingDown.Error()})
+		return
+	}
+	u, ok := ownedChunkedUpload(c)
+	if !ok {
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Falta Upload-Offset o es inválido"})
+		return
+	}
+	sum, err := backup.ParseChunkChecksum(c.GetHeader("Upload-Checksum"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	received, err := backup.AppendChunk(u.ID, offset, c.Request.Body, sum)
+	c.Header("Upload-Offset", strconv.FormatInt(received, 10))
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"offset": received, "size": u.Size})
+	case errors.Is(err, backup.ErrUploadOffset):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "offset": received})
+	case errors.Is(err, backup.ErrUploadTooLarge), errors.As(err, new(*http.MaxBytesError)):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error(), "offset": received})
+	case errors.Is(err, backup.ErrUploadChunkSum):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "offset": received})
+	case errors.As(err, new(*backup.QuotaError)):
+		uploadError(c, u.SessionID, u.Path, err)
+	default:
+		// Conexión cortada: lo recibido se conserva y el cliente sigue desde offset
+		logger.Warn("Fragmento incompleto", "upload_id", u.ID, "offset", received, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "offset": received})
+	}
+}
+
+// finishChunkedUpload - Verifica la subida completa y la mueve a la sesión
+func finishChunkedUpload(c *gin.Context) {
+	u, ok := ownedChunkedUpload(c)
+	if !ok {
+		return
+	}
+	sessionID := u.SessionID
+	var owner backup.Owner
+	if sessionID == "" {
+		sessionID, owner = backup.NewSessionID(), newSessionOwner(c)
+	} else if !ownsSession(c, sessionID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sesión no encontrada"})
+		return
+	}
+
+	u, err := backup.FinishChunkedUpload(u.ID, sessionID, owner)
+	switch {
+	case errors.Is(err, backup.ErrUploadPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "offset": u.Offset, "size": u.Size})
+		return
+	case errors.Is(err, backup.ErrUploadChecksum):
+		recordAudit(c, audit.ActionUpload, sessionID+"/"+u.Path, audit.Failure, err.Error())
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, os.ErrNotExist):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subida no encontrada"})
+		return
+	case err != nil:
+		uploadError(c, sessionID, u.Path, err)
+		return
+	}
+	logger.Info("Archivo subido", "session_id", sessionID, "file", u.Path, "bytes", u.Size, "user", currentPrincipal(c).actor())
+	recordAudit(c, audit.ActionUpload, sessionID+"/"+u.Path, audit.Success, fmt.Sprintf("%d bytes, por partes", u.Size))
+	c.JSON(http.StatusOK, gin.H{
+		"sessionId": sessionID,
+		"filename":  u.Path,
+		"size":      u.Size,
+	})
+}
+
+// deleteChunkedUpload - Cancela una subida y borra lo recibido
+func deleteChunkedUpload(c *gin.Context) {
+	u, ok := ownedChunkedUpload(c)
+	if !ok {
+		return
+	}
+	if err := backup.RemoveChunkedUpload(u.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Subida cancelada", "id": u.ID})
+}
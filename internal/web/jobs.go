@@ -0,0 +1,191 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"gobackup/internal/backup"
+	"gobackup/internal/history"
+	"gobackup/internal/logger"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterJobRoutes registra el informe y el log propio de cada trabajo
+func RegisterJobRoutes(router *gin.Engine) {
+	router.GET("/api/jobs/:id/report", getJobReport)
+	router.GET("/api/jobs/:id/log", getJobLog)
+}
+
+// findJobSnapshot busca por ID de snapshot o, si no es numérico, la última
+// ejecución del trabajo/sesión con ese ID
+func findJobSnapshot(id string) (history.Snapshot, error) {
+	if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+		return history.GetSnapshot(n)
+	}
+	snaps, err := history.JobSnapshots(id)
+	if err != nil {
+		return history.Snapshot{}, err
+	}
+	if len(snaps) == 0 {
+		return history.Snapshot{}, history.ErrNotFound
+	}
+	return snaps[len(snaps)-1], nil
+}
+
+func jobNotFound(c *gin.Context, err error) {
+	if errors.Is(err, history.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Informe no disponible"})
+		return
+	}
+	logger.Error("Error leyendo informe", "job", c.Param("id"), "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo informe"})
+}
+
+// getJobReport - Devuelve el informe del trabajo en JSON o, con format=html, como página
+func getJobReport(c *gin.Context) {
+	snap, err := findJobSnapshot(c.Param("id"))
+	if err == nil && !ownsSnapshot(c, snap) {
+		err = history.ErrNotFound
+	}
+	if err != nil {
+		jobNotFound(c, err)
+		return
+	}
+	report, err := backup.LoadReport(snap.ReportPath)
+	if err != nil {
+		jobNotFound(c, err)
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" && strings.Contains(c.GetHeader("Accept"), "text/html") {
+		format = "html"
+	}
+	if c.Query("download") != "" {
+		ext := ".json"
+		if format == "html" {
+			ext = ".html"
+		}
+		c.Header("Content-Disposition", `attachment; filename="report-`+strconv.FormatUint(snap.ID, 10)+ext+`"`)
+	}
+
+	if format != "html" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	data := struct {
+		ID uint64
+		*backup.Report
+	}{snap.ID, report}
+	if err := reportTemplate.Execute(c.Writer, data); err != nil {
+		logger.Error("Error generando informe HTML", "job", c.Param("id"), "error", err)
+	}
+}
+
+// getJobLog - Descarga el log propio del trabajo
+func getJobLog(c *gin.Context) {
+	snap, err := findJobSnapshot(c.Param("id"))
+	if err == nil && !ownsSnapshot(c, snap) {
+		err = history.ErrNotFound
+	}
+	if err != nil {
+		jobNotFound(c, err)
+		return
+	}
+	if snap.LogPath == "" {
+		jobNotFound(c, os.ErrNotExist)
+		return
+	}
+	if _, err := os.Stat(snap.LogPath); err != nil {
+		jobNotFound(c, err)
+		return
+	}
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="`+filepath.Base(snap.LogPath)+`"`)
+	c.File(snap.LogPath)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"size": formatBytes,
+	"seconds": func(s float64) string {
+		return strconv.FormatFloat(s, 'f', 2, 64) + " s"
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="UTF-8">
+<title>Informe de backup #{{.ID}}</title>
+<link rel="stylesheet" href="/static/stats.css">
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; }
+code { font-size: 0.85em; }
+</style>
+</head>
+<body>
+<h1>Informe de backup #{{.ID}}</h1>
+<p><a href="/stats.html">← Estadísticas</a> ·
+<a href="/api/jobs/{{.ID}}/report?format=json&download=1">JSON</a> ·
+<a href="/api/jobs/{{.ID}}/log">Log del trabajo</a></p>
+
+<table>
+<tr><th>Trabajo</th><td>{{.JobID}}</td></tr>
+{{if .SessionID}}<tr><th>Sesión</th><td>{{.SessionID}}</td></tr>{{end}}
+<tr><th>Tipo</th><td>{{.BackupType}}</td></tr>
+<tr><th>Estado</th><td class="status-{{.Status}}">{{.Status}}</td></tr>
+{{if .Error}}<tr><th>Error</th><td>{{.Error}}</td></tr>{{end}}
+<tr><th>Origen</th><td>{{.Source}}</td></tr>
+<tr><th>Destino</th><td>{{.Dest}}</td></tr>
+{{if .Archive}}<tr><th>Archivo</th><td>{{.Archive}}</td></tr>{{end}}
+<tr><th>Inicio</th><td>{{.StartedAt.Format "2006-01-02 15:04:05"}}</td></tr>
+<tr><th>Fin</th><td>{{.FinishedAt.Format "2006-01-02 15:04:05"}}</td></tr>
+<tr><th>Duración</th><td>{{seconds .Duration}}</td></tr>
+{{range $phase, $secs := .Phases}}<tr><th>Fase {{$phase}}</th><td>{{seconds $secs}}</td></tr>{{end}}
+<tr><th>Archivos</th><td>{{.FilesProcessed}} copiados, {{.FilesSkipped}} omitidos, {{.FilesFailed}} con error</td></tr>
+<tr><th>Leído / Escrito</th><td>{{size .BytesRead}} / {{size .BytesWritten}}</td></tr>
+</table>
+
+{{if .Failures}}
+<h2>Archivos con error</h2>
+<table>
+<tr><th>Archivo</th><th>Motivo</th></tr>
+{{range .Failures}}<tr><td>{{.Path}}</td><td>{{.Reason}}</td></tr>{{end}}
+</table>
+{{end}}
+
+<h2>Archivos copiados</h2>
+{{if .Files}}
+<table>
+<tr><th>Archivo</th><th>Tamaño</th><th>SHA256</th></tr>
+{{range .Files}}<tr><td>{{.Path}}</td><td>{{size .Size}}</td><td><code>{{.SHA256}}</code></td></tr>{{end}}
+</table>
+{{else}}
+<p>Ningún archivo copiado.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// formatBytes muestra un tamaño en la unidad más adecuada
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
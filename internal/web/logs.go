@@ -0,0 +1,128 @@
+package web
+
+import (
+	"fmt"
+	"gobackup/internal/auth"
+	"gobackup/internal/logger"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Máximo de entradas devueltas por /api/logs
+const maxLogLimit = 5000
+
+// RegisterLogRoutes registra la consulta de logs y el seguimiento en vivo
+func RegisterLogRoutes(router *gin.Engine) {
+	router.GET("/api/logs", requireScope(auth.ScopeAdmin), expensive(), getLogs)
+	router.GET("/api/logs/stream", requireScope(auth.ScopeAdmin), streamLogs)
+}
+
+// parseLogFilter arma el filtro a partir de los parámetros
+// level, since, until (RFC3339 o duración como "1h"), job y q
+func parseLogFilter(c *gin.Context) (logger.Filter, error) {
+	var f logger.Filter
+
+	if level := c.Query("level"); level != "" {
+		l, err := logger.ParseLevel(level)
+		if err != nil {
+			return f, err
+		}
+		f.MinLevel = l
+	}
+
+	var err error
+	if f.Since, err = logger.ParseSince(c.Query("since")); err != nil {
+		return f, fmt.Errorf("since inválido: %w", err)
+	}
+	if f.Until, err = logger.ParseSince(c.Query("until")); err != nil {
+		return f, fmt.Errorf("until inválido: %w", err)
+	}
+
+	f.JobID = c.Query("job")
+	f.Text = c.Query("q")
+	return f, nil
+}
+
+// getLogs - Devuelve las entradas persistidas que cumplen los filtros
+func getLogs(c *gin.Context) {
+	f, err := parseLogFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	f.Limit = 500
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit inválido"})
+			return
+		}
+		f.Limit = n
+	}
+	if f.Limit > maxLogLimit {
+		f.Limit = maxLogLimit
+	}
+
+	entries, err := logger.ReadFiles(logger.Dir(), f)
+	if err != nil {
+		logger.Error("Error leyendo logs", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo logs"})
+		return
+	}
+	if entries == nil {
+		entries = []logger.LogEntry{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// streamLogs - Envía las entradas nuevas como Server-Sent Events hasta que el cliente corte
+func streamLogs(c *gin.Context) {
+	f, err := parseLogFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries := make(chan logger.LogEntry, 100)
+	ctx := c.Request.Context()
+	go func() {
+		defer close(entries)
+		logger.Follow(ctx, logger.Dir(), f, func(e logger.LogEntry) {
+			select {
+			case entries <- e:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	// Comentario periódico para que proxies no corten la conexión
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-entries:
+			if !ok {
+				return false
+			}
+			c.SSEvent("log", e)
+		case <-keepalive.C:
+			io.WriteString(w, ": keepalive\n\n")
+		case <-ctx.Done():
+			return false
+		}
+		return true
+	})
+}
@@ -0,0 +1,201 @@
+package web
+
+import (
+	"bytes"
+	"gobackup/internal/auth"
+	"gobackup/internal/backup"
+	"gobackup/internal/disk"
+	"gobackup/internal/history"
+	"gobackup/internal/logger"
+	"gobackup/internal/metrics"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Métricas derivadas del historial y del disco, recalculadas en cada scrape
+var (
+	backupsTotal = metrics.NewCounterVec("gobackup_backups_total",
+		"Backups registrados en el historial por estado.", "status")
+	lastSuccess = metrics.NewGaugeVec("gobackup_last_success_timestamp_seconds",
+		"Fecha (unix) del último backup exitoso de cada trabajo (las sesiones de subida van juntas en session:<perfil>).", "job")
+	lastRun = metrics.NewGaugeVec("gobackup_last_run_timestamp_seconds",
+		"Fecha (unix) de la última ejecución de cada trabajo (las sesiones de subida van juntas en session:<perfil>).", "job")
+	backupBytes = metrics.NewCounterVec("gobackup_backup_bytes_total",
+		"Bytes procesados por los backups (read = origen, written = archivo generado).", "direction")
+	backupFiles = metrics.NewCounterVec("gobackup_backup_files_total",
+		"Archivos procesados por los backups según resultado.", "result")
+	backupDuration = metrics.NewHistogramVec("gobackup_backup_duration_seconds",
+		"Duración de los backups.", metrics.BackupBuckets, "status")
+	uploadSessions = metrics.NewGaugeVec("gobackup_upload_sessions",
+		"Sesiones de subida presentes en uploads_dir.")
+	uploadBytes = metrics.NewGaugeVec("gobackup_upload_bytes",
+		"Tamaño total de las sesiones de subida presentes en uploads_dir.")
+	diskBytes = metrics.NewGaugeVec("gobackup_disk_bytes",
+		"Espacio del disco de cada directorio configurado.", "dir", "kind")
+	diskInodes = metrics.NewGaugeVec("gobackup_disk_inodes",
+		"Inodos del disco de cada directorio configurado.", "dir", "kind")
+
+	// Serializa los scrapes para que el recálculo no se mezcle
+	scrapeMu sync.Mutex
+
+	// Último snapshot ya sumado: cada scrape lee solo los nuevos (el historial solo crece)
+	lastSnapshotID uint64
+	lastRunTimes   = map[string]float64{}
+	lastOKTimes    = map[string]float64{}
+	historyTotals  struct{ read, written, copied, failed, skipped int64 }
+)
+
+func init() {
+	metrics.Default.Register(backupsTotal, lastSuccess, lastRun, backupBytes, backupFiles,
+		backupDuration, uploadSessions, uploadBytes, diskBytes, diskInodes)
+}
+
+// RegisterMetricsRoutes registra el endpoint de Prometheus y el middleware HTTP
+func RegisterMetricsRoutes(router *gin.Engine) {
+	router.GET("/metrics", requireScope(auth.ScopeAdmin), expensive(), getMetrics)
+}
+
+// metricsMiddleware - Cuenta peticiones y mide su duración por ruta
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		// Usar el patrón de la ruta para no crear una serie por cada ID
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequests.Inc(c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+		metrics.HTTPDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route)
+	}
+}
+
+// getMetrics - Expone las métricas en formato de texto de Prometheus
+func getMetrics(c *gin.Context) {
+	scrapeMu.Lock()
+	defer scrapeMu.Unlock()
+
+	if err := collectHistoryMetrics(); err != nil {
+		logger.Warnf("Métricas: error leyendo historial: %v", err)
+	}
+	collectUploadMetrics()
+	collectDiskMetrics()
+
+	var buf bytes.Buffer
+	if err := metrics.Default.Write(&buf); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", buf.Bytes())
+}
+
+func collectHistoryMetrics() error {
+	snapshots, err := history.SnapshotsAfter(lastSnapshotID)
+	if err != nil {
+		return err
+	}
+	if lastSnapshotID == 0 {
+		// Los estados conocidos siempre aparecen, aunque sea en cero
+		for _, status := range []string{history.StatusSuccess, history.StatusPartial, history.StatusFailed, history.StatusCancelled} {
+			backupsTotal.Add(0, status)
+		}
+	}
+
+	t := &historyTotals
+	for _, snap := range snapshots {
+		lastSnapshotID = snap.ID
+		backupsTotal.Inc(snap.Status)
+		backupDuration.Observe(snap.Duration, snap.Status)
+		job, ts := metricsJob(snap), float64(snap.Timestamp.Unix())
+		if ts > lastRunTimes[job] {
+			lastRunTimes[job] = ts
+			lastRun.Set(ts, job)
+		}
+		if snap.Status == history.StatusSuccess && ts > lastOKTimes[job] {
+			lastOKTimes[job] = ts
+			lastSuccess.Set(ts, job)
+		}
+		t.read += snap.BytesRead
+		t.written += snap.BytesWritten
+		t.copied += int64(snap.FilesCount)
+		t.failed += int64(snap.FilesFailed)
+		t.skipped += int64(snap.FilesSkipped)
+	}
+
+	backupBytes.Set(float64(t.read), "read")
+	backupBytes.Set(float64(t.written), "written")
+	backupFiles.Set(float64(t.copied), "copied")
+	backupFiles.Set(float64(t.failed), "failed")
+	backupFiles.Set(float64(t.skipped), "skipped")
+	return nil
+}
+
+// metricsJob es la etiqueta job de un snapshot. Cada sesión de subida tiene un
+// ID al azar: con una serie por sesión las métricas crecerían sin fin, así que
+// van juntas por perfil.
+func metricsJob(snap history.Snapshot) string {
+	if snap.BackupType != "session" {
+		return snap.JobID
+	}
+	if snap.Profile == "" {
+		return "session"
+	}
+	return "session:" + snap.Profile
+}
+
+func collectUploadMetrics() {
+	entries, err := os.ReadDir(backup.UploadsDir)
+	if err != nil {
+		uploadSessions.Set(0)
+		uploadBytes.Set(0)
+		return
+	}
+
+	// Un total y no una serie por sesión: los IDs son aleatorios
+	sessions, size := 0, int64(0)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		sessions++
+		filepath.Walk(filepath.Join(backup.UploadsDir, entry.Name()), func(_ string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				size += info.Size()
+			}
+			return nil
+		})
+	}
+	uploadSessions.Set(float64(sessions))
+	uploadBytes.Set(float64(size))
+}
+
+func collectDiskMetrics() {
+	diskBytes.Reset()
+	diskInodes.Reset()
+	dirs := map[string]string{
+		"backups_dir": backup.BackupsDir,
+		"uploads_dir": backup.UploadsDir,
+		"temp_dir":    backup.TempDir,
+	}
+	for name, path := range dirs {
+		if path == "" {
+			continue
+		}
+		u, err := disk.GetUsage(path)
+		if err != nil {
+			continue
+		}
+		diskBytes.Set(float64(u.Total), name, "total")
+		diskBytes.Set(float64(u.Used), name, "used")
+		diskBytes.Set(float64(u.Free), name, "free")
+		diskInodes.Set(float64(u.InodesTotal), name, "total")
+		diskInodes.Set(float64(u.InodesFree), name, "free")
+	}
+}
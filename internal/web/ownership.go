@@ -0,0 +1,77 @@
+package web
+
+import (
+	"gobackup/internal/auth"
+	"gobackup/internal/backup"
+	"gobackup/internal/history"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cada sesión de subida y su backup pertenecen al usuario que la creó (con su
+// sesión o con uno de sus tokens). Los admin ven todo; lo que no tiene dueño
+// (sesiones de la terminal, backups anteriores, trabajos cli y de perfiles) es
+// solo para admin, salvo la fila de los trabajos en el historial y las
+// estadísticas. Lo ajeno se responde como inexistente para no revelar IDs.
+
+// ownsSession indica si quien hace la petición puede usar la sesión id
+func ownsSession(c *gin.Context, id string) bool {
+	p := currentPrincipal(c)
+	if p.can(auth.ScopeAdmin) {
+		return true
+	}
+	owner, ok := backup.GetOwner(id)
+	return ok && owner.User == p.User
+}
+
+// newSessionOwner es el dueño de una sesión creada por esta petición
+func newSessionOwner(c *gin.Context) backup.Owner {
+	p := currentPrincipal(c)
+	o := backup.Owner{User: p.User}
+	if p.Token != nil {
+		o.Token = p.Token.ID
+	}
+	return o
+}
+
+// ownedArchive resuelve el archivo de un backup (por sesión o por snapshot) y
+// devuelve history.ErrNotFound si no es de quien lo pide
+func ownedArchive(c *gin.Context, id string) (string, error) {
+	path, err := backup.ResolveArchive(id)
+	if err != nil {
+		return "", err
+	}
+	if currentPrincipal(c).can(auth.ScopeAdmin) {
+		return path, nil
+	}
+	if sessionID, ok := backup.ArchiveSessionID(path); ok && ownsSession(c, sessionID) {
+		return path, nil
+	}
+	return "", history.ErrNotFound
+}
+
+// ownsSnapshot indica si quien hace la petición puede ver el informe y el log de
+// una ejecución: los de una sesión son de su dueño y los de trabajos cli y de
+// perfiles (rutas de origen, lista de archivos, sumas) solo de los admin
+func ownsSnapshot(c *gin.Context, snap history.Snapshot) bool {
+	if snap.BackupType != "session" {
+		return currentPrincipal(c).can(auth.ScopeAdmin)
+	}
+	return ownsSession(c, snap.SessionID)
+}
+
+// visibleSnapshots deja solo las ejecuciones que puede ver quien hace la
+// petición en el historial y las estadísticas: las sesiones ajenas no, los
+// trabajos cli y de perfiles sí (sin informe ni log, ver ownsSnapshot)
+func visibleSnapshots(c *gin.Context, snapshots []history.Snapshot) []history.Snapshot {
+	if currentPrincipal(c).can(auth.ScopeAdmin) {
+		return snapshots
+	}
+	visible := make([]history.Snapshot, 0, len(snapshots))
+	for _, snap := range snapshots {
+		if snap.BackupType != "session" || ownsSession(c, snap.SessionID) {
+			visible = append(visible, snap)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"gobackup/internal/backup"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterProfileRoutes registra la consulta de los perfiles configurados
+func RegisterProfileRoutes(router *gin.Engine) {
+	router.GET("/api/profiles", getProfiles)
+	router.GET("/api/profiles/:name", getProfile)
+}
+
+// getProfiles - Lista los perfiles y cuál está activo
+func getProfiles(c *gin.Context) {
+	profiles := backup.ListProfiles()
+	c.JSON(http.StatusOK, gin.H{
+		"profiles": profiles,
+		"active":   backup.ActiveProfile().Name,
+		"count":    len(profiles),
+	})
+}
+
+// getProfile - Detalle de un perfil
+func getProfile(c *gin.Context) {
+	profile, ok := backup.GetProfile(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Perfil no encontrado"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
@@ -0,0 +1,240 @@
+package web
+
+import (
+	"gobackup/internal/config"
+	"gobackup/internal/metrics"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Límites de peticiones (rate_limit.*). Se aplican en tres lugares: por IP antes
+// de autenticar (así también frenan a quien prueba contraseñas), por token o
+// usuario después, y con cupos de peticiones simultáneas en las rutas caras.
+
+var rejectedRequests = metrics.NewCounterVec("gobackup_http_rejected_total",
+	"Peticiones rechazadas por los límites de rate_limit.", "reason")
+
+// concurrencyWait es cuánto espera una petición cara a que se libere un cupo
+const concurrencyWait = 2 * time.Second
+
+// rateLimits son los límites activos; SetRateLimits los reemplaza al recargar
+type rateLimits struct {
+	cfg       config.RateLimitConfig
+	ip        *limiter
+	principal *limiter
+	login     *limiter
+	expensive chan struct{} // nil = sin límite
+	uploads   chan struct{}
+}
+
+var activeLimits atomic.Pointer[rateLimits]
+
+func init() {
+	metrics.Default.Register(rejectedRequests)
+	SetRateLimits(config.Defaults().RateLimit)
+}
+
+// SetRateLimits aplica rate_limit.* (lo llama cmd/web.go al arrancar y al
+// recargar). Si cambian, los contadores empiezan de cero.
+func SetRateLimits(cfg config.RateLimitConfig) {
+	if cur := activeLimits.Load(); cur != nil && cur.cfg == cfg {
+		return
+	}
+	activeLimits.Store(&rateLimits{
+		cfg:       cfg,
+		ip:        newLimiter(cfg.IPPerMinute),
+		principal: newLimiter(cfg.TokenPerMinute),
+		login:     newLimiter(cfg.LoginPerMinute),
+		expensive: newSemaphore(cfg.MaxExpensive),
+		uploads:   newSemaphore(cfg.MaxUploads),
+	})
+}
+
+func newSemaphore(n int) chan struct{} {
+	if n <= 0 {
+		return nil
+	}
+	return make(chan struct{}, n)
+}
+
+// limiter es un token bucket por clave: cada una tiene perMinute peticiones
+// que se van reponiendo de a poco
+type limiter struct {
+	mu        sync.Mutex
+	perMinute float64
+	buckets   map[string]*bucket
+	swept     time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(perMinute int) *limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &limiter{perMinute: float64(perMinute), buckets: map[string]*bucket{}, swept: time.Now()}
+}
+
+// allow descuenta una petición de key; si no quedan devuelve cuánto esperar
+func (l *limiter) allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	// Un bucket sin uso por un minuto ya está lleno: no hace falta guardarlo
+	if now.Sub(l.swept) > time.Minute {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > time.Minute {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.perMinute, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.perMinute, b.tokens+now.Sub(b.last).Minutes()*l.perMinute)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.perMinute * float64(time.Minute))
+}
+
+// tooManyRequests responde 429 con Retry-After en segundos
+func tooManyRequests(c *gin.Context, reason string, retry time.Duration) {
+	secs := int(math.Ceil(retry.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	rejectedRequests.Inc(reason)
+	c.Header("Retry-After", strconv.Itoa(secs))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Demasiadas peticiones, intente de nuevo en " + strconv.Itoa(secs) + "s",
+		"retry_after": secs,
+	})
+}
+
+// ipRateLimit limita las peticiones de cada IP (va antes de autenticar)
+func ipRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/static/") {
+			c.Next()
+			return
+		}
+		if ok, retry := activeLimits.Load().ip.allow(c.ClientIP()); !ok {
+			tooManyRequests(c, "ip", retry)
+			return
+		}
+		c.Next()
+	}
+}
+
+// principalRateLimit limita las peticiones de cada token o usuario, desde
+// cualquier IP (va después de autenticar)
+func principalRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := currentPrincipal(c)
+		key := "user:" + p.User
+		if p.Token != nil {
+			key = "token:" + p.Token.ID
+		}
+		if p.User != "" {
+			if ok, retry := activeLimits.Load().principal.allow(key); !ok {
+				tooManyRequests(c, "principal", retry)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// loginRateLimit limita los intentos de inicio de sesión de cada IP
+func loginRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ok, retry := activeLimits.Load().login.allow(c.ClientIP()); !ok {
+			tooManyRequests(c, "login", retry)
+			return
+		}
+		c.Next()
+	}
+}
+
+// bodyLimit corta los cuerpos más grandes que el máximo de su ruta con 413. Las
+// subidas tienen sus propios límites (quotas.*) y los fragmentos de las subidas
+// por partes el suyo.
+func bodyLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := activeLimits.Load().cfg
+		var max int64
+		switch {
+		case c.FullPath() == "/upload":
+		case c.FullPath() == "/upload/chunked/:id" && c.Request.Method == http.MethodPatch:
+			max = int64(cfg.MaxChunkMB) << 20
+		default:
+			max = int64(cfg.MaxBodyKB) << 10
+		}
+		if max > 0 {
+			if c.Request.ContentLength > max {
+				rejectedRequests.Inc("body")
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+					"error": "El cuerpo de la petición supera el máximo de " + strconv.FormatInt(max, 10) + " bytes",
+					"max":   max,
+				})
+				return
+			}
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+		}
+		c.Next()
+	}
+}
+
+// limitConcurrency deja pasar a la vez tantas peticiones como cupos tenga el
+// semáforo que elige pick; las demás esperan un poco y si no reciben 429
+func limitConcurrency(reason string, pick func(*rateLimits) chan struct{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sem := pick(activeLimits.Load())
+		if sem == nil {
+			c.Next()
+			return
+		}
+		timer := time.NewTimer(concurrencyWait)
+		defer timer.Stop()
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			c.Next()
+		case <-timer.C:
+			tooManyRequests(c, reason, time.Second)
+		case <-c.Request.Context().Done():
+			c.Abort()
+		}
+	}
+}
+
+// expensive limita las rutas que leen el historial o archivos completos
+func expensive() gin.HandlerFunc {
+	return limitConcurrency("expensive", func(l *rateLimits) chan struct{} { return l.expensive })
+}
+
+// transfer limita las subidas y descargas simultáneas
+func transfer() gin.HandlerFunc {
+	return limitConcurrency("transfer", func(l *rateLimits) chan struct{} { return l.uploads })
+}
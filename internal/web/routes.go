@@ -1,13 +1,17 @@
 package web
 
 import (
-	"encoding/json"
 	"fmt"
+	"gobackup/internal/audit"
+	"gobackup/internal/auth"
 	"gobackup/internal/backup"
+	"gobackup/internal/disk"
+	"gobackup/internal/history"
+	"gobackup/internal/logger"
 	"net/http"
-	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,43 +19,27 @@ import (
 )
 
 // Estructuras para las respuestas JSON
-type BackupStats struct {
-	Timestamp  time.Time `json:"timestamp"`
-	SessionID  string    `json:"session_id"`
-	TotalSize  int64     `json:"total_size"`
-	FilesCount int       `json:"files_count"`
-	Duration   float64   `json:"duration_seconds"`
-	Status     string    `json:"status"`
-}
-
 type FileTypeStat struct {
 	Type string `json:"type"`
 	Size int64  `json:"size"`
 }
 
 type BackupHistory struct {
-	Backups []BackupStats `json:"backups"`
-}
-
-// diskStats - Estructura para estadísticas de disco
-type diskStats struct {
-	total       uint64
-	used        uint64
-	free        uint64
-	usedPercent float64
+	Backups []history.Snapshot `json:"backups"`
 }
 
 // RegisterStatsRoutes registra las rutas de estadísticas
 func RegisterStatsRoutes(router *gin.Engine) {
-	router.GET("/api/stats/summary", getStatsSummary)
-	router.GET("/api/stats/history", getStatsHistory)
-	router.GET("/api/stats/filetypes", getFileTypeStats)
-	router.GET("/api/system", getSystemInfo)
+	router.GET("/api/stats/summary", expensive(), getStatsSummary)
+	router.GET("/api/stats/history", expensive(), getStatsHistory)
+	router.GET("/api/stats/filetypes", expensive(), getFileTypeStats)
+	router.GET("/api/stats/failures", expensive(), getFailureStats)
+	router.GET("/api/system", requireScope(auth.ScopeAdmin), getSystemInfo)
 }
 
 // getStatsSummary - Handler para estadísticas generales
 func getStatsSummary(c *gin.Context) {
-	history, err := loadBackupHistory()
+	history, err := loadBackupHistory(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -60,14 +48,16 @@ func getStatsSummary(c *gin.Context) {
 	// Si no hay backups, retornar datos vacíos
 	if len(history.Backups) == 0 {
 		c.JSON(http.StatusOK, gin.H{
-			"total_backups": 0,
-			"total_size_mb": "0 MB",
-			"avg_size_mb":   "0 MB",
-			"avg_duration":  "0s",
-			"backups_trend": "+0 en la última semana",
-			"space_trend":   "+0 MB desde el último mes",
-			"max_size":      "Máximo: 0 MB",
-			"min_duration":  "Más rápido: 0s",
+			"total_backups":  0,
+			"total_size_mb":  "0 MB",
+			"avg_size_mb":    "0 MB",
+			"avg_duration":   "0s",
+			"backups_trend":  "+0 en la última semana",
+			"space_trend":    "+0 MB desde el último mes",
+			"max_size":       "Máximo: 0 MB",
+			"min_duration":   "Más rápido: 0s",
+			"failed_backups": 0,
+			"failure_rate":   "0.0%",
 		})
 		return
 	}
@@ -110,26 +100,30 @@ func getStatsSummary(c *gin.Context) {
 		}
 	}
 
+	failed := countFailed(history.Backups)
+
 	trendText := fmt.Sprintf("+%d en la última semana", recentBackups)
 	spaceText := fmt.Sprintf("+%.2f MB desde el último mes", float64(recentSize)/1024/1024)
 
 	c.JSON(http.StatusOK, gin.H{
-		"total_backups": totalBackups,
-		"total_size":    totalSize,
-		"total_size_mb": fmt.Sprintf("%.2f MB", float64(totalSize)/1024/1024),
-		"avg_size":      avgSize,
-		"avg_size_mb":   fmt.Sprintf("%.2f MB", float64(avgSize)/1024/1024),
-		"avg_duration":  fmt.Sprintf("%.2f seg", avgDuration),
-		"backups_trend": trendText,
-		"space_trend":   spaceText,
-		"max_size":      fmt.Sprintf("Máximo: %.2f MB", float64(maxSize)/1024/1024),
-		"min_duration":  fmt.Sprintf("Más rápido: %.2f seg", minDuration),
+		"total_backups":  totalBackups,
+		"total_size":     totalSize,
+		"total_size_mb":  fmt.Sprintf("%.2f MB", float64(totalSize)/1024/1024),
+		"avg_size":       avgSize,
+		"avg_size_mb":    fmt.Sprintf("%.2f MB", float64(avgSize)/1024/1024),
+		"avg_duration":   fmt.Sprintf("%.2f seg", avgDuration),
+		"backups_trend":  trendText,
+		"space_trend":    spaceText,
+		"max_size":       fmt.Sprintf("Máximo: %.2f MB", float64(maxSize)/1024/1024),
+		"min_duration":   fmt.Sprintf("Más rápido: %.2f seg", minDuration),
+		"failed_backups": failed,
+		"failure_rate":   fmt.Sprintf("%.1f%%", float64(failed)/float64(totalBackups)*100),
 	})
 }
 
 // getStatsHistory - Handler para historial de backups
 func getStatsHistory(c *gin.Context) {
-	history, err := loadBackupHistory()
+	history, err := loadBackupHistory(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -142,62 +136,127 @@ func getStatsHistory(c *gin.Context) {
 
 // getFileTypeStats - Handler para tipos de archivo
 func getFileTypeStats(c *gin.Context) {
-	fmt.Println("[DEBUG] getFileTypeStats: Iniciando handler")
-	history, err := loadBackupHistory()
+	history, err := loadBackupHistory(c)
 	if err != nil {
-		fmt.Println("[ERROR] loadBackupHistory:", err)
+		logger.Error("Error cargando historial", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	fmt.Printf("[DEBUG] Backups cargados: %d\n", len(history.Backups))
-	fileTypes := calculateFileTypeDistribution(history)
-	fmt.Printf("[DEBUG] Tipos de archivo calculados: %d\n", len(fileTypes))
+	fileTypes, err := calculateFileTypeDistribution(history)
+	if err != nil {
+		logger.Error("Error calculando tipos de archivo", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	var totalSize int64
 	for _, ft := range fileTypes {
 		totalSize += ft.Size
 	}
 
-	fmt.Printf("[DEBUG] Tamaño total de archivos: %d\n", totalSize)
+	logger.Debug("Distribución de tipos calculada",
+		"backups", len(history.Backups), "types", len(fileTypes), "bytes", totalSize)
 	c.JSON(http.StatusOK, gin.H{
 		"file_types": fileTypes,
 		"total_size": totalSize,
 	})
 }
 
-// loadBackupHistory - Carga el historial de backups desde el archivo JSON
-func loadBackupHistory() (BackupHistory, error) {
-	var fullHistory struct {
-		Backups []BackupStats `json:"backups"`
-		Files   []interface{} `json:"files"` // Ignoramos la sección de files por ahora
+// failureDay - Conteo de backups por estado en un día
+type failureDay struct {
+	Date        string  `json:"date"`
+	Success     int     `json:"success"`
+	Partial     int     `json:"partial"`
+	Failed      int     `json:"failed"`
+	Cancelled   int     `json:"cancelled"`
+	Total       int     `json:"total"`
+	FailureRate float64 `json:"failure_rate"`
+}
+
+// getFailureStats - Handler para la tasa de fallos por día (?days=30)
+func getFailureStats(c *gin.Context) {
+	days := 30
+	if d, err := strconv.Atoi(c.Query("days")); err == nil && d > 0 {
+		days = d
 	}
 
-	// Usar la variable BackupsDir del paquete backup
-	backupsDir := backup.BackupsDir
-	if backupsDir == "" {
-		backupsDir = "backups" // Valor por defecto
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day()-days+1, 0, 0, 0, 0, now.Location())
+	snapshots, err := history.Snapshots(history.Query{Since: since})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	snapshots = visibleSnapshots(c, snapshots)
+
+	// Un bucket por día, incluidos los días sin backups
+	byDate := make(map[string]*failureDay)
+	var result []*failureDay
+	for d := since; !d.After(now); d = d.AddDate(0, 0, 1) {
+		day := &failureDay{Date: d.Format("2006-01-02")}
+		byDate[day.Date] = day
+		result = append(result, day)
 	}
 
-	historyFile := filepath.Join(backupsDir, "backup_history.json")
+	for _, snap := range snapshots {
+		day, ok := byDate[snap.Timestamp.Local().Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		day.Total++
+		switch snap.Status {
+		case history.StatusSuccess:
+			day.Success++
+		case history.StatusPartial:
+			day.Partial++
+		case history.StatusFailed:
+			day.Failed++
+		case history.StatusCancelled:
+			day.Cancelled++
+		}
+	}
 
-	// Verificar si el archivo existe
-	if _, err := os.Stat(historyFile); os.IsNotExist(err) {
-		// Si el archivo no existe, retornar historial vacío
-		return BackupHistory{Backups: []BackupStats{}}, nil
+	for _, day := range result {
+		if day.Total > 0 {
+			day.FailureRate = float64(day.Failed) / float64(day.Total) * 100
+		}
 	}
 
-	data, err := os.ReadFile(historyFile)
-	if err != nil {
-		return BackupHistory{}, fmt.Errorf("error leyendo archivo de historial: %v", err)
+	failed := countFailed(snapshots)
+	overall := 0.0
+	if len(snapshots) > 0 {
+		overall = float64(failed) / float64(len(snapshots)) * 100
 	}
 
-	err = json.Unmarshal(data, &fullHistory)
+	c.JSON(http.StatusOK, gin.H{
+		"days":         result,
+		"total":        len(snapshots),
+		"failed":       failed,
+		"failure_rate": overall,
+	})
+}
+
+// countFailed - Cuenta los backups fallidos
+func countFailed(snapshots []history.Snapshot) int {
+	failed := 0
+	for _, snap := range snapshots {
+		if snap.Status == history.StatusFailed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// loadBackupHistory - Carga el historial de backups desde la base de historial
+// (solo las ejecuciones que puede ver quien hace la petición)
+func loadBackupHistory(c *gin.Context) (BackupHistory, error) {
+	snapshots, err := history.Snapshots(history.Query{})
 	if err != nil {
-		return BackupHistory{}, fmt.Errorf("error decodificando JSON: %v", err)
+		return BackupHistory{}, fmt.Errorf("error leyendo historial: %v", err)
 	}
 
-	return BackupHistory{Backups: fullHistory.Backups}, nil
+	return BackupHistory{Backups: visibleSnapshots(c, snapshots)}, nil
 }
 
 // getFileCategory - Determina la categoría basada en la extensión del archivo
@@ -223,49 +282,22 @@ func getFileCategory(ext string) string {
 }
 
 // calculateFileTypeDistribution - Calcula la distribución REAL de tipos de archivo
-func calculateFileTypeDistribution(history BackupHistory) []FileTypeStat {
-	if len(history.Backups) == 0 {
-		return []FileTypeStat{}
-	}
-
-	// Cargar información detallada de archivos desde el JSON
-	backupsDir := backup.BackupsDir
-	if backupsDir == "" {
-		backupsDir = "backups"
-	}
-
-	historyFile := filepath.Join(backupsDir, "backup_history.json")
-
-	var fullHistory struct {
-		Backups []BackupStats `json:"backups"`
-		Files   []struct {
-			Path string `json:"path"`
-			Size int64  `json:"size"`
-		} `json:"files"`
-	}
-
-	data, err := os.ReadFile(historyFile)
-	if err != nil {
-		// Fallback a distribución simulada si hay error
-		return calculateSimulatedFileTypeDistribution(history)
-	}
-
-	err = json.Unmarshal(data, &fullHistory)
-	if err != nil {
-		return calculateSimulatedFileTypeDistribution(history)
-	}
-
-	// Calcular distribución real basada en extensiones de archivo
+// con los archivos de las ejecuciones recibidas
+func calculateFileTypeDistribution(backupHistory BackupHistory) ([]FileTypeStat, error) {
 	typeStats := make(map[string]int64)
-
-	for _, file := range fullHistory.Files {
-		ext := strings.ToLower(filepath.Ext(file.Path))
-		category := getFileCategory(ext)
-		typeStats[category] += file.Size
+	for _, snap := range backupHistory.Backups {
+		files, err := history.Files(snap.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo archivos del backup %d: %v", snap.ID, err)
+		}
+		for _, file := range files {
+			ext := strings.ToLower(filepath.Ext(file.Path))
+			typeStats[getFileCategory(ext)] += file.Size
+		}
 	}
 
 	// Convertir a slice de FileTypeStat
-	var result []FileTypeStat
+	result := []FileTypeStat{}
 	for category, size := range typeStats {
 		result = append(result, FileTypeStat{Type: category, Size: size})
 	}
@@ -275,119 +307,119 @@ func calculateFileTypeDistribution(history BackupHistory) []FileTypeStat {
 		return result[i].Size > result[j].Size
 	})
 
-	return result
+	return result, nil
 }
 
-// calculateSimulatedFileTypeDistribution - Fallback a distribución simulada
-func calculateSimulatedFileTypeDistribution(history BackupHistory) []FileTypeStat {
-	if len(history.Backups) == 0 {
-		return []FileTypeStat{}
-	}
+// Días de historial usados para proyectar el crecimiento del disco
+const growthWindowDays = 30
 
-	// Distribución aproximada basada en datos comunes
-	fileTypes := []FileTypeStat{
-		{Type: "Documentos", Size: 0},
-		{Type: "Imágenes", Size: 0},
-		{Type: "Videos", Size: 0},
-		{Type: "Audio", Size: 0},
-		{Type: "Archivos comprimidos", Size: 0},
-		{Type: "Otros", Size: 0},
-	}
+// dirUsage - Uso de disco de un directorio configurado
+type dirUsage struct {
+	Name string `json:"name"`
+	disk.Usage
+	Error string `json:"error,omitempty"`
+}
 
-	// Distribuir el tamaño total entre los tipos de archivo
-	var totalSize int64
-	for _, backupItem := range history.Backups {
-		totalSize += backupItem.TotalSize
-	}
+// diskProjection - Proyección de llenado del disco de backups
+type diskProjection struct {
+	DailyGrowth   float64 `json:"daily_growth_bytes"`
+	DaysUntilFull float64 `json:"days_until_full"` // -1 si no hay crecimiento
+	Message       string  `json:"message"`
+}
 
-	if totalSize > 0 {
-		// Distribución porcentual aproximada
-		fileTypes[0].Size = totalSize * 40 / 100 // Documentos: 40%
-		fileTypes[1].Size = totalSize * 25 / 100 // Imágenes: 25%
-		fileTypes[2].Size = totalSize * 15 / 100 // Videos: 15%
-		fileTypes[3].Size = totalSize * 10 / 100 // Audio: 10%
-		fileTypes[4].Size = totalSize * 5 / 100  // Comprimidos: 5%
-		fileTypes[5].Size = totalSize * 5 / 100  // Otros: 5%
+// projectDiskFull - Estima en cuántos días se llena el disco según el historial reciente
+func projectDiskFull(free uint64) diskProjection {
+	since := time.Now().AddDate(0, 0, -growthWindowDays)
+	snapshots, err := history.Snapshots(history.Query{Since: since})
+	if err != nil || len(snapshots) == 0 {
+		return diskProjection{DaysUntilFull: -1, Message: "Sin datos suficientes para proyectar"}
 	}
 
-	// Filtrar tipos de archivo con tamaño cero
-	var result []FileTypeStat
-	for _, ft := range fileTypes {
-		if ft.Size > 0 {
-			result = append(result, ft)
-		}
+	var written int64
+	for _, snap := range snapshots {
+		written += snap.BytesWritten
 	}
 
-	return result
-}
-
-// getDiskUsage - Obtiene el uso del disco para una ruta (implementación básica)
-func getDiskUsage(path string) (diskStats, error) {
-	var stat diskStats
-
-	// Intentar obtener información del directorio de backups
-	if _, err := os.Stat(path); err == nil {
-		// Simular algunos valores basados en el directorio
-		// En una implementación real, usarías syscall.Statfs o paquetes específicos
-		stat.total = 500 * 1024 * 1024 * 1024 // 500 GB
-		stat.used = 187 * 1024 * 1024 * 1024  // 187 GB
-		stat.free = stat.total - stat.used
-		stat.usedPercent = float64(stat.used) / float64(stat.total) * 100
-
-		return stat, nil
+	// Promediar sobre el período realmente cubierto (al menos un día)
+	days := time.Since(snapshots[0].Timestamp).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+	daily := float64(written) / days
+	if daily <= 0 {
+		return diskProjection{DaysUntilFull: -1, Message: "Sin crecimiento en los últimos 30 días"}
 	}
 
-	return stat, fmt.Errorf("no se pudo obtener información del disco")
+	remaining := float64(free) / daily
+	return diskProjection{
+		DailyGrowth:   daily,
+		DaysUntilFull: remaining,
+		Message:       fmt.Sprintf("Disco lleno en %.0f días", remaining),
+	}
 }
 
 // getSystemInfo - Obtiene información REAL del sistema
 func getSystemInfo(c *gin.Context) {
-	// Obtener información real del espacio en disco
-	backupsDir := backup.BackupsDir
-	if backupsDir == "" {
-		backupsDir = "backups"
-	}
-
-	// Usar el directorio de backups como referencia para el espacio en disco
-	var total, used, free uint64
-	var usedPercent float64
-
-	// Intentar obtener información real del disco
-	if stat, err := getDiskUsage(backupsDir); err == nil {
-		total = stat.total
-		used = stat.used
-		free = stat.free
-		usedPercent = stat.usedPercent
-	} else {
-		// Fallback a valores por defecto
-		total = 500 * 1024 * 1024 * 1024 // 500 GB
-		used = 187 * 1024 * 1024 * 1024  // 187 GB
-		free = total - used
-		usedPercent = 37.4
+	dirs := []struct{ name, path string }{
+		{"backups_dir", backup.BackupsDir},
+		{"uploads_dir", backup.UploadsDir},
+		{"temp_dir", backup.TempDir},
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"disk_space": gin.H{
-			"total":        fmt.Sprintf("%.1f GB", float64(total)/1024/1024/1024),
-			"used":         fmt.Sprintf("%.1f GB", float64(used)/1024/1024/1024),
-			"free":         fmt.Sprintf("%.1f GB", float64(free)/1024/1024/1024),
-			"used_percent": fmt.Sprintf("%.1f%%", usedPercent),
-		},
-		"backup_dir":  backupsDir,
+	var usages []dirUsage
+	for _, d := range dirs {
+		if d.path == "" {
+			continue
+		}
+		u, err := disk.GetUsage(d.path)
+		item := dirUsage{Name: d.name, Usage: u}
+		if err != nil {
+			item.Path = d.path
+			item.Error = err.Error()
+		}
+		usages = append(usages, item)
+	}
+
+	// El directorio de backups es la referencia principal
+	response := gin.H{
+		"directories": usages,
+		"backup_dir":  backup.BackupsDir,
 		"server_time": time.Now().Format("2006-01-02 15:04:05"),
 		"version":     "Gobackup Web v1.0",
-	})
+	}
+
+	if u, err := disk.GetUsage(backup.BackupsDir); err == nil {
+		response["disk_space"] = gin.H{
+			"total":        fmt.Sprintf("%.1f GB", float64(u.Total)/1024/1024/1024),
+			"used":         fmt.Sprintf("%.1f GB", float64(u.Used)/1024/1024/1024),
+			"free":         fmt.Sprintf("%.1f GB", float64(u.Free)/1024/1024/1024),
+			"used_percent": fmt.Sprintf("%.1f%%", u.UsedPercent),
+		}
+		response["projection"] = projectDiskFull(u.Free)
+	} else {
+		response["disk_error"] = err.Error()
+	}
+	if ConfigWatcher != nil {
+		response["config"] = ConfigWatcher.Info()
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
-// getBackupStatus - Obtiene el estado actual del backup
+// getBackupStatus - Obtiene el estado actual del backup. Si el último backup es
+// de otro usuario solo se informa si sigue en curso, sin el detalle.
 func getBackupStatus(c *gin.Context) {
 	status := backup.Status.Get()
+	if !ownsSession(c, backup.CurrentSessionID) {
+		status = backup.BackupStatus{InProgress: status.InProgress}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"TotalFiles":  status.TotalFiles,
 		"FilesCopied": status.FilesCopied,
 		"Errors":      status.Errors,
 		"InProgress":  status.InProgress,
+		"Draining":    backup.Draining(), // el servidor se está apagando
 	})
 }
 
@@ -402,9 +434,15 @@ func getBackupList(c *gin.Context) {
 	// Obtener información detallada de cada backup
 	var backupList []map[string]interface{}
 	for _, backupFile := range backups {
-		sessionID := backupFile[:len(backupFile)-4] // Remover .zip
+		sessionID := backup.TrimArchiveExt(backupFile)
+		if !ownsSession(c, sessionID) {
+			continue
+		}
 		info, err := backup.GetBackupInfo(sessionID)
 		if err == nil {
+			if owner, ok := backup.GetOwner(sessionID); ok {
+				info["owner"] = owner.User
+			}
 			backupList = append(backupList, info)
 		}
 	}
@@ -415,33 +453,83 @@ func getBackupList(c *gin.Context) {
 	})
 }
 
-// getDiskSpace - Función simulada para obtener espacio en disco
-func getDiskSpace() (uint64, uint64, uint64) {
-	// Implementación simulada - retorna valores por defecto
-	return 0, 0, 0
-}
-
 // RegisterBackupRoutes - Registra las rutas de backup (versión básica)
 func RegisterBackupRoutes(router *gin.Engine) {
 	// Rutas básicas de backup - puedes expandir esto según necesites
 	backupRoutes := router.Group("/api/backup")
 	{
-		backupRoutes.POST("/create", func(c *gin.Context) {
+		backupRoutes.POST("/create", requireScope(auth.ScopeBackup), func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"message": "Funcionalidad de backup no implementada"})
 		})
 		backupRoutes.GET("/list", getBackupList)
-		backupRoutes.DELETE("/:id", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{"message": "Funcionalidad de eliminación no implementada"})
-		})
+		backupRoutes.DELETE("/:id", requireScope(auth.ScopeAdmin), deleteBackup)
+	}
+}
+
+// deleteBackup - Borra el backup de una sesión (archivo, log e informe) y su subida (solo admin)
+func deleteBackup(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := backup.SessionDir(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !backup.BackupExists(id) || !ownsSession(c, id) {
+		recordAudit(c, audit.ActionDelete, id, audit.Denied, "no encontrado o ajeno")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup no encontrado"})
+		return
+	}
+	if err := backup.RemoveSession(id, true); err != nil {
+		recordAudit(c, audit.ActionDelete, id, audit.Failure, err.Error())
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
 	}
+	logger.Info("Backup borrado desde la web", "session_id", id, "user", currentPrincipal(c).actor())
+	recordAudit(c, audit.ActionDelete, id, audit.Success, "")
+	c.JSON(http.StatusOK, gin.H{"message": "Backup eliminado", "sessionId": id})
 }
 func RegisterAllRoutes(router *gin.Engine) {
 	// Rutas de API básicas
 	router.GET("/api/status", getBackupStatus)
 
+	// Inicio y cierre de sesión
+	RegisterAuthRoutes(router)
+
+	// Tokens de API para scripts
+	RegisterTokenRoutes(router)
+
+	// Subidas, backup por sesión y descarga (interfaz drag & drop)
+	RegisterUploadRoutes(router)
+
+	// Subidas por partes que se pueden retomar
+	RegisterChunkedUploadRoutes(router)
+
+	// Listado y borrado de backups
+	RegisterBackupRoutes(router)
+
 	// Rutas de estadísticas (incluye /api/system)
 	RegisterStatsRoutes(router)
 
+	// Métricas para Prometheus
+	RegisterMetricsRoutes(router)
+
+	// Consulta y seguimiento de logs
+	RegisterLogRoutes(router)
+
+	// Informes y logs de cada trabajo
+	RegisterJobRoutes(router)
+
+	// Explorador de archivos de los backups
+	RegisterBrowseRoutes(router)
+
+	// Perfiles de backup configurados
+	RegisterProfileRoutes(router)
+
+	// Registro de auditoría
+	RegisterAuditRoutes(router)
+
+	// Webhooks y sus envíos
+	RegisterWebhookRoutes(router)
+
 	// Servir archivos estáticos
 	router.Static("/static", "./internal/web/static")
 	router.GET("/", func(c *gin.Context) {
@@ -452,7 +540,7 @@ func RegisterAllRoutes(router *gin.Engine) {
 	})
 
 	// Ruta de prueba para debug
-	router.GET("/api/debug", func(c *gin.Context) {
+	router.GET("/api/debug", requireScope(auth.ScopeAdmin), func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "API funcionando",
 			"time":    "now",
@@ -1,20 +1,160 @@
 package web
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"gobackup/internal/backup"
+	"gobackup/internal/config"
+	"gobackup/internal/history"
+	"gobackup/internal/logger"
+	"gobackup/internal/webhook"
+	"net"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// StartServer inicia el servidor web de Gobackup
-func StartServer() {
-	router := gin.Default()
+// ConfigWatcher recarga la configuración en caliente (lo crea cmd/web.go);
+// su estado se muestra en /api/system
+var ConfigWatcher *config.Watcher
+
+// TrustedProxies son los proxies cuyo X-Forwarded-For se cree (lo inicializa
+// cmd/web.go desde trusted_proxies). Sin ninguno, ClientIP es la IP de la
+// conexión y un cliente no puede elegir con qué IP cuenta para los límites ni
+// cuál queda en la auditoría.
+var TrustedProxies []string
+
+// webhookWait es cuánto se espera al apagar a que terminen los avisos a los webhooks
+const webhookWait = 15 * time.Second
+
+// ErrInterrupted indica que el apagado tuvo que cancelar backups en curso
+var ErrInterrupted = errors.New("backups interrumpidos por el apagado")
+
+// StartServer inicia el servidor web de Gobackup y lo apaga ordenadamente cuando
+// se cancela ctx: deja de aceptar backups, espera hasta timeout a los que están
+// en curso, cancela los que sigan y cierra el historial.
+func StartServer(ctx context.Context, port int, timeout time.Duration) error {
+	if logger.Level() > logger.LevelDebug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
+	if err := router.SetTrustedProxies(TrustedProxies); err != nil {
+		return fmt.Errorf("trusted_proxies: %w", err)
+	}
+	router.Use(gin.Recovery(), requestLogger(), metricsMiddleware())
+	if TLS.Enabled && TLS.HSTSMaxAgeDays > 0 {
+		router.Use(hstsMiddleware(TLS.HSTSMaxAgeDays))
+	}
+	router.Use(ipRateLimit(), bodyLimit(), authMiddleware(), principalRateLimit())
 
 	RegisterAllRoutes(router)
 
-	log.Println("Servidor iniciado en http://localhost:8080")
-	if err := router.Run(":8080"); err != nil {
-		log.Fatalf("Error starting server: %v", err)
+	// Las peticiones largas (logs en vivo) terminan al empezar el apagado
+	reqCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	srv := &http.Server{
+		Addr:        fmt.Sprintf(":%d", port),
+		Handler:     router,
+		BaseContext: func(net.Listener) context.Context { return reqCtx },
+		// Sin esto un cliente que manda los encabezados de a un byte ocupa una conexión para siempre
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	srv.RegisterOnShutdown(cancelRequests)
+
+	serveErr := make(chan error, 2)
+	var redirect *http.Server
+	if TLS.Enabled {
+		tc, err := serverTLSConfig(TLS)
+		if err != nil {
+			return fmt.Errorf("configurando HTTPS: %w", err)
+		}
+		srv.TLSConfig = tc
+		go func() { serveErr <- srv.ListenAndServeTLS("", "") }()
+		logger.Info("Servidor iniciado", "addr", fmt.Sprintf("https://localhost:%d", port), "min_tls", TLS.MinVersion,
+			"client_certs", TLS.ClientCAFile != "")
+
+		// El puerto HTTP solo redirige: nunca sirve el panel sin cifrar
+		if TLS.RedirectPort > 0 {
+			redirect = &http.Server{
+				Addr:              fmt.Sprintf(":%d", TLS.RedirectPort),
+				Handler:           redirectHandler(port),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			go func() {
+				if err := redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					serveErr <- fmt.Errorf("redirección HTTP: %w", err)
+				}
+			}()
+			logger.Info("Redirección HTTP a HTTPS", "addr", fmt.Sprintf("http://localhost:%d", TLS.RedirectPort))
+		}
+	} else {
+		go func() { serveErr <- srv.ListenAndServe() }()
+		logger.Info("Servidor iniciado", "addr", fmt.Sprintf("http://localhost:%d", port))
+	}
+
+	select {
+	case err := <-serveErr:
+		logger.Error("Error iniciando el servidor", "error", err)
+		srv.Close()
+		if redirect != nil {
+			redirect.Close()
+		}
+		return err
+	case <-ctx.Done():
+	}
+	if redirect != nil {
+		redirect.Close()
+	}
+
+	logger.Info("Apagando servidor", "running_jobs", backup.RunningJobs(), "timeout", timeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	httpDone := make(chan error, 1)
+	go func() { httpDone <- srv.Shutdown(shutdownCtx) }()
+	interrupted := backup.DrainJobs(shutdownCtx)
+	if err := <-httpDone; err != nil {
+		logger.Warn("Conexiones cerradas sin terminar", "error", err)
+		srv.Close()
+	}
+	// Los backups que terminaron (o se cancelaron) recién avisaron a los webhooks
+	if n := webhook.Wait(webhookWait); n > 0 {
+		logger.Warn("Webhooks sin terminar al apagar", "pending", n)
+	}
+	history.Close()
+
+	if interrupted > 0 {
+		logger.Warn("Servidor detenido con backups cancelados", "jobs", interrupted)
+		return fmt.Errorf("%w: %d", ErrInterrupted, interrupted)
+	}
+	logger.Info("Servidor detenido")
+	return nil
+}
+
+// requestLogger - Registra cada petición HTTP en el logger estructurado
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		fields := []interface{}{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"ip", c.ClientIP(),
+		}
+		switch {
+		case status >= 500:
+			logger.Error("Petición HTTP", fields...)
+		case status >= 400:
+			logger.Warn("Petición HTTP", fields...)
+		default:
+			logger.Debug("Petición HTTP", fields...)
+		}
 	}
 }
 
@@ -0,0 +1,55 @@
+// ================== SESIÓN ==================
+// Se carga antes que script.js / stats.js: agrega el token CSRF a las peticiones
+// que modifican algo, manda a /login si la sesión venció y muestra el usuario.
+
+function getCookie(name) {
+    const match = document.cookie.match(new RegExp("(?:^|; )" + name + "=([^;]*)"));
+    return match ? decodeURIComponent(match[1]) : "";
+}
+
+const originalFetch = window.fetch.bind(window);
+
+window.fetch = async function (input, init = {}) {
+    const method = (init.method || "GET").toUpperCase();
+    if (!["GET", "HEAD", "OPTIONS"].includes(method)) {
+        const headers = new Headers(init.headers || {});
+        headers.set("X-CSRF-Token", getCookie("gobackup_csrf"));
+        init = { ...init, headers };
+    }
+
+    const response = await originalFetch(input, init);
+    if (response.status === 401) {
+        window.location.href = "/login?next=" + encodeURIComponent(window.location.pathname);
+    }
+    return response;
+};
+
+async function logout() {
+    await fetch("/api/auth/logout", { method: "POST" });
+    window.location.href = "/login";
+}
+
+// Usuario y botón de salida en la cabecera
+async function loadCurrentUser() {
+    try {
+        const response = await fetch("/api/auth/me");
+        if (!response.ok) return;
+        const me = await response.json();
+        if (!me.auth_enabled) return;
+
+        const header = document.querySelector("header");
+        const box = document.createElement("div");
+        box.className = "user-box";
+        box.innerHTML = `<span><i class="fas fa-user"></i> <strong></strong> (<em></em>)</span>
+            <button class="btn-secondary" id="logoutButton"><i class="fas fa-sign-out-alt"></i> Salir</button>`;
+        box.querySelector("strong").textContent = me.user;
+        box.querySelector("em").textContent = me.role;
+        header.appendChild(box);
+        document.getElementById("logoutButton").addEventListener("click", logout);
+        document.body.dataset.role = me.role;
+    } catch (error) {
+        console.error("Error cargando usuario:", error);
+    }
+}
+
+document.addEventListener("DOMContentLoaded", loadCurrentUser);
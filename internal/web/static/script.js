@@ -16,6 +16,7 @@ const selectedFolderDiv = document.getElementById("selectedFolder");
 const folderPathEl = document.getElementById("folderPath");
 const clearSelectionBtn = document.getElementById("clearSelection");
 const selectButton = document.getElementById("selectButton"); // Nuevo botón con ID
+const profileSelect = document.getElementById("profileSelect");
 
 // Elementos de estadísticas rápidas
 const quickTotalBackups = document.getElementById("quick-total-backups");
@@ -116,6 +117,7 @@ async function handleFileUpload(files) {
     appendLog("[SUCCESS] Todos los archivos subidos correctamente", "success");
     startBtn.disabled = false;
     statusText.textContent = "Archivos subidos - Listo para backup";
+    loadQuota();
 }
 
 // ================== EVENTO PARA LIMPIAR SELECCIÓN ==================
@@ -154,24 +156,12 @@ function updateFileCounter() {
 
 // ================== FUNCIÓN PARA SUBIR ARCHIVOS ==================
 async function uploadFile(file) {
-    const formData = new FormData();
-    formData.append('file', file);
-    if (currentSessionId) {
-        formData.append('sessionId', currentSessionId);
-    }
-
     try {
         debugLog(`Subiendo archivo: ${file.name}`);
-        const response = await fetch('/upload', {
-            method: 'POST',
-            body: formData
-        });
-
-        if (!response.ok) {
-            throw new Error(`Error HTTP: ${response.status} ${response.statusText}`);
-        }
-
-        const data = await response.json();
+        // Los archivos grandes van por partes para poder retomarlos si se corta
+        const data = file.size > CHUNK_THRESHOLD
+            ? await uploadFileChunked(file)
+            : await uploadFileSimple(file);
         debugLog(`Respuesta del servidor: ${JSON.stringify(data)}`);
 
         if (!currentSessionId && data.sessionId) {
@@ -191,11 +181,122 @@ async function uploadFile(file) {
     }
 }
 
+// El servidor explica el motivo (p. ej. el límite superado en un 413)
+async function responseError(response) {
+    const body = await response.json().catch(() => ({}));
+    return new Error(body.error || `Error HTTP: ${response.status} ${response.statusText}`);
+}
+
+async function uploadFileSimple(file) {
+    const formData = new FormData();
+    formData.append('file', file);
+    if (currentSessionId) {
+        formData.append('sessionId', currentSessionId);
+    }
+    const response = await fetch('/upload', {
+        method: 'POST',
+        body: formData
+    });
+    if (!response.ok) {
+        throw await responseError(response);
+    }
+    return response.json();
+}
+
+// ================== SUBIDAS POR PARTES ==================
+const CHUNK_THRESHOLD = 32 * 1024 * 1024;
+const CHUNK_SIZE = 8 * 1024 * 1024;
+const CHUNK_RETRIES = 5;
+
+const sleep = ms => new Promise(resolve => setTimeout(resolve, ms));
+
+// sha256 del fragmento para Upload-Checksum (crypto.subtle solo existe en https o localhost)
+async function chunkChecksum(chunk) {
+    if (!window.crypto || !crypto.subtle) return null;
+    const digest = new Uint8Array(await crypto.subtle.digest('SHA-256', await chunk.arrayBuffer()));
+    return 'sha256 ' + btoa(String.fromCharCode(...digest));
+}
+
+// Sube file en fragmentos de CHUNK_SIZE. El ID de la subida se guarda en
+// localStorage: si se corta o se recarga la página, al volver a soltar el mismo
+// archivo se sigue desde lo que ya tiene el servidor.
+async function uploadFileChunked(file) {
+    const key = `gobackup_upload_${file.name}_${file.size}_${file.lastModified}`;
+    let upload = null;
+
+    const savedId = localStorage.getItem(key);
+    if (savedId) {
+        const response = await fetch(`/upload/chunked/${savedId}`);
+        if (response.ok) {
+            upload = await response.json();
+            appendLog(`[INFO] Retomando ${file.name} desde ${formatFileSize(upload.offset)}`, "info");
+        } else {
+            localStorage.removeItem(key);
+        }
+    }
+    if (!upload) {
+        const response = await fetch('/upload/chunked', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ sessionId: currentSessionId || '', path: file.name, size: file.size })
+        });
+        if (!response.ok) {
+            throw await responseError(response);
+        }
+        upload = await response.json();
+        localStorage.setItem(key, upload.id);
+    }
+
+    let offset = upload.offset;
+    let failures = 0;
+    while (offset < file.size) {
+        const chunk = file.slice(offset, offset + CHUNK_SIZE);
+        const headers = { 'Upload-Offset': String(offset) };
+        const checksum = await chunkChecksum(chunk);
+        if (checksum) headers['Upload-Checksum'] = checksum;
+
+        let response;
+        try {
+            response = await fetch(`/upload/chunked/${upload.id}`, { method: 'PATCH', headers, body: chunk });
+        } catch (error) {
+            response = null; // sin conexión: esperar y preguntar el offset
+        }
+        if (response && response.ok) {
+            offset = (await response.json()).offset;
+            failures = 0;
+            statusText.textContent = `Subiendo ${file.name}: ${Math.floor(offset / file.size * 100)}%`;
+            continue;
+        }
+        // 413 = el fragmento no entra en el tamaño declarado: no tiene sentido reintentar
+        if (response && response.status === 413) {
+            throw await responseError(response);
+        }
+        if (++failures > CHUNK_RETRIES) {
+            throw response ? await responseError(response) : new Error('sin conexión con el servidor');
+        }
+        appendLog(`[WARN] Fragmento de ${file.name} fallido, reintentando (${failures}/${CHUNK_RETRIES})`, "info");
+        await sleep(1000 * 2 ** failures);
+        const state = await fetch(`/upload/chunked/${upload.id}`).catch(() => null);
+        if (state && state.ok) {
+            offset = (await state.json()).offset;
+        }
+    }
+
+    const response = await fetch(`/upload/chunked/${upload.id}/finish`, { method: 'POST' });
+    if (!response.ok) {
+        // Con el sha256 distinto el servidor descarta la subida
+        if (response.status === 422 || response.status === 404) localStorage.removeItem(key);
+        throw await responseError(response);
+    }
+    localStorage.removeItem(key);
+    return response.json();
+}
+
 // ================== FUNCIÓN PARA FORMATEAR TAMAÑO ==================
 function formatFileSize(bytes) {
     if (bytes === 0) return '0 Bytes';
     const k = 1024;
-    const sizes = ['Bytes', 'KB', 'MB', 'GB'];
+    const sizes = ['Bytes', 'KB', 'MB', 'GB', 'TB'];
     const i = Math.floor(Math.log(bytes) / Math.log(k));
     return parseFloat((bytes / Math.pow(k, i)).toFixed(2)) + ' ' + sizes[i];
 }
@@ -229,7 +330,8 @@ startBtn.addEventListener("click", async () => {
                 'Content-Type': 'application/json',
             },
             body: JSON.stringify({
-                sessionId: currentSessionId || 'default_session'
+                sessionId: currentSessionId || 'default_session',
+                profile: profileSelect.value
             })
         });
 
@@ -323,7 +425,7 @@ async function downloadBackup(sessionId) {
         // Crear enlace de descarga automática
         const downloadLink = document.createElement('a');
         downloadLink.href = `/download/${sessionId}`;
-        downloadLink.download = `${sessionId}.zip`;
+        downloadLink.download = ''; // el servidor indica el nombre (zip, tar.gz, tar.zst o cifrado)
         document.body.appendChild(downloadLink);
         downloadLink.click();
         document.body.removeChild(downloadLink);
@@ -412,6 +514,64 @@ function loadQuickStats() {
         });
 }
 
+// ================== CUOTA ==================
+// Uso propio frente a la cuota y límites de subida (quotas.* en la configuración)
+function loadQuota() {
+    fetch('/api/quota')
+        .then(response => response.ok ? response.json() : Promise.reject(response.status))
+        .then(data => {
+            const usage = data.usage;
+            const limits = data.limits;
+            const bar = document.getElementById('quotaBar');
+            let text = `Usas ${formatFileSize(usage.total)} en ${usage.sessions} sesión(es)`;
+            if (usage.quota > 0) {
+                const percent = Math.min(100, usage.total / usage.quota * 100);
+                bar.style.width = `${percent}%`;
+                bar.className = percent >= 100 ? 'full' : percent >= 80 ? 'warning' : '';
+                text += ` de ${formatFileSize(usage.quota)} (${percent.toFixed(0)}%)`;
+            } else {
+                bar.style.width = '0%';
+                text += ' (sin cuota)';
+            }
+            document.getElementById('quotaText').textContent = text;
+
+            const parts = [];
+            if (limits.max_file_size > 0) parts.push(`máx. ${formatFileSize(limits.max_file_size)} por archivo`);
+            if (limits.max_session_size > 0) parts.push(`${formatFileSize(limits.max_session_size)} por sesión`);
+            if (limits.max_session_files > 0) parts.push(`${limits.max_session_files} archivos por sesión`);
+            if (data.disk_free !== undefined) parts.push(`${formatFileSize(data.disk_free)} libres en el servidor`);
+            document.getElementById('quotaLimits').textContent = parts.join(' · ');
+            document.getElementById('quotaCard').hidden = false;
+        })
+        .catch(error => {
+            console.error('Error cargando la cuota:', error);
+        });
+}
+
+// ================== PERFILES ==================
+// Los perfiles definen formato, filtros y cifrado del backup de la sesión
+function loadProfiles() {
+    fetch('/api/profiles')
+        .then(response => response.json())
+        .then(data => {
+            profileSelect.innerHTML = '';
+            (data.profiles || []).forEach(profile => {
+                const option = document.createElement('option');
+                option.value = profile.name;
+                let label = `${profile.name} (${profile.format}`;
+                if (profile.encryption && profile.encryption.enabled) {
+                    label += ', cifrado';
+                }
+                option.textContent = label + ')';
+                option.selected = profile.name === data.active;
+                profileSelect.appendChild(option);
+            });
+        })
+        .catch(error => {
+            console.error('Error cargando perfiles:', error);
+        });
+}
+
 // ================== PERSISTENCIA DE ESTADÍSTICAS ==================
 
 // Usar localStorage para mantener las estadísticas entre recargas
@@ -453,9 +613,11 @@ document.addEventListener('DOMContentLoaded', function() {
     initializeSelectButton();
     loadStatsFromStorage(); // Cargar estadísticas guardadas
     appendLog("[INFO] Aplicación cargada. Arrastra archivos o haz clic para seleccionarlos.", "info");
-    appendLog("[INFO] Los archivos se subirán al servidor y se comprimirán en un ZIP.", "info");
+    appendLog("[INFO] Los archivos se subirán al servidor y se comprimirán según el perfil elegido.", "info");
     debugLog("Inicialización completada");
 
-    // Cargar estadísticas rápidas
+    // Cargar estadísticas rápidas y perfiles
     loadQuickStats();
+    loadProfiles();
+    loadQuota();
 });
\ No newline at end of file
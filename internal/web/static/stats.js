@@ -3,17 +3,20 @@
 // Variables globales para los charts
 let sizeHistoryChartInstance = null;
 let fileTypeChartInstance = null;
+let failureRateChartInstance = null;
 
 document.addEventListener('DOMContentLoaded', function() {
     loadStatsSummary();
     loadBackupHistory();
     loadFileTypeStats();
+    loadFailureStats();
 
     // Configurar auto-actualización cada 30 segundos
     setInterval(() => {
         loadStatsSummary();
         loadBackupHistory();
         loadFileTypeStats();
+        loadFailureStats();
     }, 30000);
 });
 
@@ -63,6 +66,10 @@ function loadStatsSummary() {
                 data.max_size || 'Máximo: 0 MB';
             document.getElementById('min-duration').textContent =
                 data.min_duration || 'Más rápido: 0s';
+            document.getElementById('failure-rate').textContent =
+                data.failure_rate || '0.0%';
+            document.getElementById('failed-backups').textContent =
+                `${data.failed_backups || 0} backups fallidos`;
         })
         .catch(error => {
             console.error('Error cargando resumen:', error);
@@ -70,6 +77,7 @@ function loadStatsSummary() {
             document.getElementById('total-space').textContent = 'Error';
             document.getElementById('avg-size').textContent = 'Error';
             document.getElementById('avg-duration').textContent = 'Error';
+            document.getElementById('failure-rate').textContent = 'Error';
         });
 }
 
@@ -89,28 +97,41 @@ function loadBackupHistory() {
 
                 data.backups.forEach(backup => {
                     const row = document.createElement('tr');
+                    const ratio = backup.bytes_read > 0 ?
+                        (backup.compression_ratio * 100).toFixed(1) + '%' : '-';
                     row.innerHTML = `
                         <td>${new Date(backup.timestamp).toLocaleString()}</td>
                         <td>${backup.session_id || backup.sessionID || 'N/A'}</td>
                         <td>${formatFileSize(backup.total_size)}</td>
-                        <td>${backup.files_count || backup.FilesCount || 0}</td>
-                        <td>${formatDuration(backup.duration_seconds || backup.duration_seconds)}</td>
-                        <td class="status-${backup.status}">${backup.status}</td>
+                        <td>${backup.files_count || backup.FilesCount || 0}${backup.files_failed ? ` (${backup.files_failed} con error)` : ''}</td>
+                        <td>${backup.files_skipped || 0}</td>
+                        <td>${formatFileSize(backup.bytes_read || 0)} / ${formatFileSize(backup.bytes_written || 0)}</td>
+                        <td>${ratio}</td>
+                        <td>${formatFileSize(Math.round(backup.throughput_bps || 0))}/s</td>
+                        <td>${formatDuration(backup.duration_seconds || 0)}</td>
+                        <td class="status-${backup.status}"></td>
+                        <td>${backup.report_path ? `<a href="/api/jobs/${backup.id}/report?format=html" target="_blank">Ver</a> · <a href="/api/jobs/${backup.id}/report?download=1">JSON</a>` : '-'}</td>
                     `;
+                    // El resumen de errores se muestra como tooltip del estado
+                    const statusCell = row.children[9];
+                    statusCell.textContent = backup.status;
+                    if (backup.error_summary) {
+                        statusCell.title = backup.error_summary;
+                    }
                     tableBody.appendChild(row);
                 });
 
                 // También actualizar el gráfico de historial
                 renderSizeHistoryChart(data);
             } else {
-                tableBody.innerHTML = '<tr><td colspan="6" style="text-align: center;">No hay historial de backups</td></tr>';
+                tableBody.innerHTML = '<tr><td colspan="11" style="text-align: center;">No hay historial de backups</td></tr>';
                 renderEmptyCharts();
             }
         })
         .catch(error => {
             console.error('Error cargando historial:', error);
             const tableBody = document.getElementById('history-table').querySelector('tbody');
-            tableBody.innerHTML = '<tr><td colspan="6" style="text-align: center; color: #ff4444;">Error cargando historial</td></tr>';
+            tableBody.innerHTML = '<tr><td colspan="11" style="text-align: center; color: #ff4444;">Error cargando historial</td></tr>';
             renderErrorCharts();
         });
 }
@@ -131,6 +152,77 @@ function loadFileTypeStats() {
         });
 }
 
+function loadFailureStats() {
+    fetch('/api/stats/failures?days=30')
+        .then(response => {
+            if (!response.ok) throw new Error('Error en API stats/failures');
+            return response.json();
+        })
+        .then(data => renderFailureRateChart(data))
+        .catch(error => {
+            console.error('Error cargando tasa de fallos:', error);
+        });
+}
+
+function renderFailureRateChart(data) {
+    const ctx = document.getElementById('failureRateChart').getContext('2d');
+
+    // Destruir chart anterior si existe
+    if (failureRateChartInstance) {
+        failureRateChartInstance.destroy();
+    }
+
+    const days = data.days || [];
+    const labels = days.map(day => new Date(day.date + 'T00:00:00').toLocaleDateString());
+
+    failureRateChartInstance = new Chart(ctx, {
+        type: 'bar',
+        data: {
+            labels: labels,
+            datasets: [
+                { label: 'Exitosos', data: days.map(d => d.success), backgroundColor: '#2ecc71', stack: 'runs' },
+                { label: 'Parciales', data: days.map(d => d.partial), backgroundColor: '#f39c12', stack: 'runs' },
+                { label: 'Fallidos', data: days.map(d => d.failed), backgroundColor: '#e74c3c', stack: 'runs' },
+                { label: 'Cancelados', data: days.map(d => d.cancelled), backgroundColor: '#95a5a6', stack: 'runs' },
+                {
+                    label: 'Tasa de fallos (%)',
+                    data: days.map(d => parseFloat(d.failure_rate.toFixed(1))),
+                    type: 'line',
+                    borderColor: '#c0392b',
+                    borderWidth: 2,
+                    tension: 0.3,
+                    yAxisID: 'rate'
+                }
+            ]
+        },
+        options: {
+            responsive: true,
+            maintainAspectRatio: false,
+            plugins: {
+                legend: {
+                    display: true,
+                    position: 'top'
+                }
+            },
+            scales: {
+                x: { stacked: true, grid: { display: false } },
+                y: {
+                    stacked: true,
+                    beginAtZero: true,
+                    title: { display: true, text: 'Backups' }
+                },
+                rate: {
+                    position: 'right',
+                    min: 0,
+                    max: 100,
+                    title: { display: true, text: '% fallos' },
+                    grid: { display: false }
+                }
+            }
+        }
+    });
+}
+
 function renderSizeHistoryChart(data) {
     const ctx = document.getElementById('sizeHistoryChart').getContext('2d');
 
@@ -0,0 +1,90 @@
+// ================== TOKENS DE API ==================
+// Crear, listar y revocar los tokens que usan los scripts (Authorization: Bearer).
+// La tarjeta solo aparece con autenticación activada.
+
+function formatTokenDate(value) {
+    return value ? new Date(value).toLocaleString() : "nunca";
+}
+
+async function loadTokens() {
+    const response = await fetch("/api/tokens");
+    if (!response.ok) return; // 409 sin autenticación: la tarjeta sigue oculta
+    const data = await response.json();
+    document.getElementById("tokensCard").hidden = false;
+
+    // Casillas de permisos (una sola vez)
+    const scopesDiv = document.getElementById("tokenScopes");
+    if (!scopesDiv.children.length) {
+        for (const scope of data.scopes) {
+            const label = document.createElement("label");
+            label.innerHTML = `<input type="checkbox" value="${scope}"> ${scope}`;
+            scopesDiv.appendChild(label);
+        }
+    }
+
+    const tbody = document.getElementById("tokenList");
+    tbody.innerHTML = "";
+    for (const token of data.tokens) {
+        const row = document.createElement("tr");
+        row.className = token.status;
+        for (const text of [token.name, token.owner, token.scopes.join(", "), token.status, formatTokenDate(token.last_used_at)]) {
+            const cell = document.createElement("td");
+            cell.textContent = text;
+            row.appendChild(cell);
+        }
+        const actions = document.createElement("td");
+        if (token.status === "active") {
+            const button = document.createElement("button");
+            button.className = "btn-secondary";
+            button.innerHTML = '<i class="fas fa-ban"></i> Revocar';
+            button.addEventListener("click", () => revokeToken(token));
+            actions.appendChild(button);
+        }
+        row.appendChild(actions);
+        tbody.appendChild(row);
+    }
+}
+
+async function createToken(event) {
+    event.preventDefault();
+    const scopes = [...document.querySelectorAll("#tokenScopes input:checked")].map((input) => input.value);
+    const secretDiv = document.getElementById("tokenSecret");
+    secretDiv.hidden = false;
+
+    const response = await fetch("/api/tokens", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+            name: document.getElementById("tokenName").value,
+            scopes,
+            expires_days: parseInt(document.getElementById("tokenDays").value, 10) || 0,
+        }),
+    });
+    const data = await response.json();
+    if (!response.ok) {
+        secretDiv.textContent = data.error || "Error creando el token";
+        return;
+    }
+
+    // El token solo se puede ver ahora
+    secretDiv.innerHTML = '<i class="fas fa-exclamation-triangle"></i> <span></span><br><code></code>';
+    secretDiv.querySelector("span").textContent = data.message;
+    secretDiv.querySelector("code").textContent = data.token;
+    document.getElementById("tokenForm").reset();
+    loadTokens();
+}
+
+async function revokeToken(token) {
+    if (!confirm(`¿Revocar el token "${token.name}"? Los scripts que lo usan dejarán de funcionar.`)) return;
+    const response = await fetch("/api/tokens/" + encodeURIComponent(token.id), { method: "DELETE" });
+    if (!response.ok) {
+        const data = await response.json();
+        alert(data.error || "Error revocando el token");
+    }
+    loadTokens();
+}
+
+document.addEventListener("DOMContentLoaded", () => {
+    document.getElementById("tokenForm").addEventListener("submit", createToken);
+    loadTokens().catch((error) => console.error("Error cargando tokens:", error));
+});
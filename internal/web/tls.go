@@ -0,0 +1,198 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"gobackup/internal/auth"
+	"gobackup/internal/config"
+	"gobackup/internal/logger"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TLS es la configuración de HTTPS (la inicializa cmd/web.go desde tls.*)
+var TLS config.TLSConfig
+
+// selfSignedValidity es la duración del certificado autofirmado que se genera
+const selfSignedValidity = 2 * 365 * 24 * time.Hour
+
+// clientCertHeader tiene que venir en las peticiones que modifican algo cuando se
+// entra con certificado de cliente: el navegador puede mandar el certificado
+// solo, pero un sitio ajeno no puede agregar cabeceras (hace de CSRF)
+const clientCertHeader = "X-Requested-With"
+
+// serverTLSConfig arma la configuración TLS del servidor. Si no existen ni el
+// certificado ni la clave se genera un par autofirmado en esas rutas.
+func serverTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	if err := ensureCertificate(cfg.CertFile, cfg.KeyFile); err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cargando certificado: %w", err)
+	}
+	if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil {
+		if left := time.Until(leaf.NotAfter); left < 30*24*time.Hour {
+			logger.Warn("El certificado TLS vence pronto", "cert_file", cfg.CertFile, "not_after", leaf.NotAfter.Format(time.RFC3339))
+		}
+		logger.Info("Certificado TLS", "cert_file", cfg.CertFile, "subject", leaf.Subject.CommonName,
+			"not_after", leaf.NotAfter.Format("2006-01-02"), "sha256", fingerprint(leaf.Raw))
+	}
+
+	tc := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.MinVersion == "1.3" {
+		tc.MinVersion = tls.VersionTLS13
+	}
+	if cfg.ClientCAFile != "" {
+		data, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s no tiene certificados PEM", cfg.ClientCAFile)
+		}
+		// Opcional: los navegadores siguen entrando con usuario y contraseña
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tc, nil
+}
+
+// ensureCertificate genera un certificado autofirmado si no hay ninguno
+func ensureCertificate(certFile, keyFile string) error {
+	_, certErr := os.Stat(certFile)
+	_, keyErr := os.Stat(keyFile)
+	switch {
+	case certErr == nil && keyErr == nil:
+		return nil
+	case !os.IsNotExist(certErr) || !os.IsNotExist(keyErr):
+		return fmt.Errorf("falta el certificado o la clave (%s, %s): %w", certFile, keyFile, errors.Join(certErr, keyErr))
+	}
+	return generateSelfSigned(certFile, keyFile)
+}
+
+// generateSelfSigned crea un certificado ECDSA autofirmado para localhost, el
+// nombre del equipo y sus direcciones de loopback
+func generateSelfSigned(certFile, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	hosts := []string{"localhost"}
+	if name, err := os.Hostname(); err == nil && name != "localhost" {
+		hosts = append(hosts, name)
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: hosts[len(hosts)-1], Organization: []string{"Gobackup (autofirmado)"}},
+		DNSNames:              hosts,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.MkdirAll(filepath.Dir(f), 0700); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		os.Remove(keyFile)
+		return err
+	}
+	logger.Warn("Certificado TLS autofirmado generado: los navegadores lo van a marcar como no confiable",
+		"cert_file", certFile, "hosts", hosts, "sha256", fingerprint(der))
+	return nil
+}
+
+// fingerprint es el SHA-256 de un certificado, para compararlo al aceptarlo
+func fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// hstsMiddleware pide al navegador que use siempre HTTPS con este servidor
+func hstsMiddleware(maxAgeDays int) gin.HandlerFunc {
+	value := "max-age=" + strconv.Itoa(maxAgeDays*24*60*60)
+	return func(c *gin.Context) {
+		if c.Request.TLS != nil {
+			c.Header("Strict-Transport-Security", value)
+		}
+		c.Next()
+	}
+}
+
+// redirectHandler manda cada petición HTTP a la misma ruta en HTTPS
+func redirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host // sin puerto
+		}
+		target := "https://" + net.JoinHostPort(host, strconv.Itoa(httpsPort)) + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
+
+// clientCertAuth identifica la petición por su certificado de cliente (verificado
+// contra tls.client_ca_file): el CN tiene que ser un usuario existente, con cuyo
+// rol se entra
+func clientCertAuth(c *gin.Context) bool {
+	state := c.Request.TLS
+	if state == nil || len(state.VerifiedChains) == 0 {
+		return false
+	}
+	cert := state.VerifiedChains[0][0]
+	user, err := auth.GetUser(cert.Subject.CommonName)
+	if err != nil {
+		logger.Warn("Certificado de cliente sin usuario", "cn", cert.Subject.CommonName, "ip", c.ClientIP())
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "El certificado no corresponde a ningún usuario"})
+		return true
+	}
+	if !safeMethod(c.Request.Method) && c.GetHeader(clientCertHeader) == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Falta la cabecera " + clientCertHeader})
+		return true
+	}
+	c.Set(ctxPrincipalKey, principal{User: user.Name, Role: user.Role, Scopes: user.Role.Scopes()})
+	c.Next()
+	return true
+}
@@ -0,0 +1,195 @@
+package web
+
+import (
+	"errors"
+	"gobackup/internal/auth"
+	"gobackup/internal/logger"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterTokenRoutes registra la gestión de tokens de API desde el panel
+func RegisterTokenRoutes(router *gin.Engine) {
+	router.GET("/api/tokens", getTokens)
+	router.POST("/api/tokens", createToken)
+	router.DELETE("/api/tokens/:id", revokeToken)
+	router.GET("/api/tokens/:id/events", getTokenEvents)
+}
+
+// tokenInfo es un token sin su hash, tal como lo ve la interfaz
+type tokenInfo struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	Scopes     []auth.Scope `json:"scopes"`
+	Owner      string       `json:"owner"`
+	Status     string       `json:"status"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ExpiresAt  time.Time    `json:"expires_at,omitzero"`
+	LastUsedAt time.Time    `json:"last_used_at,omitzero"`
+	LastUsedIP string       `json:"last_used_ip,omitempty"`
+	RevokedAt  time.Time    `json:"revoked_at,omitzero"`
+}
+
+func newTokenInfo(t auth.Token) tokenInfo {
+	return tokenInfo{t.ID, t.Name, t.Scopes, t.Owner, t.Status(), t.CreatedAt, t.ExpiresAt, t.LastUsedAt, t.LastUsedIP, t.RevokedAt}
+}
+
+// requireUsers responde 409 si la autenticación está desactivada: sin usuarios
+// no hay dueño para los tokens
+func requireUsers(c *gin.Context) bool {
+	if !AuthEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "La autenticación está desactivada (auth.enabled)"})
+		return false
+	}
+	return true
+}
+
+// requireTokenManager responde 403 si la petición viene con un token que no es
+// de admin: los tokens se gestionan con la sesión del usuario, así uno filtrado
+// no puede listar, revocar ni crear los demás
+func requireTokenManager(c *gin.Context, p principal) bool {
+	if p.Token != nil && !p.can(auth.ScopeAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Los tokens solo se gestionan con sesión de usuario"})
+		return false
+	}
+	return true
+}
+
+// canManageToken indica si el principal puede ver o revocar el token: su dueño o un admin
+func canManageToken(p principal, t auth.Token) bool {
+	return p.can(auth.ScopeAdmin) || t.Owner == p.User
+}
+
+// getTokens - Lista los tokens propios (todos para un admin)
+func getTokens(c *gin.Context) {
+	if !requireUsers(c) {
+		return
+	}
+	p := currentPrincipal(c)
+	if !requireTokenManager(c, p) {
+		return
+	}
+	tokens, err := auth.ListTokens()
+	if err != nil {
+		logger.Error("Error leyendo tokens", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo tokens"})
+		return
+	}
+
+	list := make([]tokenInfo, 0, len(tokens))
+	for _, t := range tokens {
+		if canManageToken(p, t) {
+			list = append(list, newTokenInfo(t))
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": list,
+		"count":  len(list),
+		"scopes": auth.Scopes,
+	})
+}
+
+// createToken - Crea un token con permisos que no superen los de quien lo pide.
+// El token en claro solo se devuelve en esta respuesta.
+func createToken(c *gin.Context) {
+	if !requireUsers(c) {
+		return
+	}
+	var req struct {
+		Name        string       `json:"name"`
+		Scopes      []auth.Scope `json:"scopes"`
+		ExpiresDays int          `json:"expires_days"` // 0 = sin vencimiento
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "JSON inválido"})
+		return
+	}
+	if req.ExpiresDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_days no puede ser negativo"})
+		return
+	}
+
+	p := currentPrincipal(c)
+	if !requireTokenManager(c, p) {
+		return
+	}
+	if !auth.ScopesCover(p.Scopes, req.Scopes) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No puede dar permisos que no tiene"})
+		return
+	}
+
+	token, secret, err := auth.CreateToken(req.Name, p.User, req.Scopes, time.Duration(req.ExpiresDays)*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ip, remote := clientAddr(c)
+	recordTokenEvent(auth.TokenEvent{TokenID: token.ID, Name: token.Name, Action: "created", Actor: p.actor(), IP: ip, Remote: remote})
+	logger.Info("Token de API creado", "token", token.ID, "name", token.Name, "scopes", token.Scopes, "user", p.actor())
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":   secret,
+		"info":    newTokenInfo(token),
+		"message": "Guarde el token ahora: no se vuelve a mostrar",
+	})
+}
+
+// revokeToken - Revoca un token propio (cualquiera para un admin)
+func revokeToken(c *gin.Context) {
+	if !requireUsers(c) {
+		return
+	}
+	p := currentPrincipal(c)
+	if !requireTokenManager(c, p) {
+		return
+	}
+	token, err := auth.GetToken(c.Param("id"))
+	if err == nil && !canManageToken(p, token) {
+		err = auth.ErrTokenNotFound // no revelar tokens ajenos
+	}
+	if err == nil {
+		token, err = auth.RevokeToken(token.ID)
+	}
+	if errors.Is(err, auth.ErrTokenNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Error("Error revocando token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revocando token"})
+		return
+	}
+
+	ip, remote := clientAddr(c)
+	recordTokenEvent(auth.TokenEvent{TokenID: token.ID, Name: token.Name, Action: "revoked", Actor: p.actor(), IP: ip, Remote: remote})
+	logger.Info("Token de API revocado", "token", token.ID, "name", token.Name, "user", p.actor())
+	c.JSON(http.StatusOK, gin.H{"message": "Token revocado", "info": newTokenInfo(token)})
+}
+
+// getTokenEvents - Historial de acciones de un token
+func getTokenEvents(c *gin.Context) {
+	if !requireUsers(c) {
+		return
+	}
+	p := currentPrincipal(c)
+	if !requireTokenManager(c, p) {
+		return
+	}
+	token, err := auth.GetToken(c.Param("id"))
+	if err != nil || !canManageToken(p, token) {
+		c.JSON(http.StatusNotFound, gin.H{"error": auth.ErrTokenNotFound.Error()})
+		return
+	}
+	events, err := auth.TokenEvents(token.ID)
+	if err != nil {
+		logger.Error("Error leyendo el historial de tokens", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo el historial"})
+		return
+	}
+	if events == nil {
+		events = []auth.TokenEvent{}
+	}
+	c.JSON(http.StatusOK, gin.H{"token": newTokenInfo(token), "events": events, "count": len(events)})
+}
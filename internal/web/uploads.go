@@ -0,0 +1,268 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"gobackup/internal/audit"
+	"gobackup/internal/auth"
+	"gobackup/internal/backup"
+	"gobackup/internal/disk"
+	"gobackup/internal/logger"
+	"gobackup/internal/safepath"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterUploadRoutes registra las rutas que usa la interfaz de drag & drop
+// (subida, backup, estado y descarga) y la consulta de sesiones
+func RegisterUploadRoutes(router *gin.Engine) {
+	router.POST("/upload", requireScope(auth.ScopeBackup), transfer(), uploadFile)
+	router.POST("/backup", requireScope(auth.ScopeBackup), startSessionBackup)
+	router.GET("/status", getBackupStatus)
+	router.GET("/download/:id", requireScope(auth.ScopeDownload), transfer(), downloadBackup)
+
+	router.GET("/api/uploads", getUploadSessions)
+	router.GET("/api/uploads/:id", getUploadSession)
+	router.GET("/api/quota", getQuota)
+}
+
+// uploadFile - Guarda un archivo en la sesión indicada (o en una nueva). El
+// cuerpo se lee como stream para cortar apenas se pasa de un límite (413) en
+// vez de guardarlo entero primero.
+func uploadFile(c *gin.Context) {
+	if backup.Draining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": backup.ErrShuttingDown.Error()})
+		return
+	}
+	user := currentPrincipal(c).User
+
+	// Si ya se sabe por el Content-Length que no entra, no esperar al cuerpo
+	if n := c.Request.ContentLength - multipartOverhead; n > 0 {
+		if err := backup.CheckUploadSize(user, n); err != nil {
+			uploadError(c, "", "", err)
+			return
+		}
+	}
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se esperaba un formulario multipart"})
+		return
+	}
+
+	// Los campos pueden venir antes o después del archivo
+	var sessionID, name, filename, tmpPath string
+	var size int64
+	defer func() {
+		if tmpPath != "" {
+			os.Remove(tmpPath) // no-op si ya se movió a la sesión
+		}
+	}()
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Formulario inválido: " + err.Error()})
+			return
+		}
+		switch part.FormName() {
+		case "file":
+			if tmpPath != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Solo se acepta un archivo por petición"})
+				return
+			}
+			filename = part.FileName()
+			tmpPath, size, err = backup.ReceiveUpload(part, user)
+			if err != nil {
+				uploadError(c, sessionID, filename, err)
+				return
+			}
+		case "sessionId":
+			sessionID = formValue(part)
+		case "path":
+			name = formValue(part)
+		}
+		part.Close()
+	}
+	if tmpPath == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Falta el archivo"})
+		return
+	}
+
+	// Ruta dentro de la sesión: la relativa que manda el cliente al subir una
+	// carpeta (campo path) o solo el nombre del archivo
+	if name == "" {
+		name = filepath.Base(filepath.Clean("/" + filename))
+	}
+	if name, err = safepath.Rel(name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nombre de archivo inválido: " + err.Error()})
+		return
+	}
+
+	var owner backup.Owner
+	if sessionID == "" {
+		sessionID, owner = backup.NewSessionID(), newSessionOwner(c)
+	} else if !ownsSession(c, sessionID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sesión no encontrada"})
+		return
+	}
+	if err := backup.AddToSession(sessionID, name, tmpPath, size, owner); err != nil {
+		uploadError(c, sessionID, name, err)
+		return
+	}
+	logger.Info("Archivo subido", "session_id", sessionID, "file", name, "bytes", size, "user", currentPrincipal(c).actor())
+	recordAudit(c, audit.ActionUpload, sessionID+"/"+name, audit.Success, fmt.Sprintf("%d bytes", size))
+	c.JSON(http.StatusOK, gin.H{
+		"sessionId": sessionID,
+		"filename":  name,
+		"size":      size,
+	})
+}
+
+// multipartOverhead es lo que se descuenta del Content-Length por los encabezados
+// y campos del formulario antes de compararlo con los límites
+const multipartOverhead = 16 << 10
+
+// formValue lee un campo de texto del formulario (los IDs y rutas son cortos)
+func formValue(part io.Reader) string {
+	b, _ := io.ReadAll(io.LimitReader(part, 4<<10))
+	return string(b)
+}
+
+// uploadError responde el error de una subida: 413 si se pasó de un límite
+// (con la clave de configuración y el máximo), 400 si la ruta o el ID son
+// inválidos y 500 en otro caso
+func uploadError(c *gin.Context, sessionID, name string, err error) {
+	var qe *backup.QuotaError
+	switch {
+	case errors.As(err, &qe):
+		logger.Warn("Subida rechazada por límite", "session_id", sessionID, "file", name, "limit", qe.Limit, "user", currentPrincipal(c).actor())
+		recordAudit(c, audit.ActionUpload, sessionID+"/"+name, audit.Denied, qe.Error())
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": qe.Error(), "limit": qe.Limit, "max": qe.Max})
+	case errors.Is(err, safepath.ErrUnsafe):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logger.Error("Error guardando archivo subido", "session_id", sessionID, "file", name, "error", err)
+		recordAudit(c, audit.ActionUpload, sessionID+"/"+name, audit.Failure, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error guardando archivo"})
+	}
+}
+
+// getQuota - Uso de quien hace la petición frente a su cuota, los límites de
+// subida y el espacio libre para subidas
+func getQuota(c *gin.Context) {
+	usage, err := backup.UserUsage(currentPrincipal(c).User)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := gin.H{"usage": usage, "limits": backup.UploadLimits()}
+	if d, err := disk.GetUsage(backup.UploadsDir); err == nil {
+		resp["disk_free"] = d.Free
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// startSessionBackup - Lanza el backup de una sesión en segundo plano
+func startSessionBackup(c *gin.Context) {
+	var req struct {
+		SessionID string `json:"sessionId"`
+		Profile   string `json:"profile"` // vacío = perfil activo
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.SessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Falta sessionId"})
+		return
+	}
+	profile, ok := backup.GetProfile(req.Profile)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Perfil desconocido: " + req.Profile})
+		return
+	}
+	if _, err := backup.GetSession(req.SessionID); err != nil || !ownsSession(c, req.SessionID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sesión no encontrada"})
+		return
+	}
+	ctx, done, err := backup.TrackJob()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	// Marcar en curso antes de responder para que /status no vea el estado anterior
+	if !backup.Status.TryStart() {
+		done()
+		c.JSON(http.StatusConflict, gin.H{"error": "Ya hay un backup en curso"})
+		return
+	}
+	// El contexto de gin no se puede usar después de responder
+	event := audit.Event{Actor: currentPrincipal(c).actor(), Action: audit.ActionBackup,
+		Target: req.SessionID, Outcome: audit.Success, Detail: "perfil " + profile.Name}
+	event.IP, event.Remote = clientAddr(c)
+	go func(id string) {
+		defer done()
+		err := backup.RunBackupWithSession(ctx, profile, id)
+		// Algunos errores tempranos no actualizan el estado: no dejarlo en curso
+		if err != nil && backup.Status.Get().InProgress {
+			backup.Status.SetError(err.Error())
+		}
+		if err != nil {
+			event.Outcome, event.Detail = audit.Failure, event.Detail+": "+err.Error()
+		}
+		recordAuditEvent(event)
+	}(req.SessionID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Backup iniciado para la sesión " + req.SessionID,
+		"sessionId": req.SessionID,
+	})
+}
+
+// downloadBackup - Descarga el ZIP de una sesión
+func downloadBackup(c *gin.Context) {
+	zipPath, err := ownedArchive(c, c.Param("id"))
+	if err != nil {
+		recordAudit(c, audit.ActionDownload, c.Param("id"), audit.Denied, "no encontrado o ajeno")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup no encontrado"})
+		return
+	}
+	c.FileAttachment(zipPath, filepath.Base(zipPath))
+	recordAudit(c, audit.ActionDownload, c.Param("id"), audit.Success, filepath.Base(zipPath))
+}
+
+// getUploadSessions - Lista las sesiones de subida propias (todas para un admin)
+func getUploadSessions(c *gin.Context) {
+	all, err := backup.ListSessions()
+	if err != nil && !os.IsNotExist(err) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sessions := []backup.Session{}
+	for _, s := range all {
+		if ownsSession(c, s.ID) {
+			sessions = append(sessions, s)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions, "count": len(sessions)})
+}
+
+// getUploadSession - Detalle de una sesión con sus archivos
+func getUploadSession(c *gin.Context) {
+	session, err := backup.GetSession(c.Param("id"))
+	if err != nil || !ownsSession(c, session.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sesión no encontrada"})
+		return
+	}
+	files, err := backup.SessionFiles(session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if files == nil {
+		files = []backup.SessionFile{}
+	}
+	c.JSON(http.StatusOK, gin.H{"session": session, "files": files})
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"gobackup/internal/auth"
+	"gobackup/internal/backup"
+	"gobackup/internal/config"
+	"gobackup/internal/webhook"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterWebhookRoutes registra la consulta y prueba de los webhooks (solo admin)
+func RegisterWebhookRoutes(router *gin.Engine) {
+	router.GET("/api/webhooks", requireScope(auth.ScopeAdmin), getWebhooks)
+	router.POST("/api/webhooks/:name/test", requireScope(auth.ScopeAdmin), testWebhook)
+}
+
+// getWebhooks - Webhooks configurados (sin la ruta de la URL ni la clave) y sus
+// últimos envíos; ?limit= cambia cuántos (50 por defecto)
+func getWebhooks(c *gin.Context) {
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit inválido"})
+			return
+		}
+		limit = n
+	}
+
+	hooks := []gin.H{}
+	for _, name := range webhook.Names() {
+		w, _ := webhook.Get(name)
+		hooks = append(hooks, gin.H{
+			"name":   name,
+			"url":    webhook.Redact(w.URL),
+			"format": w.Format,
+			"events": w.Events,
+			"signed": w.SecretEnv != "" || w.SecretFile != "",
+		})
+	}
+	deliveries, err := webhook.Deliveries(c.Query("webhook"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if deliveries == nil {
+		deliveries = []webhook.Delivery{}
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks, "deliveries": deliveries})
+}
+
+// testWebhook - Manda un evento de ejemplo (?event=, por defecto test) y
+// responde con el resultado del envío
+func testWebhook(c *gin.Context) {
+	event := c.DefaultQuery("event", webhook.EventTest)
+	if event != webhook.EventTest && !slices.Contains(config.WebhookEvents, event) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Evento desconocido: " + event})
+		return
+	}
+	d, err := webhook.Send(c.Request.Context(), c.Param("name"), webhook.TestPayload(event, backup.ActiveProfile().Name))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	status := http.StatusOK
+	if !d.OK {
+		status = http.StatusBadGateway
+	}
+	c.JSON(status, d)
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LogFile es el registro de envíos (lo inicializa cmd/root.go desde webhook_log_file)
+var LogFile = "config/webhook_deliveries.jsonl"
+
+// Delivery es el resultado de mandar un evento a un webhook, con todos sus intentos
+type Delivery struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Webhook    string    `json:"webhook"`
+	Event      string    `json:"event"`
+	JobID      string    `json:"job_id,omitempty"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"status_code,omitempty"` // la última respuesta (0 = no hubo)
+	OK         bool      `json:"ok"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"` // incluye las esperas entre intentos
+}
+
+var logMu sync.Mutex
+
+// recordDelivery agrega un envío al registro
+func recordDelivery(d Delivery) error {
+	line, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	logMu.Lock()
+	defer logMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(LogFile), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// Deliveries devuelve los últimos limit envíos (0 = todos), de un webhook si
+// name no está vacío, del más viejo al más nuevo
+func Deliveries(name string, limit int) ([]Delivery, error) {
+	f, err := os.Open(LogFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var list []Delivery
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var d Delivery
+		if json.Unmarshal(scanner.Bytes(), &d) != nil || (name != "" && d.Webhook != name) {
+			continue
+		}
+		list = append(list, d)
+		if limit > 0 && len(list) > limit {
+			list = list[1:]
+		}
+	}
+	return list, scanner.Err()
+}
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"gobackup/internal/config"
+	"gobackup/internal/history"
+	"gobackup/internal/utils"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Límites de Discord para los embeds (Slack y Teams aceptan más)
+const (
+	maxTitle      = 256
+	maxFieldValue = 1024
+)
+
+// fact es un dato del mensaje para los formatos de chat
+type fact struct {
+	Name  string
+	Value string
+}
+
+// render arma el cuerpo según el formato del webhook
+func render(format string, p Payload) ([]byte, error) {
+	if format == "" || format == "json" {
+		return json.Marshal(p)
+	}
+
+	title, color := headline(p)
+	facts := summary(p)
+	switch format {
+	case "slack":
+		fields := make([]map[string]interface{}, len(facts))
+		for i, f := range facts {
+			fields[i] = map[string]interface{}{"title": f.Name, "value": f.Value, "short": len(f.Value) < 40}
+		}
+		return json.Marshal(map[string]interface{}{
+			"text": title,
+			"attachments": []map[string]interface{}{{
+				"color":    "#" + color,
+				"fallback": title,
+				"fields":   fields,
+			}},
+		})
+	case "teams":
+		// MessageCard: lo aceptan los conectores de entrada y los flujos de Workflows
+		list := make([]map[string]string, len(facts))
+		for i, f := range facts {
+			list[i] = map[string]string{"name": f.Name, "value": f.Value}
+		}
+		return json.Marshal(map[string]interface{}{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    title,
+			"themeColor": color,
+			"title":      title,
+			"sections":   []map[string]interface{}{{"facts": list}},
+		})
+	case "discord":
+		rgb, _ := strconv.ParseInt(color, 16, 32)
+		fields := make([]map[string]interface{}, len(facts))
+		for i, f := range facts {
+			fields[i] = map[string]interface{}{"name": f.Name, "value": truncate(f.Value, maxFieldValue), "inline": len(f.Value) < 40}
+		}
+		return json.Marshal(map[string]interface{}{
+			"username": "Gobackup",
+			"embeds": []map[string]interface{}{{
+				"title":     truncate(title, maxTitle),
+				"color":     rgb,
+				"fields":    fields,
+				"timestamp": p.Time.Format("2006-01-02T15:04:05Z07:00"),
+			}},
+		})
+	}
+	return nil, fmt.Errorf("formato de webhook desconocido %q", format)
+}
+
+// headline devuelve el título del mensaje y su color (hex sin #)
+func headline(p Payload) (string, string) {
+	var title, color string
+	switch p.Event {
+	case config.WebhookJobStarted:
+		title, color = "▶️ Backup iniciado", "439FE0"
+	case config.WebhookJobSuccess:
+		title, color = "✅ Backup correcto", "2EB67D"
+	case config.WebhookJobPartial:
+		title, color = "⚠️ Backup parcial", "ECB22E"
+	case config.WebhookJobFailed:
+		title, color = "❌ Backup fallido", "E01E5A"
+	case config.WebhookJobCancelled:
+		title, color = "⏹️ Backup cancelado", "868686"
+	default:
+		title, color = "🔔 Gobackup: "+p.Event, "868686"
+	}
+	if p.Profile != "" {
+		title += " — perfil " + p.Profile
+	}
+	return title, color
+}
+
+// summary son los datos que se muestran en los formatos de chat
+func summary(p Payload) []fact {
+	facts := []fact{{"Trabajo", p.JobID}}
+	if p.SessionID != "" && p.SessionID != p.JobID {
+		facts = append(facts, fact{"Sesión", p.SessionID})
+	}
+	facts = append(facts, fact{"Equipo", p.Host})
+	if s := p.Stats; s != nil {
+		facts = append(facts,
+			fact{"Archivos", fmt.Sprintf("%d copiados, %d con error, %d omitidos", s.FilesCount, s.FilesFailed, s.FilesSkipped)},
+			fact{"Tamaño", utils.FormatFileSize(s.BytesRead) + " leídos, " + utils.FormatFileSize(s.BytesWritten) + " escritos"},
+			fact{"Duración", fmt.Sprintf("%.1fs", s.Duration)},
+		)
+		if s.Archive != "" {
+			facts = append(facts, fact{"Archivo", s.Archive})
+		}
+	}
+	if len(p.Errors) > 0 {
+		facts = append(facts, fact{"Errores", strings.Join(p.Errors, "\n")})
+	}
+	for i := range facts {
+		if facts[i].Value == "" {
+			facts[i].Value = "-" // Discord rechaza campos vacíos
+		}
+	}
+	return facts
+}
+
+// truncate corta s a max bytes sin partir un carácter
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max - len("…")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+// TestPayload arma un evento de ejemplo para probar un webhook. Con un evento
+// de fin lleva estadísticas inventadas, para ver cómo queda el mensaje.
+func TestPayload(event, profile string) Payload {
+	if event == "" {
+		event = EventTest
+	}
+	p := Payload{Event: event, JobID: "prueba", Profile: profile, BackupType: "test"}
+	if status, ok := strings.CutPrefix(event, "job."); ok && event != config.WebhookJobStarted {
+		p.Status = status
+		p.Stats = &history.Snapshot{JobID: p.JobID, Profile: profile, BackupType: p.BackupType, Status: status,
+			FilesCount: 42, FilesSkipped: 3, BytesRead: 52 << 20, BytesWritten: 18 << 20, Duration: 12.5}
+		if event == config.WebhookJobPartial || event == config.WebhookJobFailed {
+			p.Stats.FilesFailed = 2
+			p.Errors = []string{"fotos/img_001.jpg: permiso denegado", "fotos/img_002.jpg: permiso denegado"}
+		}
+	}
+	return p
+}
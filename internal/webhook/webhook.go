@@ -0,0 +1,359 @@
+// Package webhook avisa a otros sistemas cuando empieza o termina un backup:
+// un POST con JSON (o con el formato de Slack, Teams o Discord) a cada URL de
+// la sección webhooks, firmado con HMAC si tiene clave y reintentado con
+// espera creciente si falla. El resultado de cada envío queda en LogFile.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gobackup/internal/config"
+	"gobackup/internal/history"
+	"gobackup/internal/logger"
+	"gobackup/internal/metrics"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventTest es el evento de 'gobackup webhook test' (se manda aunque el webhook
+// no esté suscrito)
+const EventTest = "test"
+
+// Cabeceras de cada envío
+const (
+	HeaderEvent     = "X-Gobackup-Event"
+	HeaderDelivery  = "X-Gobackup-Delivery"
+	HeaderTimestamp = "X-Gobackup-Timestamp"
+	HeaderSignature = "X-Gobackup-Signature" // sha256=<hex de HMAC-SHA256(clave, timestamp + "." + cuerpo)>
+)
+
+const (
+	defaultTimeout     = 10 * time.Second
+	defaultMaxAttempts = 5
+	maxRetryDelay      = time.Minute
+	maxPayloadErrors   = 20
+)
+
+var deliveriesTotal = metrics.NewCounterVec("gobackup_webhook_deliveries_total",
+	"Envíos de webhooks terminados por webhook y resultado (ok o failed).", "webhook", "result")
+
+func init() {
+	metrics.Default.Register(deliveriesTotal)
+}
+
+// Payload es lo que se manda en formato json
+type Payload struct {
+	Event      string            `json:"event"`
+	Time       time.Time         `json:"time"`
+	Host       string            `json:"host"`
+	JobID      string            `json:"job_id"`
+	SessionID  string            `json:"session_id,omitempty"`
+	Profile    string            `json:"profile,omitempty"`
+	BackupType string            `json:"backup_type,omitempty"`
+	Status     string            `json:"status,omitempty"` // al terminar: success, partial, failed o cancelled
+	Stats      *history.Snapshot `json:"stats,omitempty"`  // al terminar
+	Errors     []string          `json:"errors,omitempty"` // los primeros errores del trabajo
+}
+
+// ErrorMessages arma la lista de errores del payload (con tope)
+func ErrorMessages(errs []history.ErrorRecord) []string {
+	var msgs []string
+	for i, e := range errs {
+		if i == maxPayloadErrors {
+			msgs = append(msgs, fmt.Sprintf("(+%d más)", len(errs)-maxPayloadErrors))
+			break
+		}
+		if e.Path != "" {
+			msgs = append(msgs, e.Path+": "+e.Message)
+		} else {
+			msgs = append(msgs, e.Message)
+		}
+	}
+	return msgs
+}
+
+// Webhooks activos; Configure los reemplaza al recargar la configuración
+var hooks atomic.Pointer[map[string]config.WebhookConfig]
+
+// Configure aplica la sección webhooks (lo llama cmd/root.go al arrancar y al recargar)
+func Configure(webhooks map[string]config.WebhookConfig) {
+	hooks.Store(&webhooks)
+}
+
+// Get devuelve la configuración de un webhook
+func Get(name string) (config.WebhookConfig, bool) {
+	if m := hooks.Load(); m != nil {
+		w, ok := (*m)[name]
+		return w, ok
+	}
+	return config.WebhookConfig{}, false
+}
+
+// Names lista los webhooks configurados
+func Names() []string {
+	var names []string
+	if m := hooks.Load(); m != nil {
+		for name := range *m {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Redact deja solo el esquema y el host de la URL: las de Slack o Discord
+// llevan el secreto en la ruta
+func Redact(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "(URL inválida)"
+	}
+	if u.Path != "" && u.Path != "/" {
+		return u.Scheme + "://" + u.Host + "/…"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// Envíos en segundo plano, para poder esperarlos al salir
+var (
+	inflight sync.WaitGroup
+	pending  atomic.Int64
+)
+
+// Notify manda el evento a los webhooks suscritos, en segundo plano
+func Notify(p Payload) {
+	p = complete(p)
+	for _, name := range Names() {
+		w, _ := Get(name)
+		if len(w.Events) > 0 && !slices.Contains(w.Events, p.Event) {
+			continue
+		}
+		inflight.Add(1)
+		pending.Add(1)
+		go func() {
+			defer inflight.Done()
+			defer pending.Add(-1)
+			deliver(context.Background(), name, w, p)
+		}()
+	}
+}
+
+// Wait espera hasta timeout a los envíos en curso. Devuelve cuántos quedaron sin terminar.
+func Wait(timeout time.Duration) int {
+	done := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return 0
+	case <-time.After(timeout):
+		return int(pending.Load())
+	}
+}
+
+// Send manda el evento a un webhook y espera el resultado (para probarlo)
+func Send(ctx context.Context, name string, p Payload) (Delivery, error) {
+	w, ok := Get(name)
+	if !ok {
+		return Delivery{}, fmt.Errorf("webhook desconocido %q", name)
+	}
+	return deliver(ctx, name, w, complete(p)), nil
+}
+
+var hostname = sync.OnceValue(func() string {
+	name, _ := os.Hostname()
+	return name
+})
+
+func complete(p Payload) Payload {
+	if p.Time.IsZero() {
+		p.Time = time.Now()
+	}
+	if p.Host == "" {
+		p.Host = hostname()
+	}
+	return p
+}
+
+// deliver arma el cuerpo, lo manda con reintentos y registra el resultado
+func deliver(ctx context.Context, name string, w config.WebhookConfig, p Payload) (d Delivery) {
+	d = Delivery{ID: newDeliveryID(), Time: time.Now(), Webhook: name, Event: p.Event, JobID: p.JobID}
+	start := time.Now()
+	defer func() {
+		d.DurationMS = time.Since(start).Milliseconds()
+		result := "failed"
+		if d.OK {
+			result = "ok"
+		}
+		deliveriesTotal.Inc(name, result)
+		if err := recordDelivery(d); err != nil {
+			logger.Error("Error guardando el registro de webhooks", "error", err)
+		}
+	}()
+
+	body, err := render(w.Format, p)
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	key, err := secret(w)
+	if err != nil {
+		// Mejor no mandar nada que mandarlo sin firmar
+		d.Error = err.Error()
+		logger.Error("Webhook sin clave para firmar", "webhook", name, "error", err)
+		return d
+	}
+
+	timeout := time.Duration(w.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	attempts := w.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultMaxAttempts
+	}
+	client := &http.Client{Timeout: timeout}
+
+	for d.Attempts < attempts {
+		d.Attempts++
+		status, retryAfter, err := post(ctx, client, w.URL, body, key, p.Event, d.ID)
+		d.StatusCode = status
+		if err == nil {
+			d.OK, d.Error = true, ""
+			logger.Debug("Webhook enviado", "webhook", name, "event", p.Event, "status", status, "attempts", d.Attempts)
+			return d
+		}
+		d.Error = err.Error()
+		retryable := status == 0 || status == http.StatusTooManyRequests || status >= 500
+		if !retryable || d.Attempts == attempts {
+			break
+		}
+
+		delay := retryDelay(d.Attempts, retryAfter)
+		logger.Warn("Webhook fallido, se reintenta", "webhook", name, "event", p.Event,
+			"attempt", d.Attempts, "retry_in", delay.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			d.Error = ctx.Err().Error()
+			return d
+		case <-time.After(delay):
+		}
+	}
+	logger.Error("Webhook no entregado", "webhook", name, "event", p.Event, "attempts", d.Attempts, "error", d.Error)
+	return d
+}
+
+// post hace un intento. Devuelve el código HTTP (0 si no hubo respuesta) y el
+// Retry-After que haya pedido el receptor.
+func post(ctx context.Context, client *http.Client, target string, body, key []byte, event, id string) (int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, redactErr(err, target)
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Gobackup-Webhook")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, id)
+	req.Header.Set(HeaderTimestamp, ts)
+	if key != nil {
+		req.Header.Set(HeaderSignature, Sign(key, ts, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, redactErr(err, target)
+	}
+	defer resp.Body.Close()
+	// Leer un poco de la respuesta para el mensaje y para reusar la conexión
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, 0, nil
+	}
+	var retryAfter time.Duration
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		retryAfter = time.Duration(secs) * time.Second
+	}
+	msg := resp.Status
+	if s := strings.TrimSpace(string(snippet)); s != "" {
+		msg += ": " + s
+	}
+	return resp.StatusCode, retryAfter, fmt.Errorf("respuesta %s", msg)
+}
+
+// redactErr quita la URL completa de los errores de net/http (*url.Error la
+// incluye, con el secreto de Slack o Discord) antes de guardarlos o registrarlos
+func redactErr(err error, target string) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return fmt.Errorf("%s %s: %w", ue.Op, Redact(target), ue.Err)
+	}
+	return err
+}
+
+// retryDelay es la espera antes del siguiente intento: 1s, 2s, 4s... hasta un
+// minuto, o lo que pida el receptor con Retry-After
+func retryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	delay := time.Second << (attempt - 1)
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// Sign calcula la firma de un envío. El receptor la recalcula con la misma clave
+// sobre la cabecera de timestamp y el cuerpo tal cual llegó.
+func Sign(key []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// secret lee la clave HMAC del webhook (nil si no tiene)
+func secret(w config.WebhookConfig) ([]byte, error) {
+	switch {
+	case w.SecretEnv != "":
+		v := strings.TrimSpace(os.Getenv(w.SecretEnv))
+		if v == "" {
+			return nil, fmt.Errorf("la variable %s no está definida", w.SecretEnv)
+		}
+		return []byte(v), nil
+	case w.SecretFile != "":
+		data, err := os.ReadFile(w.SecretFile)
+		if err != nil {
+			return nil, err
+		}
+		v := strings.TrimSpace(string(data))
+		if v == "" {
+			return nil, fmt.Errorf("%s está vacío", w.SecretFile)
+		}
+		return []byte(v), nil
+	}
+	return nil, nil
+}
+
+func newDeliveryID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "dlv_" + hex.EncodeToString(b)
+}